@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"os"
+	sched "scheduler"
+	"time"
+)
+
+// saveCheckpoint 用于把调度器的检查点保存到给定路径的文件中。
+// 检查点会先被写入临时文件，然后再替换原有的文件。
+func saveCheckpoint(scheduler sched.Scheduler, filePath string) error {
+	tmpPath := filePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err = scheduler.Checkpoint(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+// keepCheckpointing 用于按照给定的间隔时间定期保存检查点，
+// 直到参数stopCh代表的通道被关闭。
+func keepCheckpointing(
+	scheduler sched.Scheduler,
+	filePath string,
+	interval time.Duration,
+	stopCh <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+			if err := saveCheckpoint(scheduler, filePath); err != nil {
+				log.Printf("An error occurs when saving checkpoint: %s\n", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"module"
+	"os"
+	sched "scheduler"
+)
+
+// reinjectReqs 代表从死信中还原的、需要重新爬取的请求的列表。
+// 不为空时调度器会在没有首次请求的情况下启动，然后放入这些请求。
+var reinjectReqs []*module.Request
+
+// deadLetterUsage 用于打印deadletter子命令的用法。
+func deadLetterUsage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s deadletter:\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\tfinder deadletter list <file>\n")
+	fmt.Fprintf(os.Stderr, "\tfinder deadletter reinject <file> [flags]\n")
+}
+
+// runDeadLetterCommand 用于执行deadletter子命令。
+// 对于list，会列出给定文件中的所有死信并退出。
+// 对于reinject，会还原给定文件中的请求和响应种类的死信中的请求，
+// 并返回其余的参数，以便按照这些参数开始新的爬取并放入这些请求。
+// 还原的请求会保留原有的方法、请求头、请求体和深度。
+func runDeadLetterCommand(args []string) []string {
+	if len(args) < 2 {
+		deadLetterUsage()
+		os.Exit(2)
+	}
+	deadLetters, err := loadDeadLetters(args[1])
+	if err != nil {
+		log.Fatalf("An error occurs when reading dead letters: %s", err)
+	}
+	switch args[0] {
+	case "list":
+		for _, dl := range deadLetters {
+			target := dl.URL
+			if dl.Kind == sched.DEAD_LETTER_KIND_ITEM {
+				target = fmt.Sprintf("%v", dl.Item)
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\n",
+				dl.Time.Format("2006-01-02 15:04:05"), dl.Kind, dl.MID, target, dl.Error)
+		}
+		fmt.Printf("Total: %d\n", len(deadLetters))
+		os.Exit(0)
+	case "reinject":
+		urlSet := map[string]bool{}
+		var skipped int
+		for _, dl := range deadLetters {
+			if dl.Kind == sched.DEAD_LETTER_KIND_ITEM || dl.URL == "" {
+				skipped++
+				continue
+			}
+			if urlSet[dl.URL] {
+				continue
+			}
+			req, err := dl.Request()
+			if err != nil {
+				log.Printf("Ignore the dead letter: %s (URL: %s)\n", err, dl.URL)
+				skipped++
+				continue
+			}
+			urlSet[dl.URL] = true
+			reinjectReqs = append(reinjectReqs, req)
+		}
+		log.Printf("Reinject %d request(s) from dead letters. (skipped: %d)\n",
+			len(reinjectReqs), skipped)
+		if len(reinjectReqs) == 0 {
+			log.Fatal("No request to reinject.")
+		}
+		return args[2:]
+	default:
+		deadLetterUsage()
+		os.Exit(2)
+	}
+	return nil
+}
+
+// loadDeadLetters 用于从给定路径的文件中读出所有死信。
+func loadDeadLetters(filePath string) ([]*sched.DeadLetter, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return sched.ReadDeadLetters(file)
+}
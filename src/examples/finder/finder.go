@@ -1,28 +1,56 @@
 package main
 
 import (
+	"encoding/json"
 	lib "examples/finder/internal"
 	"examples/finder/monitor"
 	"flag"
 	"fmt"
 	"log"
+	"module"
 	"net/http"
 	"os"
 	sched "scheduler"
 	"strings"
 	"time"
+	"toolkit/canonicalizer"
+	"toolkit/linkgraph"
+	"toolkit/publicsuffix"
 )
 
 var (
-	firstURL string
-	domains  string
-	depth    uint
-	dirPath  string
+	firstURL           string
+	domains            string
+	depth              uint
+	dirPath            string
+	checkpointPath     string
+	checkpointInterval time.Duration
+	resume             bool
+	spillDir           string
+	maxAttempts        uint
+	deadLetterPath     string
+	pslPath            string
+	scopePath          string
+	stripTracking      bool
+	bloomVisited       bool
+	maxPages           uint64
+	maxBytes           uint64
+	maxDuration        time.Duration
+	maxDomainPages     uint64
+	stopTimeout        time.Duration
+	downloadTimeout    time.Duration
+	linkGraphPath      string
+	balancerName       string
+	breakerFailures    uint
+	breakerOpenTimeout time.Duration
+	remoteDownloaders  string
+	healthInterval     time.Duration
 )
 
 func init() {
 	flag.StringVar(&firstURL, "first", "http://zhihu.sogou.com/zhihu?query=golang+logo",
-		"The first URL which you want to access.")
+		"The first URL which you want to access. "+
+			"Please using comma-separated multiple URLs.")
 	flag.StringVar(&domains, "domains", "zhihu.com",
 		"The primary domains which you accepted. "+
 			"Please using comma-separated multiple domains.")
@@ -30,18 +58,93 @@ func init() {
 		"The depth for crawling.")
 	flag.StringVar(&dirPath, "dir", "./pictures",
 		"The path which you want to save the image files.")
+	flag.StringVar(&checkpointPath, "checkpoint", "",
+		"The path of the file which the crawl state will be saved to. "+
+			"Empty means no checkpoint.")
+	flag.DurationVar(&checkpointInterval, "checkpoint-interval", 30*time.Second,
+		"The interval for saving the checkpoint.")
+	flag.BoolVar(&resume, "resume", false,
+		"Restart from the last checkpoint instead of the first URL.")
+	flag.StringVar(&spillDir, "spill", "",
+		"The directory which the overflowing requests will be spilled to. "+
+			"Empty means keeping all requests in memory.")
+	flag.UintVar(&maxAttempts, "max-attempts", 3,
+		"The max number of download attempts for each URL. "+
+			"0 or 1 means no retry.")
+	flag.StringVar(&deadLetterPath, "dead-letter", "",
+		"The path of the JSONL file which the failed requests and items will be appended to. "+
+			"Empty means no dead letter.")
+	flag.StringVar(&pslPath, "psl", "",
+		"The path of a newer public suffix list file. "+
+			"Empty means using the embedded one.")
+	flag.StringVar(&scopePath, "scope", "",
+		"The path of the JSON file which contains the URL scope rules. "+
+			"Empty means no scope rule.")
+	flag.BoolVar(&stripTracking, "strip-tracking", false,
+		"Remove the common tracking parameters from URLs before deduplication.")
+	flag.BoolVar(&bloomVisited, "bloom", false,
+		"Record the visited URLs with a Bloom filter instead of an exact map.")
+	flag.Uint64Var(&maxPages, "max-pages", 0,
+		"The max number of pages for crawling. 0 means no limit.")
+	flag.Uint64Var(&maxBytes, "max-bytes", 0,
+		"The max number of downloaded bytes. 0 means no limit.")
+	flag.DurationVar(&maxDuration, "max-duration", 0,
+		"The max duration for crawling. 0 means no limit.")
+	flag.Uint64Var(&maxDomainPages, "max-domain-pages", 0,
+		"The max number of pages for each primary domain. 0 means no limit.")
+	flag.DurationVar(&stopTimeout, "stop-timeout", 10*time.Second,
+		"The max duration for draining the scheduler when interrupted.")
+	flag.DurationVar(&downloadTimeout, "download-timeout", 30*time.Second,
+		"The max duration for downloading each page. 0 means no limit.")
+	flag.StringVar(&linkGraphPath, "link-graph", "",
+		"The path of the JSONL file which the discovered links will be appended to. "+
+			"Empty means no link graph.")
+	flag.StringVar(&balancerName, "balancer", module.BALANCER_MIN_SCORE,
+		"The load balancing strategy for all module types: min-score, round-robin, "+
+			"weighted-round-robin, least-in-flight, p2c or consistent-hash.")
+	flag.UintVar(&breakerFailures, "breaker-failures", 0,
+		"The number of consecutive failures which trips a module's circuit breaker. "+
+			"0 means the breakers never trip on failures.")
+	flag.DurationVar(&breakerOpenTimeout, "breaker-open-timeout", 30*time.Second,
+		"The time a tripped circuit breaker stays open before allowing a trial call.")
+	flag.StringVar(&remoteDownloaders, "remote-downloaders", "",
+		"The comma-separated IDs of remote downloaders served by 'finder serve', "+
+			"e.g. 'D1|127.0.0.1:9001'. Empty means a local downloader.")
+	flag.DurationVar(&healthInterval, "health-check-interval", 10*time.Second,
+		"The interval of active health checks for remote modules. 0 means no health checks.")
 }
 
 func Usage() {
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "\tfinder [flags] \n")
+	fmt.Fprintf(os.Stderr, "\tfinder deadletter list <file>\n")
+	fmt.Fprintf(os.Stderr, "\tfinder deadletter reinject <file> [flags]\n")
+	fmt.Fprintf(os.Stderr, "\tfinder linkgraph export <file> [-format dot|graphml|csv] [-o <output>]\n")
+	fmt.Fprintf(os.Stderr, "\tfinder serve [-listen <ip:port>] [-downloaders <number>]\n")
 	fmt.Fprintf(os.Stderr, "Flags:\n")
 	flag.PrintDefaults()
 }
 
 func main() {
 	flag.Usage = Usage
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "deadletter" {
+		args = runDeadLetterCommand(args[1:])
+	}
+	if len(args) > 0 && args[0] == "linkgraph" {
+		runLinkGraphCommand(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		runServeCommand(args[1:])
+		return
+	}
+	flag.CommandLine.Parse(args)
+	if pslPath != "" {
+		if err := loadPublicSuffixList(pslPath); err != nil {
+			log.Fatalf("An error occurs when loading public suffix list: %s", err)
+		}
+	}
 
 	scheduler := sched.NewScheduler()
 	domainParts := strings.Split(domains, ",")
@@ -56,6 +159,30 @@ func main() {
 	requestArgs := sched.RequestArgs{
 		AcceptedDomains: acceptedDomains,
 		MaxDepth:        uint32(depth),
+		DownloadTimeout: downloadTimeout,
+		Retry: sched.RetryArgs{
+			MaxAttempts: uint32(maxAttempts),
+		},
+		Budget: sched.BudgetArgs{
+			MaxRequests:          maxPages,
+			MaxBytes:             maxBytes,
+			MaxDuration:          maxDuration,
+			MaxRequestsPerDomain: maxDomainPages,
+		},
+	}
+	// 重新放入的请求的主域名需要被接受，就像它们曾作为首次请求时那样。
+	if len(reinjectReqs) > 0 {
+		requestArgs.AcceptEnqueuedDomains = true
+	}
+	if stripTracking {
+		requestArgs.Canonicalizer = canonicalizer.New(canonicalizer.Options{
+			TrackingParams: canonicalizer.DefaultTrackingParams,
+		})
+	}
+	if scopePath != "" {
+		if err := loadScopeArgs(scopePath, &requestArgs.Scope); err != nil {
+			log.Fatalf("An error occurs when loading scope rules: %s", err)
+		}
 	}
 	dataArgs := sched.DataArgs{
 		ReqBufferCap:         50,
@@ -66,9 +193,28 @@ func main() {
 		ItemMaxBufferNumber:  100,
 		ErrorBufferCap:       50,
 		ErrorMaxBufferNumber: 1,
+		ReqSpillDir:          spillDir,
+		DeadLetterPath:       deadLetterPath,
+	}
+	if bloomVisited {
+		dataArgs.Visited.Mode = sched.VISITED_MODE_BLOOM
+	}
+	if linkGraphPath != "" {
+		linkGraph, err := linkgraph.NewFileStore(linkGraphPath)
+		if err != nil {
+			log.Fatalf("An error occurs when opening link graph store: %s", err)
+		}
+		defer linkGraph.Close()
+		dataArgs.LinkGraph = linkGraph
 	}
 
-	downloaders, err := lib.GetDownloaders(1)
+	var downloaders []module.Downloader
+	var err error
+	if remoteDownloaders != "" {
+		downloaders, err = lib.GetRemoteDownloaders(strings.Split(remoteDownloaders, ","))
+	} else {
+		downloaders, err = lib.GetDownloaders(1)
+	}
 
 	if err != nil {
 		log.Fatalf("An error occurs when creating downloaders: %s", err)
@@ -85,6 +231,21 @@ func main() {
 		Downloaders: downloaders,
 		Analyzers:   analyzers,
 		Pipelines:   pipelines,
+		Balancers:   map[module.Type]module.Balancer{},
+		Breaker: module.BreakerArgs{
+			ConsecutiveFailures: uint32(breakerFailures),
+			OpenTimeout:         breakerOpenTimeout,
+		},
+		HealthCheckInterval: healthInterval,
+	}
+	// 为每种组件分别创建负载均衡器，以免它们共享内部状态。
+	for _, moduleType := range []module.Type{
+		module.TYPE_DOWNLOADER, module.TYPE_ANALYZER, module.TYPE_PIPELINE} {
+		balancer, err := module.NewBalancer(balancerName)
+		if err != nil {
+			log.Fatalf("An error occurs when creating balancer: %s", err)
+		}
+		moduleArgs.Balancers[moduleType] = balancer
 	}
 	// 初始化调度器。
 	err = scheduler.Init(
@@ -106,17 +267,96 @@ func main() {
 		maxIdleCount,
 		true,
 		lib.Record)
-	// 准备调度器的启动参数。
-	firstHTTPReq, err := http.NewRequest("GET", firstURL, nil)
+	if resume {
+		// 从检查点恢复调度器。
+		if checkpointPath == "" {
+			log.Fatal("The checkpoint path is required for resumption.")
+		}
+		file, err := os.Open(checkpointPath)
+		if err != nil {
+			log.Fatalf("An error occurs when opening checkpoint: %s", err)
+		}
+		err = scheduler.ResumeFrom(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("An error occurs when resuming scheduler: %s", err)
+		}
+	} else {
+		// 准备调度器的启动参数。
+		var firstHTTPReqs []*http.Request
+		firstURLs := strings.Split(firstURL, ",")
+		// 重新放入死信中的请求时，不使用首次请求。
+		if len(reinjectReqs) > 0 {
+			firstURLs = nil
+		}
+		for _, u := range firstURLs {
+			u = strings.TrimSpace(u)
+			if u == "" {
+				continue
+			}
+			firstHTTPReq, err := http.NewRequest("GET", u, nil)
+			if err != nil {
+				log.Fatal(err)
+				return
+			}
+			firstHTTPReqs = append(firstHTTPReqs, firstHTTPReq)
+		}
+		// 开启调度器
+		err = scheduler.Start(firstHTTPReqs...)
+		if err != nil {
+			log.Fatalf("An error occurs when starting scheduler: %s", err)
+		}
+		if len(reinjectReqs) > 0 {
+			accepted, err := scheduler.EnqueueRequests(reinjectReqs...)
+			if err != nil {
+				log.Printf("An error occurs when reinjecting requests: %s", err)
+			}
+			log.Printf("Reinjected %d/%d request(s).\n", accepted, len(reinjectReqs))
+		}
+	}
+	// 定期保存检查点。
+	stopCh := make(chan struct{})
+	if checkpointPath != "" {
+		keepCheckpointing(scheduler, checkpointPath, checkpointInterval, stopCh)
+	}
+	// 收到中断信号时优雅地停止调度器。
+	stoppedCh := make(chan struct{})
+	stopOnInterrupt(scheduler, stopTimeout, stoppedCh)
+	// 等待监控结束或调度器被中断。
+	select {
+	case <-checkCountChan:
+	case <-stoppedCh:
+	}
+	close(stopCh)
+	if checkpointPath != "" {
+		if err = saveCheckpoint(scheduler, checkpointPath); err != nil {
+			log.Printf("An error occurs when saving checkpoint: %s", err)
+		}
+	}
+}
+
+// loadPublicSuffixList 用于从给定路径的文件中加载公共后缀列表，
+// 并用它替换默认的公共后缀列表。
+func loadPublicSuffixList(filePath string) error {
+	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatal(err)
-		return
+		return err
 	}
-	// 开启调度器
-	err = scheduler.Start(firstHTTPReq)
+	defer file.Close()
+	list, err := publicsuffix.Parse(file)
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded %d public suffix rules from %s.\n", list.RuleNumber(), filePath)
+	return publicsuffix.SetDefault(list)
+}
+
+// loadScopeArgs 用于从给定路径的JSON文件中加载URL范围规则。
+func loadScopeArgs(filePath string, scopeArgs *sched.ScopeArgs) error {
+	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatalf("An error occurs when starting scheduler: %s", err)
+		return err
 	}
-	// 等待监控结束。
-	<-checkCountChan
+	defer file.Close()
+	return json.NewDecoder(file).Decode(scopeArgs)
 }
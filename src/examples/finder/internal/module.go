@@ -5,6 +5,8 @@ import (
 	"module/local/analyzer"
 	"module/local/downloader"
 	"module/local/pipeline"
+	"module/remote"
+	"net"
 )
 
 // snGen 代表组件序列号生成器。
@@ -12,18 +14,38 @@ var snGen = module.NewSNGenertor(1, 0)
 
 // GetDownloaders 用于获取下载器列表。
 func GetDownloaders(number uint8) ([]module.Downloader, error) {
+	return GetDownloadersAt(number, nil)
+}
+
+// GetDownloadersAt 用于获取ID中包含给定网络地址的下载器列表。
+// 这些下载器可以通过该地址上的远程组件服务被调用。
+func GetDownloadersAt(number uint8, addr net.Addr) ([]module.Downloader, error) {
 	downloaders := []module.Downloader{}
 	if number == 0 {
 		return downloaders, nil
 	}
 	for i := uint8(0); i < number; i++ {
 		mid, err := module.GenMID(
-			module.TYPE_DOWNLOADER, snGen.Get(), nil)
+			module.TYPE_DOWNLOADER, snGen.Get(), addr)
 		if err != nil {
 			return downloaders, err
 		}
 		d, err := downloader.New(
-			mid, genHTTPClient(), module.CalculateScoreSimple)
+			mid, genHTTPClient(), module.CalculateScorePenalized)
+		if err != nil {
+			return downloaders, err
+		}
+		downloaders = append(downloaders, d)
+	}
+	return downloaders, nil
+}
+
+// GetRemoteDownloaders 用于获取给定ID的远程下载器的客户端代理列表。
+func GetRemoteDownloaders(mids []string) ([]module.Downloader, error) {
+	downloaders := []module.Downloader{}
+	for _, mid := range mids {
+		d, err := remote.NewDownloader(
+			module.MID(mid), genHTTPClient(), module.CalculateScorePenalized)
 		if err != nil {
 			return downloaders, err
 		}
@@ -45,7 +67,7 @@ func GetAnalyzers(number uint8) ([]module.Analyzer, error) {
 			return analyzers, err
 		}
 		a, err := analyzer.New(
-			mid, genResponseParsers(), module.CalculateScoreSimple)
+			mid, genResponseParsers(), module.CalculateScorePenalized)
 		if err != nil {
 			return analyzers, err
 		}
@@ -67,7 +89,7 @@ func GetPipelines(number uint8, dirPath string) ([]module.Pipeline, error) {
 			return pipelines, err
 		}
 		a, err := pipeline.New(
-			mid, genItemProcessors(dirPath), module.CalculateScoreSimple)
+			mid, genItemProcessors(dirPath), module.CalculateScorePenalized)
 		if err != nil {
 			return pipelines, err
 		}
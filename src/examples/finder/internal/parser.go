@@ -81,6 +81,8 @@ func genResponseParsers() []module.ParseResponse {
 				errs = append(errs, err)
 			} else {
 				req := module.NewRequest(httpReq, respDepth)
+				req.SetAnchorText(strings.TrimSpace(sel.Text()))
+				req.SetAttr("link_tag", "a")
 				dataList = append(dataList, req)
 			}
 		})
@@ -105,6 +107,10 @@ func genResponseParsers() []module.ParseResponse {
 				errs = append(errs, err)
 			} else {
 				req := module.NewRequest(httpReq, respDepth)
+				if alt, exists := sel.Attr("alt"); exists {
+					req.SetAnchorText(strings.TrimSpace(alt))
+				}
+				req.SetAttr("link_tag", "img")
 				dataList = append(dataList, req)
 			}
 		})
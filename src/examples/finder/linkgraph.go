@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"toolkit/linkgraph"
+)
+
+// linkGraphUsage 用于打印linkgraph子命令的用法。
+func linkGraphUsage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s linkgraph:\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\tfinder linkgraph export <file> [-format dot|graphml|csv] [-o <output>]\n")
+}
+
+// runLinkGraphCommand 用于执行linkgraph子命令。
+// 对于export，会把给定文件中的链接图按照指定的格式导出并退出。
+func runLinkGraphCommand(args []string) {
+	if len(args) < 2 || args[0] != "export" {
+		linkGraphUsage()
+		os.Exit(2)
+	}
+	flagSet := flag.NewFlagSet("linkgraph export", flag.ExitOnError)
+	format := flagSet.String("format", string(linkgraph.FORMAT_DOT),
+		"The format for exporting: dot, graphml or csv.")
+	outputPath := flagSet.String("o", "",
+		"The path of the output file. Empty means the standard output.")
+	flagSet.Parse(args[2:])
+	edges, err := loadLinkGraph(args[1])
+	if err != nil {
+		log.Fatalf("An error occurs when reading link graph: %s", err)
+	}
+	var w io.Writer = os.Stdout
+	if *outputPath != "" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			log.Fatalf("An error occurs when creating output file: %s", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err = linkgraph.Write(w, linkgraph.Format(*format), edges); err != nil {
+		log.Fatalf("An error occurs when exporting link graph: %s", err)
+	}
+	if *outputPath != "" {
+		log.Printf("Exported %d edge(s) to %s.\n", len(edges), *outputPath)
+	}
+}
+
+// loadLinkGraph 用于从给定路径的文件中读出链接图的所有的边。
+func loadLinkGraph(filePath string) ([]linkgraph.Edge, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return linkgraph.ReadEdges(file)
+}
@@ -25,6 +25,9 @@ type summary struct {
 var msgReachMaxIdleCount = "The scheduler has been idle for a period of time" +
 	" (about %s)." + " Consider to stop it now."
 
+// msgCrawlDone 代表爬取已完成的消息。
+var msgCrawlDone = "The scheduler has no remaining work."
+
 // msgStopScheduler 代表停止调度器的消息模板。
 var msgStopScheduler = "Stop scheduler...%s."
 
@@ -37,7 +40,7 @@ type Record func(level uint8, content string)
 // 参数checkInterval代表检查间隔时间，单位：纳秒。
 // 参数summarizeInterval代表摘要获取间隔时间，单位：纳秒。
 // 参数maxIdleCount代表最大空闲计数。
-// 参数autoStop被用来指示该方法是否在调度器空闲足够长的时间之后自行停止调度器。
+// 参数autoStop被用来指示该方法是否在调度器爬取完成或空闲足够长的时间之后自行停止调度器。
 // 参数record代表日志记录函数。
 // 当监控结束之后，该方法会向作为唯一结果值的通道发送一个代表了空闲状态检查次数的数值。
 func Monitor(
@@ -106,6 +109,14 @@ func checkStatus(
 		var idleCount uint
 		var firstIdleTime time.Time
 		for {
+			// 检查调度器是否已爬取完成。
+			select {
+			case <-scheduler.Done():
+				record(0, msgCrawlDone)
+				stopScheduler(scheduler, autoStop, record)
+				return
+			default:
+			}
 			// 检查调度器的空闲状态。
 			if scheduler.Idle() {
 				idleCount++
@@ -118,16 +129,7 @@ func checkStatus(
 					record(0, msg)
 					// 再次检查调度器的空闲状态，确保它已经可以被停止。
 					if scheduler.Idle() {
-						if autoStop {
-							var result string
-							if err := scheduler.Stop(); err == nil {
-								result = "success"
-							} else {
-								result = fmt.Sprintf("failing(%s)", err)
-							}
-							msg = fmt.Sprintf(msgStopScheduler, result)
-							record(0, msg)
-						}
+						stopScheduler(scheduler, autoStop, record)
 						break
 					} else {
 						if idleCount > 0 {
@@ -141,11 +143,28 @@ func checkStatus(
 				}
 			}
 			checkCount++
-			time.Sleep(checkInterval)
+			select {
+			case <-scheduler.Done():
+			case <-time.After(checkInterval):
+			}
 		}
 	}()
 }
 
+// stopScheduler 用于在参数autoStop为true时停止仍在运行的调度器。
+func stopScheduler(scheduler sched.Scheduler, autoStop bool, record Record) {
+	if !autoStop || scheduler.Status() != sched.SCHED_STATUS_STARTED {
+		return
+	}
+	var result string
+	if err := scheduler.Stop(); err == nil {
+		result = "success"
+	} else {
+		result = fmt.Sprintf("failing(%s)", err)
+	}
+	record(0, fmt.Sprintf(msgStopScheduler, result))
+}
+
 // recordSummary 用于记录摘要信息。
 func recordSummary(
 	scheduler sched.Scheduler,
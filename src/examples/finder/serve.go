@@ -0,0 +1,53 @@
+package main
+
+import (
+	lib "examples/finder/internal"
+	"flag"
+	"fmt"
+	"log"
+	"module"
+	"module/remote"
+	"net"
+	"net/http"
+	"os"
+)
+
+// serveUsage 用于打印serve子命令的用法。
+func serveUsage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s serve:\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\tfinder serve [-listen <ip:port>] [-downloaders <number>]\n")
+}
+
+// runServeCommand 用于执行serve子命令。
+// 它会创建给定数量的下载器，并在给定的地址上把它们暴露为远程组件服务。
+// 打印出的组件ID可以通过-remote-downloaders标记传给爬取进程。
+func runServeCommand(args []string) {
+	flagSet := flag.NewFlagSet("serve", flag.ExitOnError)
+	flagSet.Usage = serveUsage
+	listen := flagSet.String("listen", "127.0.0.1:9001",
+		"The IP address and port to listen on.")
+	number := flagSet.Uint("downloaders", 1,
+		"The number of downloaders to serve.")
+	flagSet.Parse(args)
+	addr, err := net.ResolveTCPAddr("tcp", *listen)
+	if err != nil || addr.IP == nil {
+		log.Fatalf("Illegal listen address %q: the IP address and port are both required", *listen)
+	}
+	downloaders, err := lib.GetDownloadersAt(uint8(*number), addr)
+	if err != nil {
+		log.Fatalf("An error occurs when creating downloaders: %s", err)
+	}
+	modules := make([]module.Module, 0, len(downloaders))
+	for _, d := range downloaders {
+		modules = append(modules, d)
+		log.Printf("Serve the downloader %q.\n", d.ID())
+	}
+	server, err := remote.NewServer(modules...)
+	if err != nil {
+		log.Fatalf("An error occurs when creating remote module server: %s", err)
+	}
+	log.Printf("Listen on %s...\n", addr)
+	if err := http.ListenAndServe(addr.String(), server); err != nil {
+		log.Fatalf("An error occurs when serving remote modules: %s", err)
+	}
+}
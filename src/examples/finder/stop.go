@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	sched "scheduler"
+	"time"
+)
+
+// stopOnInterrupt 用于在收到中断信号时优雅地停止调度器。
+// 参数timeout代表等待各阶段排空的最长时间。
+// 调度器停止后，参数stoppedCh代表的通道会被关闭。
+func stopOnInterrupt(
+	scheduler sched.Scheduler,
+	timeout time.Duration,
+	stoppedCh chan<- struct{}) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		<-sigCh
+		signal.Stop(sigCh)
+		log.Printf("Interrupted. Stop scheduler gracefully within %s...\n", timeout)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		report, err := scheduler.StopGracefully(ctx)
+		if err != nil {
+			log.Printf("An error occurs when stopping scheduler: %s\n", err)
+		} else {
+			log.Printf("Stop report: drained: %v, elapsed: %s, rejected requests: %d, "+
+				"discarded requests: %d, responses: %d, items: %d, interrupted workers: %d\n",
+				report.Drained, report.Elapsed, report.RejectedRequests,
+				len(report.DiscardedRequestURLs), report.DiscardedResponses,
+				report.DiscardedItems, report.InterruptedWorkers)
+		}
+		close(stoppedCh)
+	}()
+}
@@ -0,0 +1,120 @@
+package module
+
+import (
+	"context"
+)
+
+// LegacyDownloader 代表不接受上下文的下载器的接口类型。
+type LegacyDownloader interface {
+	Module
+	Download(req *Request) (*Response, error)
+}
+
+// LegacyAnalyzer 代表不接受上下文的分析器的接口类型。
+type LegacyAnalyzer interface {
+	Module
+	RespParsers() []ParseResponse
+	Analyze(resp *Response) ([]Data, []error)
+}
+
+// LegacyPipeline 代表不接受上下文的条目处理管道的接口类型。
+type LegacyPipeline interface {
+	Module
+	ItemProcessors() []ProcessItem
+	Send(item Item) []error
+	FailFast() bool
+	SetFailFast(failFast bool)
+}
+
+// AdaptDownloader 会把不接受上下文的下载器适配为下载器。
+// 上下文会被附加到HTTP请求上，因此基于HTTP客户端的下载器也能被中止。
+// 此外，上下文被取消时适配器会立即返回，而不等待原下载器返回，
+// 原下载器之后返回的响应的响应体会被关闭。
+func AdaptDownloader(downloader LegacyDownloader) Downloader {
+	if downloader == nil {
+		return nil
+	}
+	return &downloaderAdapter{LegacyDownloader: downloader}
+}
+
+// downloaderAdapter 代表下载器的适配器。
+type downloaderAdapter struct {
+	LegacyDownloader
+}
+
+func (adapter *downloaderAdapter) Download(
+	ctx context.Context, req *Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if req != nil && req.HTTPReq() != nil {
+		ctxReq := *req
+		ctxReq.httpReq = req.HTTPReq().WithContext(ctx)
+		req = &ctxReq
+	}
+	type result struct {
+		resp *Response
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		resp, err := adapter.LegacyDownloader.Download(req)
+		resultCh <- result{resp, err}
+	}()
+	select {
+	case r := <-resultCh:
+		return r.resp, r.err
+	case <-ctx.Done():
+		// 原下载器稍后返回的响应已无人使用，需要关闭其响应体以释放连接。
+		go func() {
+			r := <-resultCh
+			if r.resp != nil && r.resp.HTTPResp() != nil && r.resp.HTTPResp().Body != nil {
+				r.resp.HTTPResp().Body.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// AdaptAnalyzer 会把不接受上下文的分析器适配为分析器。
+// 上下文只会在调用原分析器之前被检查。
+func AdaptAnalyzer(analyzer LegacyAnalyzer) Analyzer {
+	if analyzer == nil {
+		return nil
+	}
+	return &analyzerAdapter{LegacyAnalyzer: analyzer}
+}
+
+// analyzerAdapter 代表分析器的适配器。
+type analyzerAdapter struct {
+	LegacyAnalyzer
+}
+
+func (adapter *analyzerAdapter) Analyze(
+	ctx context.Context, resp *Response) ([]Data, []error) {
+	if err := ctx.Err(); err != nil {
+		return nil, []error{err}
+	}
+	return adapter.LegacyAnalyzer.Analyze(resp)
+}
+
+// AdaptPipeline 会把不接受上下文的条目处理管道适配为条目处理管道。
+// 上下文只会在调用原条目处理管道之前被检查。
+func AdaptPipeline(pipeline LegacyPipeline) Pipeline {
+	if pipeline == nil {
+		return nil
+	}
+	return &pipelineAdapter{LegacyPipeline: pipeline}
+}
+
+// pipelineAdapter 代表条目处理管道的适配器。
+type pipelineAdapter struct {
+	LegacyPipeline
+}
+
+func (adapter *pipelineAdapter) Send(ctx context.Context, item Item) []error {
+	if err := ctx.Err(); err != nil {
+		return []error{err}
+	}
+	return adapter.LegacyPipeline.Send(item)
+}
@@ -0,0 +1,78 @@
+package module
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closeNotifier 代表会在被关闭时发出通知的响应体。
+type closeNotifier struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (body *closeNotifier) Close() error {
+	close(body.closed)
+	return nil
+}
+
+// slowDownloader 代表会在一段时间之后才返回响应的不接受上下文的下载器。
+type slowDownloader struct {
+	Module
+	delay time.Duration
+	body  *closeNotifier
+}
+
+func (downloader *slowDownloader) Download(req *Request) (*Response, error) {
+	time.Sleep(downloader.delay)
+	httpResp := &http.Response{StatusCode: 200, Body: downloader.body}
+	return NewResponse(httpResp, req.Depth()), nil
+}
+
+func TestDownloaderAdapterClosesAbandonedBody(t *testing.T) {
+	body := &closeNotifier{
+		Reader: strings.NewReader("ok"),
+		closed: make(chan struct{}),
+	}
+	downloader := AdaptDownloader(&slowDownloader{delay: 50 * time.Millisecond, body: body})
+	httpReq, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	resp, err := downloader.Download(ctx, NewRequest(httpReq, 0))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Inconsistent error: expected: %s, actual: %v", context.DeadlineExceeded, err)
+	}
+	if resp != nil {
+		t.Fatalf("Non-nil response: %v", resp)
+	}
+	select {
+	case <-body.closed:
+	case <-time.After(time.Second):
+		t.Fatal("The body of the abandoned response has not been closed!")
+	}
+}
+
+func TestDownloaderAdapterReturnsResponse(t *testing.T) {
+	body := &closeNotifier{
+		Reader: strings.NewReader("ok"),
+		closed: make(chan struct{}),
+	}
+	downloader := AdaptDownloader(&slowDownloader{body: body})
+	httpReq, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	resp, err := downloader.Download(context.Background(), NewRequest(httpReq, 2))
+	if err != nil {
+		t.Fatalf("An error occurs when downloading: %s", err)
+	}
+	if resp.Depth() != 2 {
+		t.Fatalf("Inconsistent depth: expected: %d, actual: %d", 2, resp.Depth())
+	}
+	content, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+	if string(content) != "ok" {
+		t.Fatalf("Inconsistent body: expected: %q, actual: %q", "ok", content)
+	}
+}
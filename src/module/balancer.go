@@ -0,0 +1,331 @@
+package module
+
+import (
+	"errs"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 内置的负载均衡策略的名称。
+const (
+	BALANCER_MIN_SCORE            = "min-score"
+	BALANCER_ROUND_ROBIN          = "round-robin"
+	BALANCER_WEIGHTED_ROUND_ROBIN = "weighted-round-robin"
+	BALANCER_LEAST_IN_FLIGHT      = "least-in-flight"
+	BALANCER_P2C                  = "p2c"
+	BALANCER_CONSISTENT_HASH      = "consistent-hash"
+)
+
+// DEFAULT_HASH_REPLICAS 代表一致性哈希环上每个组件实例的默认虚拟节点数。
+const DEFAULT_HASH_REPLICAS = 100
+
+// Balancer 代表负载均衡器的接口类型。
+// 注册器会用它从同一类型的多个组件实例中选出一个。
+// 它的所有方法都应该是并发安全的。
+type Balancer interface {
+	// Name 会返回负载均衡策略的名称。
+	Name() string
+	// Select 会从给定的组件实例中选出一个。
+	// 参数modules不会为空，且已按组件ID排序。
+	// 参数key代表用于选择的键，例如请求的主机名。可能为空。
+	Select(modules []Module, key string) Module
+}
+
+// NewBalancer 会根据给定的名称创建一个内置的负载均衡器。
+// 加权轮询策略创建的负载均衡器中每个组件实例的权重都为1，
+// 需要指定权重时请使用NewWeightedRoundRobinBalancer函数。
+func NewBalancer(name string) (Balancer, error) {
+	switch name {
+	case BALANCER_MIN_SCORE:
+		return NewMinScoreBalancer(), nil
+	case BALANCER_ROUND_ROBIN:
+		return NewRoundRobinBalancer(), nil
+	case BALANCER_WEIGHTED_ROUND_ROBIN:
+		return NewWeightedRoundRobinBalancer(nil), nil
+	case BALANCER_LEAST_IN_FLIGHT:
+		return NewLeastInFlightBalancer(), nil
+	case BALANCER_P2C:
+		return NewP2CBalancer(), nil
+	case BALANCER_CONSISTENT_HASH:
+		return NewConsistentHashBalancer(0), nil
+	default:
+		errMsg := fmt.Sprintf("unsupported balancer: %q", name)
+		return nil, errs.NewIllegalParameterError(errMsg)
+	}
+}
+
+// minScoreBalancer 代表选择评分最低的组件实例的负载均衡器。
+// 评分相同时选择组件ID最小的那个。
+type minScoreBalancer struct{}
+
+// NewMinScoreBalancer 会创建一个选择评分最低的组件实例的负载均衡器。
+// 这是注册器默认使用的负载均衡策略。
+func NewMinScoreBalancer() Balancer {
+	return minScoreBalancer{}
+}
+
+func (minScoreBalancer) Name() string {
+	return BALANCER_MIN_SCORE
+}
+
+func (minScoreBalancer) Select(modules []Module, key string) Module {
+	selected := modules[0]
+	for _, module := range modules[1:] {
+		if module.Score() < selected.Score() {
+			selected = module
+		}
+	}
+	return selected
+}
+
+// roundRobinBalancer 代表轮流选择组件实例的负载均衡器。
+type roundRobinBalancer struct {
+	next uint64
+}
+
+// NewRoundRobinBalancer 会创建一个轮流选择组件实例的负载均衡器。
+func NewRoundRobinBalancer() Balancer {
+	return &roundRobinBalancer{}
+}
+
+func (balancer *roundRobinBalancer) Name() string {
+	return BALANCER_ROUND_ROBIN
+}
+
+func (balancer *roundRobinBalancer) Select(modules []Module, key string) Module {
+	n := atomic.AddUint64(&balancer.next, 1) - 1
+	return modules[n%uint64(len(modules))]
+}
+
+// weightedRoundRobinBalancer 代表按照权重平滑地轮流选择组件实例的负载均衡器。
+type weightedRoundRobinBalancer struct {
+	// weights 代表组件ID与权重的字典。
+	weights map[MID]uint32
+	// current 代表各组件实例的当前权重。
+	current map[MID]int64
+	lock    sync.Mutex
+}
+
+// NewWeightedRoundRobinBalancer 会创建一个按照权重平滑地轮流选择组件实例的负载均衡器。
+// 参数weights代表组件ID与权重的字典。未指定或为0的权重会被视为1。
+// 在任意连续的一轮选择中，各组件实例被选中的次数与其权重成正比，且不会扎堆。
+func NewWeightedRoundRobinBalancer(weights map[MID]uint32) Balancer {
+	copied := map[MID]uint32{}
+	for mid, weight := range weights {
+		copied[mid] = weight
+	}
+	return &weightedRoundRobinBalancer{
+		weights: copied,
+		current: map[MID]int64{},
+	}
+}
+
+func (balancer *weightedRoundRobinBalancer) Name() string {
+	return BALANCER_WEIGHTED_ROUND_ROBIN
+}
+
+func (balancer *weightedRoundRobinBalancer) weight(mid MID) int64 {
+	if weight := balancer.weights[mid]; weight > 0 {
+		return int64(weight)
+	}
+	return 1
+}
+
+func (balancer *weightedRoundRobinBalancer) Select(modules []Module, key string) Module {
+	balancer.lock.Lock()
+	defer balancer.lock.Unlock()
+	var selected Module
+	var total int64
+	for _, module := range modules {
+		mid := module.ID()
+		weight := balancer.weight(mid)
+		total += weight
+		balancer.current[mid] += weight
+		if selected == nil || balancer.current[mid] > balancer.current[selected.ID()] {
+			selected = module
+		}
+	}
+	balancer.current[selected.ID()] -= total
+	// 清理已不存在的组件实例的当前权重。
+	if len(balancer.current) > len(modules) {
+		present := make(map[MID]bool, len(modules))
+		for _, module := range modules {
+			present[module.ID()] = true
+		}
+		for mid := range balancer.current {
+			if !present[mid] {
+				delete(balancer.current, mid)
+			}
+		}
+	}
+	return selected
+}
+
+// leastInFlightBalancer 代表选择正在处理的数据最少的组件实例的负载均衡器。
+type leastInFlightBalancer struct {
+	next uint64
+}
+
+// NewLeastInFlightBalancer 会创建一个选择正在处理的数据最少的组件实例的负载均衡器。
+// 处理数相同时会轮流选择。
+func NewLeastInFlightBalancer() Balancer {
+	return &leastInFlightBalancer{}
+}
+
+func (balancer *leastInFlightBalancer) Name() string {
+	return BALANCER_LEAST_IN_FLIGHT
+}
+
+func (balancer *leastInFlightBalancer) Select(modules []Module, key string) Module {
+	start := int((atomic.AddUint64(&balancer.next, 1) - 1) % uint64(len(modules)))
+	selected := modules[start]
+	for i := 1; i < len(modules); i++ {
+		module := modules[(start+i)%len(modules)]
+		if module.HandlingNumber() < selected.HandlingNumber() {
+			selected = module
+		}
+	}
+	return selected
+}
+
+// p2cBalancer 代表基于“两次随机选择”的负载均衡器。
+type p2cBalancer struct {
+	random *rand.Rand
+	lock   sync.Mutex
+}
+
+// NewP2CBalancer 会创建一个基于“两次随机选择”的负载均衡器。
+// 它会随机选出两个组件实例，然后选择其中正在处理的数据较少的那个。
+// 处理数相同时选择评分较低的那个。
+func NewP2CBalancer() Balancer {
+	return &p2cBalancer{
+		random: rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func (balancer *p2cBalancer) Name() string {
+	return BALANCER_P2C
+}
+
+func (balancer *p2cBalancer) Select(modules []Module, key string) Module {
+	if len(modules) == 1 {
+		return modules[0]
+	}
+	balancer.lock.Lock()
+	i := balancer.random.Intn(len(modules))
+	j := balancer.random.Intn(len(modules) - 1)
+	balancer.lock.Unlock()
+	if j >= i {
+		j++
+	}
+	a, b := modules[i], modules[j]
+	if b.HandlingNumber() < a.HandlingNumber() ||
+		(b.HandlingNumber() == a.HandlingNumber() && b.Score() < a.Score()) {
+		return b
+	}
+	return a
+}
+
+// consistentHashBalancer 代表基于一致性哈希的负载均衡器。
+type consistentHashBalancer struct {
+	replicas int
+	// ringKey 代表当前的哈希环所对应的组件ID的列表。
+	ringKey string
+	// hashes 代表哈希环上的虚拟节点的哈希值，已排序。
+	hashes []uint32
+	// owners 代表哈希值与组件ID的字典。
+	owners map[uint32]MID
+	// fallback 代表键为空时使用的负载均衡器。
+	fallback Balancer
+	lock     sync.RWMutex
+}
+
+// NewConsistentHashBalancer 会创建一个基于一致性哈希的负载均衡器。
+// 相同的键（例如同一主机名）总会被分配给同一个组件实例，
+// 组件实例增减时只有少量的键会被重新分配。
+// 参数replicas代表每个组件实例的虚拟节点数，为0时使用默认值。
+// 键为空时会轮流选择组件实例。
+func NewConsistentHashBalancer(replicas int) Balancer {
+	if replicas <= 0 {
+		replicas = DEFAULT_HASH_REPLICAS
+	}
+	return &consistentHashBalancer{
+		replicas: replicas,
+		fallback: NewRoundRobinBalancer(),
+	}
+}
+
+func (balancer *consistentHashBalancer) Name() string {
+	return BALANCER_CONSISTENT_HASH
+}
+
+func (balancer *consistentHashBalancer) Select(modules []Module, key string) Module {
+	if key == "" {
+		return balancer.fallback.Select(modules, key)
+	}
+	mids := make([]string, len(modules))
+	moduleMap := make(map[MID]Module, len(modules))
+	for i, module := range modules {
+		mids[i] = string(module.ID())
+		moduleMap[module.ID()] = module
+	}
+	ringKey := strings.Join(mids, ",")
+	balancer.lock.RLock()
+	if balancer.ringKey != ringKey {
+		balancer.lock.RUnlock()
+		balancer.rebuild(ringKey, mids)
+		balancer.lock.RLock()
+	}
+	hash := hashKey(key)
+	index := sort.Search(len(balancer.hashes), func(i int) bool {
+		return balancer.hashes[i] >= hash
+	})
+	if index == len(balancer.hashes) {
+		index = 0
+	}
+	owner := balancer.owners[balancer.hashes[index]]
+	balancer.lock.RUnlock()
+	if module, ok := moduleMap[owner]; ok {
+		return module
+	}
+	// 哈希环已被并发地按照另一组组件实例重建。
+	return modules[int(hash)%len(modules)]
+}
+
+// rebuild 用于按照给定的组件ID的列表重建哈希环。
+func (balancer *consistentHashBalancer) rebuild(ringKey string, mids []string) {
+	balancer.lock.Lock()
+	defer balancer.lock.Unlock()
+	if balancer.ringKey == ringKey {
+		return
+	}
+	hashes := make([]uint32, 0, len(mids)*balancer.replicas)
+	owners := make(map[uint32]MID, len(mids)*balancer.replicas)
+	for _, mid := range mids {
+		for i := 0; i < balancer.replicas; i++ {
+			hash := hashKey(mid + "#" + strconv.Itoa(i))
+			if _, ok := owners[hash]; ok {
+				continue
+			}
+			owners[hash] = MID(mid)
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	balancer.ringKey = ringKey
+	balancer.hashes = hashes
+	balancer.owners = owners
+}
+
+// hashKey 用于计算给定键的哈希值。
+func hashKey(key string) uint32 {
+	hasher := fnv.New32a()
+	hasher.Write([]byte(key))
+	return hasher.Sum32()
+}
@@ -0,0 +1,206 @@
+package module
+
+import (
+	"strconv"
+	"testing"
+)
+
+// selectIDs 用于连续地进行给定次数的选择，并返回被选中的组件实例的序号。
+// 序号从1开始，与newFakeDownloaders生成的组件ID中的序列号一致。
+func selectIDs(balancer Balancer, modules []Module, key string, times int) []int {
+	indexes := make([]int, times)
+	for i := range indexes {
+		selected := balancer.Select(modules, key)
+		for j, m := range modules {
+			if m == selected {
+				indexes[i] = j + 1
+			}
+		}
+	}
+	return indexes
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNewBalancer(t *testing.T) {
+	names := []string{
+		BALANCER_MIN_SCORE,
+		BALANCER_ROUND_ROBIN,
+		BALANCER_WEIGHTED_ROUND_ROBIN,
+		BALANCER_LEAST_IN_FLIGHT,
+		BALANCER_P2C,
+		BALANCER_CONSISTENT_HASH,
+	}
+	for _, name := range names {
+		balancer, err := NewBalancer(name)
+		if err != nil {
+			t.Fatalf("An error occurs when creating balancer %q: %s", name, err)
+		}
+		if balancer.Name() != name {
+			t.Fatalf("Inconsistent balancer name: expected: %q, actual: %q",
+				name, balancer.Name())
+		}
+	}
+	if _, err := NewBalancer("unknown"); err == nil {
+		t.Fatal("No error when creating an unsupported balancer!")
+	}
+}
+
+func TestMinScoreBalancer(t *testing.T) {
+	cases := []struct {
+		scores   []uint64
+		expected int
+	}{
+		{[]uint64{3, 1, 2}, 2},
+		{[]uint64{1, 1, 2}, 1},
+		{[]uint64{2, 2, 1}, 3},
+	}
+	for _, c := range cases {
+		downloaders := newFakeDownloaders(t, len(c.scores))
+		for i, score := range c.scores {
+			downloaders[i].SetScore(score)
+		}
+		actual := selectIDs(NewMinScoreBalancer(), modulesOf(downloaders), "", 1)[0]
+		if actual != c.expected {
+			t.Fatalf("Inconsistent selection for scores %v: expected: %d, actual: %d",
+				c.scores, c.expected, actual)
+		}
+	}
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	modules := modulesOf(newFakeDownloaders(t, 3))
+	expected := []int{1, 2, 3, 1, 2, 3, 1}
+	actual := selectIDs(NewRoundRobinBalancer(), modules, "", len(expected))
+	if !sameInts(actual, expected) {
+		t.Fatalf("Inconsistent selections: expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestWeightedRoundRobinBalancer(t *testing.T) {
+	cases := []struct {
+		weights  []uint32
+		expected []int
+	}{
+		{[]uint32{5, 1, 1}, []int{1, 1, 2, 1, 3, 1, 1, 1, 1, 2, 1, 3, 1, 1}},
+		{[]uint32{1, 1, 1}, []int{1, 2, 3, 1, 2, 3}},
+		// 未指定的权重会被视为1。
+		{[]uint32{2, 0}, []int{1, 2, 1, 1, 2, 1}},
+		{[]uint32{3, 2}, []int{1, 2, 1, 2, 1}},
+	}
+	for _, c := range cases {
+		downloaders := newFakeDownloaders(t, len(c.weights))
+		weights := map[MID]uint32{}
+		for i, weight := range c.weights {
+			weights[downloaders[i].ID()] = weight
+		}
+		balancer := NewWeightedRoundRobinBalancer(weights)
+		actual := selectIDs(balancer, modulesOf(downloaders), "", len(c.expected))
+		if !sameInts(actual, c.expected) {
+			t.Fatalf("Inconsistent selections for weights %v: expected: %v, actual: %v",
+				c.weights, c.expected, actual)
+		}
+	}
+}
+
+func TestLeastInFlightBalancer(t *testing.T) {
+	cases := []struct {
+		handling []uint64
+		expected []int
+	}{
+		// 处理数相同时轮流选择。
+		{[]uint64{0, 0, 0}, []int{1, 2, 3, 1, 2, 3}},
+		{[]uint64{2, 1, 1}, []int{2, 2, 3, 2, 2, 3}},
+		{[]uint64{3, 0, 2}, []int{2, 2, 2, 2}},
+	}
+	for _, c := range cases {
+		downloaders := newFakeDownloaders(t, len(c.handling))
+		for i, handling := range c.handling {
+			downloaders[i].handling = handling
+		}
+		actual := selectIDs(NewLeastInFlightBalancer(), modulesOf(downloaders), "", len(c.expected))
+		if !sameInts(actual, c.expected) {
+			t.Fatalf("Inconsistent selections for handling numbers %v: expected: %v, actual: %v",
+				c.handling, c.expected, actual)
+		}
+	}
+}
+
+func TestP2CBalancer(t *testing.T) {
+	cases := []struct {
+		handling []uint64
+		scores   []uint64
+		expected int
+	}{
+		{[]uint64{0, 5}, []uint64{0, 0}, 1},
+		{[]uint64{5, 0}, []uint64{0, 0}, 2},
+		// 处理数相同时选择评分较低的那个。
+		{[]uint64{1, 1}, []uint64{3, 2}, 2},
+		{[]uint64{1, 1}, []uint64{2, 3}, 1},
+		{[]uint64{0}, []uint64{9}, 1},
+	}
+	for _, c := range cases {
+		downloaders := newFakeDownloaders(t, len(c.handling))
+		for i := range downloaders {
+			downloaders[i].handling = c.handling[i]
+			downloaders[i].SetScore(c.scores[i])
+		}
+		// 两个随机选出的组件实例总是不同的，因此较优的那个每次都会被选中。
+		for _, actual := range selectIDs(NewP2CBalancer(), modulesOf(downloaders), "", 100) {
+			if actual != c.expected {
+				t.Fatalf("Inconsistent selection for handling numbers %v and scores %v: "+
+					"expected: %d, actual: %d", c.handling, c.scores, c.expected, actual)
+			}
+		}
+	}
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	downloaders := newFakeDownloaders(t, 4)
+	modules := modulesOf(downloaders)
+	balancer := NewConsistentHashBalancer(0)
+	keyNumber := 1000
+	owners := map[string]MID{}
+	counts := map[MID]int{}
+	for i := 0; i < keyNumber; i++ {
+		key := "host" + strconv.Itoa(i)
+		owner := balancer.Select(modules, key).ID()
+		owners[key] = owner
+		counts[owner]++
+		// 相同的键总会被分配给同一个组件实例。
+		if again := balancer.Select(modules, key).ID(); again != owner {
+			t.Fatalf("Inconsistent owner for key %q: expected: %s, actual: %s", key, owner, again)
+		}
+	}
+	if len(counts) != len(modules) {
+		t.Fatalf("Some modules own no keys: %v", counts)
+	}
+	// 去掉一个组件实例后，只有原属于它的键会被重新分配。
+	removed := downloaders[1].ID()
+	remaining := removeModule(modules, removed)
+	for key, owner := range owners {
+		actual := balancer.Select(remaining, key).ID()
+		if actual == removed {
+			t.Fatalf("The key %q was assigned to the removed module %s!", key, removed)
+		}
+		if owner != removed && actual != owner {
+			t.Fatalf("The key %q was moved from %s to %s!", key, owner, actual)
+		}
+	}
+	// 键为空时轮流选择。
+	expected := []int{1, 2, 3, 4, 1}
+	if actual := selectIDs(balancer, modules, "", len(expected)); !sameInts(actual, expected) {
+		t.Fatalf("Inconsistent selections for empty key: expected: %v, actual: %v",
+			expected, actual)
+	}
+}
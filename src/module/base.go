@@ -1,7 +1,9 @@
 package module
 
 import (
+	"context"
 	"net/http"
+	"time"
 )
 
 // Counts 代表用于汇集组件内部计数的类型。
@@ -14,15 +16,21 @@ type Counts struct {
 	CompletedCount uint64
 	// HandlingNumber 代表实时处理数。
 	HandlingNumber uint64
+	// FailedCount 代表失败计数。
+	FailedCount uint64
+	// LatencyEWMA 代表处理耗时的指数加权移动平均值。
+	LatencyEWMA time.Duration
 }
 
 type SummaryStruct struct {
-	ID        MID         `json:"id"`
-	Called    uint64      `json:"called"`
-	Accepted  uint64      `json:"accepted"`
-	Completed uint64      `json:"completed"`
-	Handling  uint64      `json:"handling"`
-	Extra     interface{} `json:"extra,omitempty"`
+	ID        MID                  `json:"id"`
+	Called    uint64               `json:"called"`
+	Accepted  uint64               `json:"accepted"`
+	Completed uint64               `json:"completed"`
+	Handling  uint64               `json:"handling"`
+	Failed    uint64               `json:"failed"`
+	Latency   LatencySummaryStruct `json:"latency"`
+	Extra     interface{}          `json:"extra,omitempty"`
 }
 
 type MID string
@@ -40,23 +48,31 @@ type Module interface {
 	Summary() SummaryStruct
 }
 
+// Downloader 代表下载器的接口类型。
+// 参数ctx被取消时，下载器应尽快中止下载并返回错误值。
 type Downloader interface {
 	Module
-	Download(req *Request) (*Response, error)
+	Download(ctx context.Context, req *Request) (*Response, error)
 }
 
 type ParseResponse func(httpResp *http.Response, respDepth uint32) ([]Data, []error)
+
+// Analyzer 代表分析器的接口类型。
+// 参数ctx被取消时，分析器应不再调用剩余的响应解析函数。
 type Analyzer interface {
 	Module
 	RespParsers() []ParseResponse
-	Analyze(resp *Response) ([]Data, []error)
+	Analyze(ctx context.Context, resp *Response) ([]Data, []error)
 }
 
 type ProcessItem func(item Item) (result Item, err error)
+
+// Pipeline 代表条目处理管道的接口类型。
+// 参数ctx被取消时，条目处理管道应不再调用剩余的条目处理函数。
 type Pipeline interface {
 	Module
 	ItemProcessors() []ProcessItem
-	Send(item Item) []error
+	Send(ctx context.Context, item Item) []error
 	FailFast() bool
 	SetFailFast(failFast bool)
 }
@@ -0,0 +1,376 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"errs"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState 代表断路器的状态的类型。
+type BreakerState uint32
+
+// 断路器的状态。
+const (
+	// BREAKER_CLOSED 代表闭合状态，组件可以被正常选择。
+	BREAKER_CLOSED BreakerState = 0
+	// BREAKER_OPEN 代表断开状态，组件不会被选择。
+	BREAKER_OPEN BreakerState = 1
+	// BREAKER_HALF_OPEN 代表半开状态，组件只能被少量的试探调用选择。
+	BREAKER_HALF_OPEN BreakerState = 2
+)
+
+// breakerStateNameMap 代表断路器的状态与名称的字典。
+var breakerStateNameMap = map[BreakerState]string{
+	BREAKER_CLOSED:    "closed",
+	BREAKER_OPEN:      "open",
+	BREAKER_HALF_OPEN: "half-open",
+}
+
+func (state BreakerState) String() string {
+	if name, ok := breakerStateNameMap[state]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// DEFAULT_BREAKER_WINDOW_SIZE 代表计算失败率时默认统计的最近调用的次数。
+const DEFAULT_BREAKER_WINDOW_SIZE = 20
+
+// DEFAULT_BREAKER_OPEN_TIMEOUT 代表断路器断开后进入半开状态之前的默认时长。
+const DEFAULT_BREAKER_OPEN_TIMEOUT = 30 * time.Second
+
+// BREAKER_TRIP_HISTORY_SIZE 代表每个断路器保留的最近断开记录的数量。
+const BREAKER_TRIP_HISTORY_SIZE = 8
+
+// BreakerArgs 代表断路器相关的参数容器的类型。
+// ConsecutiveFailures和FailureRatio都为0时，
+// 断路器只会因主动健康检查失败而断开。
+type BreakerArgs struct {
+	// ConsecutiveFailures 代表使断路器断开的连续失败次数。0代表不按连续失败次数断开。
+	ConsecutiveFailures uint32 `json:"consecutive_failures"`
+	// FailureRatio 代表使断路器断开的失败率，取值范围为[0, 1]。0代表不按失败率断开。
+	FailureRatio float64 `json:"failure_ratio"`
+	// WindowSize 代表计算失败率时统计的最近调用的次数。0代表使用默认值。
+	WindowSize uint32 `json:"window_size"`
+	// MinCalls 代表计算失败率所需的最少调用次数。0代表等于WindowSize。
+	MinCalls uint32 `json:"min_calls"`
+	// OpenTimeout 代表断路器断开后进入半开状态之前的时长。0代表使用默认值。
+	OpenTimeout time.Duration `json:"open_timeout"`
+	// HalfOpenCalls 代表半开状态下允许同时进行的试探调用的数量，
+	// 也是使断路器重新闭合所需的连续成功的试探调用的次数。0代表1。
+	HalfOpenCalls uint32 `json:"half_open_calls"`
+}
+
+// Check 用于检查当前参数容器的有效性。
+func (args *BreakerArgs) Check() error {
+	if args.FailureRatio < 0 || args.FailureRatio > 1 {
+		errMsg := fmt.Sprintf("illegal failure ratio for breaker: %v", args.FailureRatio)
+		return errs.NewIllegalParameterError(errMsg)
+	}
+	if args.OpenTimeout < 0 {
+		return errs.NewIllegalParameterError("negative open timeout for breaker")
+	}
+	if args.MinCalls > args.windowSize() {
+		errMsg := fmt.Sprintf("too many min calls for breaker: %d (window size: %d)",
+			args.MinCalls, args.windowSize())
+		return errs.NewIllegalParameterError(errMsg)
+	}
+	return nil
+}
+
+func (args *BreakerArgs) windowSize() uint32 {
+	if args.WindowSize == 0 {
+		return DEFAULT_BREAKER_WINDOW_SIZE
+	}
+	return args.WindowSize
+}
+
+func (args *BreakerArgs) minCalls() uint32 {
+	if args.MinCalls == 0 {
+		return args.windowSize()
+	}
+	return args.MinCalls
+}
+
+func (args *BreakerArgs) openTimeout() time.Duration {
+	if args.OpenTimeout == 0 {
+		return DEFAULT_BREAKER_OPEN_TIMEOUT
+	}
+	return args.OpenTimeout
+}
+
+func (args *BreakerArgs) halfOpenCalls() uint32 {
+	if args.HalfOpenCalls == 0 {
+		return 1
+	}
+	return args.HalfOpenCalls
+}
+
+// BreakerTripStruct 代表断路器的一次断开的记录。
+type BreakerTripStruct struct {
+	// Time 代表断开的时间。
+	Time time.Time `json:"time"`
+	// Reason 代表断开的原因。
+	Reason string `json:"reason"`
+}
+
+// BreakerSummaryStruct 代表断路器的摘要类型。
+type BreakerSummaryStruct struct {
+	ID    MID    `json:"id"`
+	State string `json:"state"`
+	// ConsecutiveFailures 代表当前的连续失败次数。
+	ConsecutiveFailures uint32 `json:"consecutive_failures"`
+	// WindowCalls 代表统计窗口中的调用次数。
+	WindowCalls uint32 `json:"window_calls"`
+	// WindowFailures 代表统计窗口中的失败次数。
+	WindowFailures uint32 `json:"window_failures"`
+	// TripCount 代表断开的总次数。
+	TripCount uint64 `json:"trip_count"`
+	// Trips 代表最近的断开记录，按时间先后排列。
+	Trips []BreakerTripStruct `json:"trips,omitempty"`
+}
+
+// Same 用于判断当前的断路器摘要与另一份是否相同。
+func (one *BreakerSummaryStruct) Same(another BreakerSummaryStruct) bool {
+	if another.ID != one.ID ||
+		another.State != one.State ||
+		another.ConsecutiveFailures != one.ConsecutiveFailures ||
+		another.WindowCalls != one.WindowCalls ||
+		another.WindowFailures != one.WindowFailures ||
+		another.TripCount != one.TripCount ||
+		len(another.Trips) != len(one.Trips) {
+		return false
+	}
+	for i, trip := range another.Trips {
+		if !trip.Time.Equal(one.Trips[i].Time) || trip.Reason != one.Trips[i].Reason {
+			return false
+		}
+	}
+	return true
+}
+
+// breaker 代表单个组件实例的断路器。
+type breaker struct {
+	args  BreakerArgs
+	state BreakerState
+	// consecutiveFailures 代表连续失败次数。
+	consecutiveFailures uint32
+	// outcomes 代表统计窗口中各次调用是否失败的环形缓冲区。
+	outcomes []bool
+	// next 代表下一次调用的结果在环形缓冲区中的位置。
+	next int
+	// calls 代表统计窗口中的调用次数。
+	calls uint32
+	// failures 代表统计窗口中的失败次数。
+	failures uint32
+	// openedAt 代表最近一次断开的时间。
+	openedAt time.Time
+	// trialCalls 代表半开状态下正在进行的试探调用的数量。
+	trialCalls uint32
+	// trialSuccesses 代表半开状态下连续成功的试探调用的次数。
+	trialSuccesses uint32
+	tripCount      uint64
+	trips          []BreakerTripStruct
+	lock           sync.Mutex
+}
+
+// newBreaker 会创建一个处于闭合状态的断路器。
+func newBreaker(args BreakerArgs) *breaker {
+	return &breaker{
+		args:     args,
+		outcomes: make([]bool, args.windowSize()),
+	}
+}
+
+// available 用于判断组件实例当前是否可能被选择。
+// 断开时长超过时限的断路器会在这里进入半开状态。
+// 注意！结果值只用于预先筛选，组件实例被选择后仍需调用tryAcquire方法。
+func (b *breaker) available(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.checkOpenTimeout(now)
+	switch b.state {
+	case BREAKER_OPEN:
+		return false
+	case BREAKER_HALF_OPEN:
+		return b.trialCalls < b.args.halfOpenCalls()
+	default:
+		return true
+	}
+}
+
+// tryAcquire 用于在组件实例被选择后尝试占用一次调用的机会。
+// 半开状态下的试探调用的数量已达上限或断路器处于断开状态时返回false。
+// 判断与占用在同一次加锁中完成，因此并发的调用不会超过上限。
+func (b *breaker) tryAcquire(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.checkOpenTimeout(now)
+	switch b.state {
+	case BREAKER_OPEN:
+		return false
+	case BREAKER_HALF_OPEN:
+		if b.trialCalls >= b.args.halfOpenCalls() {
+			return false
+		}
+		b.trialCalls++
+		return true
+	default:
+		return true
+	}
+}
+
+// forceAcquire 用于在没有可用的组件实例时强制占用一次调用的机会。
+// 半开状态下的试探调用的数量可能因此超过上限。
+func (b *breaker) forceAcquire() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state == BREAKER_HALF_OPEN {
+		b.trialCalls++
+	}
+}
+
+// checkOpenTimeout 用于使断开时长超过时限的断路器进入半开状态。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (b *breaker) checkOpenTimeout(now time.Time) {
+	if b.state == BREAKER_OPEN && now.Sub(b.openedAt) >= b.args.openTimeout() {
+		b.toHalfOpen()
+	}
+}
+
+// report 用于记录一次调用的结果。
+// 因上下文被取消而失败的调用既不算成功也不算失败。
+func (b *breaker) report(err error, now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state == BREAKER_HALF_OPEN && b.trialCalls > 0 {
+		b.trialCalls--
+	}
+	if err != nil && errors.Is(err, context.Canceled) {
+		return
+	}
+	switch b.state {
+	case BREAKER_OPEN:
+		// 断开前已开始的调用的结果不影响状态。
+	case BREAKER_HALF_OPEN:
+		if err != nil {
+			b.trip(now, fmt.Sprintf("trial call failed: %s", err))
+			return
+		}
+		b.trialSuccesses++
+		if b.trialSuccesses >= b.args.halfOpenCalls() {
+			b.toClosed()
+		}
+	default:
+		b.record(err != nil)
+		if err == nil {
+			return
+		}
+		if n := b.args.ConsecutiveFailures; n > 0 && b.consecutiveFailures >= n {
+			b.trip(now, fmt.Sprintf("%d consecutive failures (last: %s)",
+				b.consecutiveFailures, err))
+			return
+		}
+		if ratio := b.args.FailureRatio; ratio > 0 && b.calls >= b.args.minCalls() &&
+			float64(b.failures) >= ratio*float64(b.calls) {
+			b.trip(now, fmt.Sprintf("failure ratio %d/%d (last: %s)",
+				b.failures, b.calls, err))
+		}
+	}
+}
+
+// healthCheck 用于记录一次主动健康检查的结果。
+// 检查失败会使断路器断开，检查成功会使断开的断路器进入半开状态。
+func (b *breaker) healthCheck(err error, now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if err != nil {
+		if b.state == BREAKER_OPEN {
+			// 推迟进入半开状态的时间，但不重复记录断开。
+			b.openedAt = now
+			return
+		}
+		b.trip(now, fmt.Sprintf("health check failed: %s", err))
+		return
+	}
+	if b.state == BREAKER_OPEN {
+		b.toHalfOpen()
+	}
+}
+
+// record 用于在统计窗口中记录一次调用的结果。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (b *breaker) record(failed bool) {
+	if b.calls == uint32(len(b.outcomes)) {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.calls++
+	}
+	b.outcomes[b.next] = failed
+	b.next = (b.next + 1) % len(b.outcomes)
+	if failed {
+		b.failures++
+		b.consecutiveFailures++
+	} else {
+		b.consecutiveFailures = 0
+	}
+}
+
+// trip 用于使断路器断开。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (b *breaker) trip(now time.Time, reason string) {
+	b.state = BREAKER_OPEN
+	b.openedAt = now
+	b.trialCalls = 0
+	b.trialSuccesses = 0
+	b.tripCount++
+	b.trips = append(b.trips, BreakerTripStruct{Time: now, Reason: reason})
+	if len(b.trips) > BREAKER_TRIP_HISTORY_SIZE {
+		b.trips = b.trips[len(b.trips)-BREAKER_TRIP_HISTORY_SIZE:]
+	}
+}
+
+// toHalfOpen 用于使断路器进入半开状态。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (b *breaker) toHalfOpen() {
+	b.state = BREAKER_HALF_OPEN
+	b.trialCalls = 0
+	b.trialSuccesses = 0
+}
+
+// toClosed 用于使断路器闭合并清空统计窗口。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (b *breaker) toClosed() {
+	b.state = BREAKER_CLOSED
+	b.consecutiveFailures = 0
+	for i := range b.outcomes {
+		b.outcomes[i] = false
+	}
+	b.next = 0
+	b.calls = 0
+	b.failures = 0
+}
+
+// summary 用于获取断路器的摘要。
+func (b *breaker) summary(mid MID) BreakerSummaryStruct {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	summary := BreakerSummaryStruct{
+		ID:                  mid,
+		State:               b.state.String(),
+		ConsecutiveFailures: b.consecutiveFailures,
+		WindowCalls:         b.calls,
+		WindowFailures:      b.failures,
+		TripCount:           b.tripCount,
+	}
+	if len(b.trips) > 0 {
+		summary.Trips = make([]BreakerTripStruct, len(b.trips))
+		copy(summary.Trips, b.trips)
+	}
+	return summary
+}
@@ -0,0 +1,317 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBreakerTest = errors.New("breaker test error")
+
+func TestBreakerArgsCheck(t *testing.T) {
+	illegalArgsList := []BreakerArgs{
+		{FailureRatio: -0.1},
+		{FailureRatio: 1.1},
+		{OpenTimeout: -time.Second},
+		{WindowSize: 4, MinCalls: 5},
+		{MinCalls: DEFAULT_BREAKER_WINDOW_SIZE + 1},
+	}
+	for _, args := range illegalArgsList {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when checking illegal breaker args %+v!", args)
+		}
+	}
+	legalArgsList := []BreakerArgs{
+		{},
+		{FailureRatio: 1, WindowSize: 4, MinCalls: 4},
+		{ConsecutiveFailures: 3, OpenTimeout: time.Second, HalfOpenCalls: 2},
+	}
+	for _, args := range legalArgsList {
+		if err := args.Check(); err != nil {
+			t.Fatalf("An error occurs when checking legal breaker args %+v: %s", args, err)
+		}
+	}
+}
+
+func TestBreakerStateString(t *testing.T) {
+	cases := map[BreakerState]string{
+		BREAKER_CLOSED:    "closed",
+		BREAKER_OPEN:      "open",
+		BREAKER_HALF_OPEN: "half-open",
+		BreakerState(100): "unknown",
+	}
+	for state, expected := range cases {
+		if actual := state.String(); actual != expected {
+			t.Fatalf("Inconsistent state name: expected: %s, actual: %s", expected, actual)
+		}
+	}
+}
+
+// reportAll 用于依次报告给定的调用结果，其中true代表失败。
+func reportAll(b *breaker, now time.Time, outcomes ...bool) {
+	for _, failed := range outcomes {
+		var err error
+		if failed {
+			err = errBreakerTest
+		}
+		b.report(err, now)
+	}
+}
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBreaker(BreakerArgs{ConsecutiveFailures: 3})
+	reportAll(b, now, true, true, false, true, true)
+	if b.state != BREAKER_CLOSED {
+		t.Fatalf("Inconsistent state: expected: %s, actual: %s", BREAKER_CLOSED, b.state)
+	}
+	reportAll(b, now, true)
+	if b.state != BREAKER_OPEN {
+		t.Fatalf("Inconsistent state: expected: %s, actual: %s", BREAKER_OPEN, b.state)
+	}
+	summary := b.summary("D1")
+	if summary.TripCount != 1 || len(summary.Trips) != 1 || !summary.Trips[0].Time.Equal(now) {
+		t.Fatalf("Inconsistent trips: %+v", summary)
+	}
+}
+
+func TestBreakerFailureRatio(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cases := []struct {
+		args     BreakerArgs
+		outcomes []bool
+		tripped  bool
+		calls    uint32
+		failures uint32
+	}{
+		// 调用次数不足MinCalls时不按失败率断开。
+		{BreakerArgs{FailureRatio: 0.5, WindowSize: 4}, []bool{true, true, true}, false, 3, 3},
+		{BreakerArgs{FailureRatio: 0.5, WindowSize: 4}, []bool{false, true, false, true}, true, 4, 2},
+		{BreakerArgs{FailureRatio: 0.5, WindowSize: 4, MinCalls: 2}, []bool{false, true}, true, 2, 1},
+		{BreakerArgs{FailureRatio: 0.75, WindowSize: 4}, []bool{false, true, false, true}, false, 4, 2},
+		// 统计窗口只包含最近的WindowSize次调用。
+		{BreakerArgs{FailureRatio: 0.75, WindowSize: 4, MinCalls: 4},
+			[]bool{true, true, false, false, false, false, true, true}, false, 4, 2},
+		{BreakerArgs{FailureRatio: 0.75, WindowSize: 4, MinCalls: 4},
+			[]bool{false, false, false, false, false, true, true, true}, true, 4, 3},
+	}
+	for i, c := range cases {
+		b := newBreaker(c.args)
+		reportAll(b, now, c.outcomes...)
+		if tripped := b.state == BREAKER_OPEN; tripped != c.tripped {
+			t.Fatalf("Inconsistent trip for case %d: expected: %v, actual: %v", i, c.tripped, tripped)
+		}
+		summary := b.summary("D1")
+		if summary.WindowCalls != c.calls || summary.WindowFailures != c.failures {
+			t.Fatalf("Inconsistent window for case %d: expected: %d/%d, actual: %d/%d",
+				i, c.failures, c.calls, summary.WindowFailures, summary.WindowCalls)
+		}
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeout := 10 * time.Second
+	b := newBreaker(BreakerArgs{ConsecutiveFailures: 1, OpenTimeout: timeout, HalfOpenCalls: 2})
+	reportAll(b, now, true)
+	// 断开期间开始的调用的结果不影响状态。
+	b.report(nil, now)
+	if b.available(now.Add(timeout-time.Nanosecond)) || b.tryAcquire(now.Add(timeout-time.Nanosecond)) {
+		t.Fatal("The open breaker is available before the open timeout!")
+	}
+	now = now.Add(timeout)
+	if !b.available(now) || b.state != BREAKER_HALF_OPEN {
+		t.Fatalf("Inconsistent state after the open timeout: %s", b.state)
+	}
+	if !b.tryAcquire(now) || !b.tryAcquire(now) {
+		t.Fatal("Couldn't acquire trial calls!")
+	}
+	if b.available(now) || b.tryAcquire(now) {
+		t.Fatal("Too many trial calls are acquired!")
+	}
+	// 被取消的调用只会释放试探调用的机会。
+	b.report(context.Canceled, now)
+	if b.state != BREAKER_HALF_OPEN || !b.tryAcquire(now) {
+		t.Fatalf("Inconsistent state after a canceled trial call: %s", b.state)
+	}
+	b.report(nil, now)
+	if b.state != BREAKER_HALF_OPEN {
+		t.Fatalf("Inconsistent state after a trial success: %s", b.state)
+	}
+	b.report(nil, now)
+	if b.state != BREAKER_CLOSED {
+		t.Fatalf("Inconsistent state after enough trial successes: %s", b.state)
+	}
+	summary := b.summary("D1")
+	if summary.WindowCalls != 0 || summary.ConsecutiveFailures != 0 || summary.TripCount != 1 {
+		t.Fatalf("The window hasn't been cleared after closing: %+v", summary)
+	}
+	// 试探调用失败会使断路器重新断开。
+	reportAll(b, now, true)
+	now = now.Add(timeout)
+	if !b.tryAcquire(now) {
+		t.Fatal("Couldn't acquire a trial call!")
+	}
+	b.report(errBreakerTest, now)
+	if b.state != BREAKER_OPEN || b.summary("D1").TripCount != 3 {
+		t.Fatalf("Inconsistent state after a trial failure: %+v", b.summary("D1"))
+	}
+}
+
+func TestBreakerTryAcquireConcurrently(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBreaker(BreakerArgs{ConsecutiveFailures: 1, OpenTimeout: time.Second, HalfOpenCalls: 3})
+	reportAll(b, now, true)
+	now = now.Add(time.Second)
+	var acquired uint32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.available(now) && b.tryAcquire(now) {
+				atomic.AddUint32(&acquired, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if acquired != 3 {
+		t.Fatalf("Inconsistent number of trial calls: expected: %d, actual: %d", 3, acquired)
+	}
+	b.forceAcquire()
+	if b.trialCalls != 4 {
+		t.Fatalf("Inconsistent number of trial calls: expected: %d, actual: %d", 4, b.trialCalls)
+	}
+}
+
+func TestBreakerHealthCheck(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeout := 10 * time.Second
+	b := newBreaker(BreakerArgs{OpenTimeout: timeout})
+	// 只按健康检查断开时，调用失败不会使断路器断开。
+	reportAll(b, now, true, true, true, true, true)
+	if b.state != BREAKER_CLOSED {
+		t.Fatalf("Inconsistent state: expected: %s, actual: %s", BREAKER_CLOSED, b.state)
+	}
+	b.healthCheck(errBreakerTest, now)
+	if b.state != BREAKER_OPEN {
+		t.Fatalf("Inconsistent state: expected: %s, actual: %s", BREAKER_OPEN, b.state)
+	}
+	// 断开期间的检查失败会推迟进入半开状态的时间，但不会重复记录断开。
+	b.healthCheck(errBreakerTest, now.Add(timeout/2))
+	if b.tryAcquire(now.Add(timeout)) {
+		t.Fatal("The breaker is available before the postponed open timeout!")
+	}
+	if summary := b.summary("D1"); summary.TripCount != 1 {
+		t.Fatalf("Inconsistent trip count: expected: %d, actual: %d", 1, summary.TripCount)
+	}
+	b.healthCheck(nil, now.Add(timeout))
+	if b.state != BREAKER_HALF_OPEN {
+		t.Fatalf("Inconsistent state: expected: %s, actual: %s", BREAKER_HALF_OPEN, b.state)
+	}
+	b.healthCheck(nil, now.Add(timeout))
+	if b.state != BREAKER_HALF_OPEN {
+		t.Fatalf("Inconsistent state: expected: %s, actual: %s", BREAKER_HALF_OPEN, b.state)
+	}
+}
+
+func TestBreakerTripHistory(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBreaker(BreakerArgs{})
+	total := BREAKER_TRIP_HISTORY_SIZE + 3
+	for i := 0; i < total; i++ {
+		b.trip(now.Add(time.Duration(i)*time.Second), fmt.Sprintf("trip %d", i))
+	}
+	summary := b.summary("D1")
+	if summary.TripCount != uint64(total) || len(summary.Trips) != BREAKER_TRIP_HISTORY_SIZE {
+		t.Fatalf("Inconsistent trips: count: %d, history: %d", summary.TripCount, len(summary.Trips))
+	}
+	if first := summary.Trips[0].Reason; first != "trip 3" {
+		t.Fatalf("Inconsistent first trip: expected: %s, actual: %s", "trip 3", first)
+	}
+	// 摘要中的断开记录是副本。
+	summary.Trips[0].Reason = "changed"
+	if b.trips[0].Reason != "trip 3" {
+		t.Fatal("The trip history has been changed through the summary!")
+	}
+}
+
+func TestBreakerSummarySame(t *testing.T) {
+	now := time.Unix(1000, 0)
+	summary := BreakerSummaryStruct{
+		ID:        "D1",
+		State:     BREAKER_OPEN.String(),
+		TripCount: 1,
+		Trips:     []BreakerTripStruct{{Time: now, Reason: "a"}},
+	}
+	same := summary
+	same.Trips = []BreakerTripStruct{{Time: now.In(time.FixedZone("X", 3600)), Reason: "a"}}
+	if !summary.Same(same) {
+		t.Fatal("The same summaries are considered different!")
+	}
+	changes := []func(s *BreakerSummaryStruct){
+		func(s *BreakerSummaryStruct) { s.ID = "D2" },
+		func(s *BreakerSummaryStruct) { s.State = BREAKER_CLOSED.String() },
+		func(s *BreakerSummaryStruct) { s.ConsecutiveFailures = 1 },
+		func(s *BreakerSummaryStruct) { s.WindowCalls = 1 },
+		func(s *BreakerSummaryStruct) { s.WindowFailures = 1 },
+		func(s *BreakerSummaryStruct) { s.TripCount = 2 },
+		func(s *BreakerSummaryStruct) { s.Trips = nil },
+		func(s *BreakerSummaryStruct) { s.Trips = []BreakerTripStruct{{Time: now, Reason: "b"}} },
+		func(s *BreakerSummaryStruct) { s.Trips = []BreakerTripStruct{{Time: now.Add(1), Reason: "a"}} },
+	}
+	for i, change := range changes {
+		another := summary
+		change(&another)
+		if summary.Same(another) {
+			t.Fatalf("The different summaries are considered same! (change %d)", i)
+		}
+	}
+}
+
+func TestRegistrarHalfOpenLimit(t *testing.T) {
+	registrar, err := NewRegistrarWithArgs(RegistrarArgs{
+		Balancers: map[Type]Balancer{TYPE_DOWNLOADER: NewRoundRobinBalancer()},
+		Breaker: BreakerArgs{
+			ConsecutiveFailures: 1,
+			OpenTimeout:         time.Nanosecond,
+			HalfOpenCalls:       1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("An error occurs when creating registrar: %s", err)
+	}
+	downloaders := newFakeDownloaders(t, 2)
+	for _, d := range downloaders {
+		if _, err := registrar.Register(d); err != nil {
+			t.Fatalf("An error occurs when registering module: %s", err)
+		}
+	}
+	failing := downloaders[0].ID()
+	registrar.Report(failing, errBreakerTest)
+	time.Sleep(time.Millisecond)
+	var selected uint32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, err := registrar.Get(TYPE_DOWNLOADER)
+			if err != nil {
+				t.Errorf("An error occurs when getting module: %s", err)
+				return
+			}
+			if m.ID() == failing {
+				atomic.AddUint32(&selected, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if selected != 1 {
+		t.Fatalf("Inconsistent number of trial calls: expected: %d, actual: %d", 1, selected)
+	}
+}
@@ -2,17 +2,33 @@ package module
 
 import (
 	"net/http"
+	"sync/atomic"
+	"time"
 )
 
 type Request struct {
 	httpReq *http.Request
-	depth uint32
+	depth   uint32
+	// priority 代表请求的优先级。值越大越优先。
+	priority int32
+	// attempt 代表请求已被尝试下载的次数。
+	// 调度器在运行中生成检查点时会并发地读取它，因此需要原子地访问。
+	attempt uint32
+	// parentURL 代表发现该请求的页面的URL。
+	parentURL string
+	// anchorText 代表指向该请求的URL的链接文本。
+	anchorText string
+	// discoveredAt 代表该请求被发现的时间。
+	discoveredAt time.Time
+	// attrs 代表用户属性的字典。
+	attrs map[string]interface{}
 }
 
-func NewRequest(httpReq *http.Request, depth uint32) *Request{
+func NewRequest(httpReq *http.Request, depth uint32) *Request {
 	return &Request{
-		httpReq:httpReq,
-		depth:depth,
+		httpReq:      httpReq,
+		depth:        depth,
+		discoveredAt: time.Now(),
 	}
 }
 
@@ -24,19 +40,41 @@ func (req *Request) Depth() uint32 {
 	return req.depth
 }
 
+// Priority 用于获取请求的优先级。
+func (req *Request) Priority() int32 {
+	return req.priority
+}
+
+// SetPriority 用于设置请求的优先级。值越大越优先。
+func (req *Request) SetPriority(priority int32) {
+	req.priority = priority
+}
+
+// Attempt 用于获取请求已被尝试下载的次数。
+func (req *Request) Attempt() uint32 {
+	return atomic.LoadUint32(&req.attempt)
+}
+
+// SetAttempt 用于设置请求已被尝试下载的次数。
+func (req *Request) SetAttempt(attempt uint32) {
+	atomic.StoreUint32(&req.attempt, attempt)
+}
+
 func (req *Request) Valid() bool {
 	return req.httpReq != nil && req.httpReq.URL != nil
 }
 
 type Response struct {
 	httpResp *http.Response
-	depth uint32
+	depth    uint32
+	// req 代表该响应所对应的请求。
+	req *Request
 }
 
-func NewResponse(httpResp *http.Response, depth uint32) *Response{
+func NewResponse(httpResp *http.Response, depth uint32) *Response {
 	return &Response{
-		httpResp:httpResp,
-		depth:depth,
+		httpResp: httpResp,
+		depth:    depth,
 	}
 }
 
@@ -60,4 +98,4 @@ func (item Item) Valid() bool {
 
 type Data interface {
 	Valid() bool
-}
\ No newline at end of file
+}
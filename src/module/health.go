@@ -0,0 +1,51 @@
+package module
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HealthChecker 代表可以被主动检查健康状况的组件的接口类型。
+// 组件可以选择性地实现该接口。
+type HealthChecker interface {
+	// HealthCheck 用于检查组件的健康状况。结果值不为nil代表组件不健康。
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheckFunc 代表组件健康检查函数的类型。
+type HealthCheckFunc func(ctx context.Context, module Module) error
+
+// checkHealth 用于检查给定组件的健康状况。
+// 参数check为nil时只检查实现了HealthChecker接口的组件。
+// 结果值checked为false代表该组件无法被检查。
+func checkHealth(ctx context.Context, check HealthCheckFunc, module Module) (checked bool, err error) {
+	if check != nil {
+		return true, check(ctx, module)
+	}
+	if checker, ok := module.(HealthChecker); ok {
+		return true, checker.HealthCheck(ctx)
+	}
+	return false, nil
+}
+
+// CheckHealth 会并发地检查所有已注册的组件的健康状况，
+// 并根据结果改变相应的断路器的状态。
+func (registrar *myRegistrar) CheckHealth(ctx context.Context) {
+	moduleMap := registrar.GetAll()
+	var wg sync.WaitGroup
+	wg.Add(len(moduleMap))
+	for mid, module := range moduleMap {
+		go func(mid MID, module Module) {
+			defer wg.Done()
+			checked, err := checkHealth(ctx, registrar.healthCheck, module)
+			if !checked || ctx.Err() != nil {
+				return
+			}
+			if b := registrar.breakerOf(mid); b != nil {
+				b.healthCheck(err, time.Now())
+			}
+		}(mid, module)
+	}
+	wg.Wait()
+}
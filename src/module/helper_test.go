@@ -0,0 +1,88 @@
+package module
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDownloader 代表测试用的下载器。
+// 它的评分和正在处理的数据的数量可以被直接设定。
+type fakeDownloader struct {
+	mid      MID
+	score    uint64
+	handling uint64
+}
+
+// newFakeDownloaders 会创建给定数量的测试用的下载器，它们的序列号从1开始。
+func newFakeDownloaders(t *testing.T, number int) []*fakeDownloader {
+	downloaders := make([]*fakeDownloader, number)
+	for i := range downloaders {
+		mid, err := GenMID(TYPE_DOWNLOADER, uint64(i+1), nil)
+		if err != nil {
+			t.Fatalf("An error occurs when generating MID: %s", err)
+		}
+		downloaders[i] = &fakeDownloader{mid: mid}
+	}
+	return downloaders
+}
+
+// modulesOf 用于把测试用的下载器转换为组件实例的列表。
+func modulesOf(downloaders []*fakeDownloader) []Module {
+	modules := make([]Module, len(downloaders))
+	for i, d := range downloaders {
+		modules[i] = d
+	}
+	return modules
+}
+
+func (d *fakeDownloader) ID() MID {
+	return d.mid
+}
+
+func (d *fakeDownloader) Addr() string {
+	return ""
+}
+
+func (d *fakeDownloader) Score() uint64 {
+	return atomic.LoadUint64(&d.score)
+}
+
+func (d *fakeDownloader) SetScore(score uint64) {
+	atomic.StoreUint64(&d.score, score)
+}
+
+// ScoreCalculator 会返回保持当前评分不变的评分计算函数。
+func (d *fakeDownloader) ScoreCalculator() CalculateScore {
+	return func(Counts) uint64 {
+		return d.Score()
+	}
+}
+
+func (d *fakeDownloader) CalledCount() uint64 {
+	return 0
+}
+
+func (d *fakeDownloader) AcceptedCount() uint64 {
+	return 0
+}
+
+func (d *fakeDownloader) CompletedCount() uint64 {
+	return 0
+}
+
+func (d *fakeDownloader) HandlingNumber() uint64 {
+	return atomic.LoadUint64(&d.handling)
+}
+
+func (d *fakeDownloader) Counts() Counts {
+	return Counts{HandlingNumber: d.HandlingNumber()}
+}
+
+func (d *fakeDownloader) Summary() SummaryStruct {
+	return SummaryStruct{ID: d.mid}
+}
+
+func (d *fakeDownloader) Download(ctx context.Context, req *Request) (*Response, error) {
+	return nil, nil
+}
@@ -0,0 +1,44 @@
+package module
+
+import (
+	"time"
+)
+
+// LatencyBucketBounds 代表处理耗时直方图中各个桶的上界（包含）。
+// 最后一个桶没有上界，用于存放超过所有上界的耗时。
+var LatencyBucketBounds = [LATENCY_BUCKET_NUMBER - 1]time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LATENCY_BUCKET_NUMBER 代表处理耗时直方图中桶的数量。
+const LATENCY_BUCKET_NUMBER = 10
+
+// LATENCY_EWMA_ALPHA 代表计算处理耗时的指数加权移动平均值时新样本的权重。
+const LATENCY_EWMA_ALPHA = 0.2
+
+// LatencyBucketIndex 用于获取给定的耗时所属的桶的序号。
+func LatencyBucketIndex(latency time.Duration) int {
+	for i, bound := range LatencyBucketBounds {
+		if latency <= bound {
+			return i
+		}
+	}
+	return LATENCY_BUCKET_NUMBER - 1
+}
+
+// LatencySummaryStruct 代表组件处理耗时的摘要类型。
+type LatencySummaryStruct struct {
+	// EWMA 代表处理耗时的指数加权移动平均值。
+	EWMA time.Duration `json:"ewma"`
+	// Buckets 代表处理耗时直方图中各个桶的计数。
+	// 各桶的上界见LatencyBucketBounds。
+	Buckets [LATENCY_BUCKET_NUMBER]uint64 `json:"buckets"`
+}
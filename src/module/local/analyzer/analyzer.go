@@ -1,10 +1,12 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"module"
 	"module/stub"
+	"time"
 	"toolkit/reader"
 )
 
@@ -46,6 +48,7 @@ func (analyzer *myAnalyzer) RespParsers() []module.ParseResponse {
 }
 
 func (analyzer *myAnalyzer) Analyze(
+	ctx context.Context,
 	resp *module.Response) (dataList []module.Data, errorList []error) {
 	analyzer.ModuleInternal.IncrHandlingNumber()
 	defer analyzer.ModuleInternal.DecrHandlingNumber()
@@ -74,6 +77,13 @@ func (analyzer *myAnalyzer) Analyze(
 		return
 	}
 	analyzer.ModuleInternal.IncrAcceptedCount()
+	startTime := time.Now()
+	defer func() {
+		analyzer.ModuleInternal.ObserveLatency(time.Since(startTime))
+		if len(errorList) > 0 {
+			analyzer.ModuleInternal.IncrFailedCount()
+		}
+	}()
 	respDepth := resp.Depth()
 	log.Printf("Parse the response (URL: %s, depth: %d)... \n",
 		reqURL, respDepth)
@@ -81,6 +91,12 @@ func (analyzer *myAnalyzer) Analyze(
 	if httpResp.Body != nil {
 		defer httpResp.Body.Close()
 	}
+	// 让响应解析函数可以通过module.RequestOf函数获取请求的元数据。
+	parentReq := resp.Request()
+	if parentReq != nil {
+		httpResp.Request = httpReq.WithContext(
+			module.ContextWithRequest(httpReq.Context(), parentReq))
+	}
 	multipleReader, err := reader.NewMultipleReader(httpResp.Body)
 	if err != nil {
 		errorList = append(errorList, genError(err.Error()))
@@ -88,6 +104,10 @@ func (analyzer *myAnalyzer) Analyze(
 	}
 	dataList = []module.Data{}
 	for _, respParser := range analyzer.respParsers {
+		if err := ctx.Err(); err != nil {
+			errorList = append(errorList, err)
+			break
+		}
 		httpResp.Body = multipleReader.Reader()
 		pDataList, pErrorList := respParser(httpResp, respDepth)
 		if pDataList != nil {
@@ -95,7 +115,7 @@ func (analyzer *myAnalyzer) Analyze(
 				if pData == nil {
 					continue
 				}
-				dataList = appendDataList(dataList, pData, respDepth)
+				dataList = appendDataList(dataList, pData, respDepth, reqURL.String(), parentReq)
 			}
 		}
 		if pErrorList != nil {
@@ -114,17 +134,34 @@ func (analyzer *myAnalyzer) Analyze(
 }
 
 // appendDataList 用于添加请求值或条目值到列表。
-func appendDataList(dataList []module.Data, data module.Data, respDepth uint32) []module.Data {
+// 参数respURL代表响应所对应的请求的URL。
+// 参数parentReq代表响应所对应的请求，可能为nil。
+// 新请求会被记录来源页面，新条目会被附加来源请求的元数据。
+func appendDataList(
+	dataList []module.Data,
+	data module.Data,
+	respDepth uint32,
+	respURL string,
+	parentReq *module.Request) []module.Data {
 	if data == nil {
 		return dataList
 	}
+	if item, ok := data.(module.Item); ok {
+		if _, exists := item[module.ITEM_KEY_META]; !exists && parentReq != nil {
+			item[module.ITEM_KEY_META] = parentReq.Meta()
+		}
+		return append(dataList, item)
+	}
 	req, ok := data.(*module.Request)
 	if !ok {
 		return append(dataList, data)
 	}
 	newDepth := respDepth + 1
 	if req.Depth() != newDepth {
-		req = module.NewRequest(req.HTTPReq(), newDepth)
+		req = req.WithDepth(newDepth)
+	}
+	if req.ParentURL() == "" {
+		req.SetParentURL(respURL)
 	}
 	return append(dataList, req)
 }
@@ -1,10 +1,12 @@
 package downloader
 
 import (
+	"context"
 	"log"
 	"module"
 	"module/stub"
 	"net/http"
+	"time"
 )
 
 type myDownloader struct {
@@ -29,7 +31,10 @@ func New(
 	}, nil
 }
 
-func (downloader *myDownloader) Download(req *module.Request) (*module.Response, error) {
+// Download 会执行给定的请求。
+// 参数ctx被取消时，请求以及对响应体的读取都会被中止。
+func (downloader *myDownloader) Download(
+	ctx context.Context, req *module.Request) (*module.Response, error) {
 	downloader.ModuleInternal.IncrHandlingNumber()
 	defer downloader.ModuleInternal.DecrHandlingNumber()
 	downloader.ModuleInternal.IncrCalledCount()
@@ -42,10 +47,15 @@ func (downloader *myDownloader) Download(req *module.Request) (*module.Response,
 	}
 	downloader.ModuleInternal.IncrAcceptedCount()
 	log.Printf("Do the request (URL: %s, depth: %d)... \n", httpReq.URL, req.Depth())
-	httpResp, err := downloader.httpClient.Do(httpReq)
+	startTime := time.Now()
+	httpResp, err := downloader.httpClient.Do(httpReq.WithContext(ctx))
+	downloader.ModuleInternal.ObserveLatency(time.Since(startTime))
 	if err != nil {
+		downloader.ModuleInternal.IncrFailedCount()
 		return nil, err
 	}
 	downloader.ModuleInternal.IncrCompletedCount()
-	return module.NewResponse(httpResp, req.Depth()), nil
+	resp := module.NewResponse(httpResp, req.Depth())
+	resp.SetRequest(req)
+	return resp, nil
 }
@@ -1,10 +1,12 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"module"
 	"module/stub"
+	"time"
 )
 
 type myPipeline struct {
@@ -47,7 +49,7 @@ func (pipeline *myPipeline) ItemProcessors() []module.ProcessItem {
 	return processors
 }
 
-func (pipeline *myPipeline) Send(item module.Item) []error {
+func (pipeline *myPipeline) Send(ctx context.Context, item module.Item) []error {
 	pipeline.ModuleInternal.IncrHandlingNumber()
 	defer pipeline.ModuleInternal.DecrHandlingNumber()
 	pipeline.ModuleInternal.IncrCalledCount()
@@ -58,9 +60,20 @@ func (pipeline *myPipeline) Send(item module.Item) []error {
 		return errs
 	}
 	pipeline.ModuleInternal.IncrAcceptedCount()
+	startTime := time.Now()
+	defer func() {
+		pipeline.ModuleInternal.ObserveLatency(time.Since(startTime))
+		if len(errs) > 0 {
+			pipeline.ModuleInternal.IncrFailedCount()
+		}
+	}()
 	log.Printf("Process item %+v... \n", item)
 	var currentItem = item
 	for _, processor := range pipeline.itemProcessors {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
 		processedItem, err := processor(currentItem)
 		if err != nil {
 			errs = append(errs, err)
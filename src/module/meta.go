@@ -0,0 +1,146 @@
+package module
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ITEM_KEY_META 代表条目中存放来源请求的元数据的键。
+// 分析器会为解析函数生成的每个条目设置该键，除非它已存在。
+const ITEM_KEY_META = "_meta"
+
+// ItemMeta 代表条目的来源请求的元数据。
+type ItemMeta struct {
+	// URL 代表产生条目的响应所对应的请求的URL。
+	URL          string                 `json:"url"`
+	ParentURL    string                 `json:"parent_url,omitempty"`
+	AnchorText   string                 `json:"anchor_text,omitempty"`
+	DiscoveredAt time.Time              `json:"discovered_at"`
+	Depth        uint32                 `json:"depth"`
+	Attempt      uint32                 `json:"attempt"`
+	Attrs        map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// ParentURL 用于获取发现该请求的页面的URL。为空代表该请求是种子。
+func (req *Request) ParentURL() string {
+	return req.parentURL
+}
+
+// SetParentURL 用于设置发现该请求的页面的URL。
+func (req *Request) SetParentURL(parentURL string) {
+	req.parentURL = parentURL
+}
+
+// AnchorText 用于获取指向该请求的URL的链接文本。
+func (req *Request) AnchorText() string {
+	return req.anchorText
+}
+
+// SetAnchorText 用于设置指向该请求的URL的链接文本。
+func (req *Request) SetAnchorText(anchorText string) {
+	req.anchorText = anchorText
+}
+
+// DiscoveredAt 用于获取该请求被发现的时间。
+func (req *Request) DiscoveredAt() time.Time {
+	return req.discoveredAt
+}
+
+// SetDiscoveredAt 用于设置该请求被发现的时间。
+func (req *Request) SetDiscoveredAt(discoveredAt time.Time) {
+	req.discoveredAt = discoveredAt
+}
+
+// Attr 用于获取该请求的用户属性。
+// 第二个结果值代表该属性是否存在。
+func (req *Request) Attr(key string) (interface{}, bool) {
+	value, ok := req.attrs[key]
+	return value, ok
+}
+
+// SetAttr 用于设置该请求的用户属性。
+// 注意！需要保存到检查点或磁盘上的属性值必须能被编码为JSON。
+func (req *Request) SetAttr(key string, value interface{}) {
+	if req.attrs == nil {
+		req.attrs = map[string]interface{}{}
+	}
+	req.attrs[key] = value
+}
+
+// Attrs 用于获取该请求的所有用户属性的副本。
+func (req *Request) Attrs() map[string]interface{} {
+	if len(req.attrs) == 0 {
+		return nil
+	}
+	attrs := make(map[string]interface{}, len(req.attrs))
+	for key, value := range req.attrs {
+		attrs[key] = value
+	}
+	return attrs
+}
+
+// WithDepth 会生成一个深度不同的请求的副本。
+// 优先级、尝试次数以及所有的元数据都会被保留。
+func (req *Request) WithDepth(depth uint32) *Request {
+	newReq := *req
+	newReq.depth = depth
+	newReq.attrs = req.Attrs()
+	return &newReq
+}
+
+// Meta 用于生成该请求的元数据。
+func (req *Request) Meta() ItemMeta {
+	meta := ItemMeta{
+		ParentURL:    req.parentURL,
+		AnchorText:   req.anchorText,
+		DiscoveredAt: req.discoveredAt,
+		Depth:        req.depth,
+		Attempt:      req.Attempt(),
+		Attrs:        req.Attrs(),
+	}
+	if req.Valid() {
+		meta.URL = req.httpReq.URL.String()
+	}
+	return meta
+}
+
+// Request 用于获取该响应所对应的请求。可能为nil。
+func (resp *Response) Request() *Request {
+	return resp.req
+}
+
+// SetRequest 用于设置该响应所对应的请求。
+func (resp *Response) SetRequest(req *Request) {
+	resp.req = req
+}
+
+// Meta 用于获取条目的来源请求的元数据。
+// 第二个结果值代表条目中是否存在元数据。
+func (item Item) Meta() (ItemMeta, bool) {
+	meta, ok := item[ITEM_KEY_META].(ItemMeta)
+	return meta, ok
+}
+
+// requestContextKey 代表上下文中存放请求的键的类型。
+type requestContextKey struct{}
+
+// ContextWithRequest 会生成一个携带给定请求的上下文。
+func ContextWithRequest(ctx context.Context, req *Request) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, req)
+}
+
+// RequestFromContext 用于获取给定上下文携带的请求。可能为nil。
+func RequestFromContext(ctx context.Context) *Request {
+	req, _ := ctx.Value(requestContextKey{}).(*Request)
+	return req
+}
+
+// RequestOf 用于在响应解析函数中获取给定HTTP响应所对应的请求，
+// 从而读取它的元数据。可能为nil。
+func RequestOf(httpResp *http.Response) *Request {
+	if httpResp == nil || httpResp.Request == nil {
+		return nil
+	}
+	return RequestFromContext(httpResp.Request.Context())
+}
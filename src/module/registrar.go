@@ -1,29 +1,109 @@
 package module
 
 import (
+	"context"
 	"errs"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
 type Registrar interface {
 	Register(module Module) (bool, error)
 	Unregister(mid MID) (bool, error)
 	Get(moduleType Type) (Module, error)
+	// GetByKey 用于基于给定的键获取一个指定类型的组件的实例。
+	// 只有基于一致性哈希的负载均衡策略会使用该键。
+	GetByKey(moduleType Type, key string) (Module, error)
 	GetAllByType(moduleType Type) (map[MID]Module, error)
 	GetAll() map[MID]Module
+	// Balancer 用于获取指定类型的组件所用的负载均衡器。
+	Balancer(moduleType Type) Balancer
+	// Report 用于报告对给定组件的一次调用的结果。
+	// 参数err为nil代表调用成功。
+	// 每次通过Get或GetByKey获取组件并调用之后都应该报告结果。
+	Report(mid MID, err error)
+	// InUse 用于获取给定组件已通过Get或GetByKey被选出但尚未报告结果的调用数。
+	// 组件被注销之后该数量仍然有效，可用于等待已被选出的调用完成。
+	InUse(mid MID) uint64
+	// CheckHealth 会对所有已注册的组件执行一轮主动健康检查。
+	CheckHealth(ctx context.Context)
+	// BreakerSummaries 用于获取所有已注册的组件的断路器的摘要，按组件ID排序。
+	BreakerSummaries() []BreakerSummaryStruct
 	Clear()
 }
 
+// RegistrarArgs 代表组件注册器相关的参数容器的类型。
+type RegistrarArgs struct {
+	// Balancers 代表各类型的组件所用的负载均衡器。
+	// 未指定的类型使用选择评分最低的实例的负载均衡策略。
+	Balancers map[Type]Balancer
+	// Breaker 代表每个组件实例的断路器的参数。
+	Breaker BreakerArgs
+	// HealthCheck 代表主动健康检查函数。
+	// 为nil时只检查实现了HealthChecker接口的组件。
+	HealthCheck HealthCheckFunc
+}
+
 type myRegistrar struct {
 	moduleTypeMap map[Type]map[MID]Module
-	rwlock        sync.RWMutex
+	// balancerMap 代表组件类型与负载均衡器的字典。
+	balancerMap map[Type]Balancer
+	// breakerArgs 代表断路器的参数。
+	breakerArgs BreakerArgs
+	// breakerMap 代表组件ID与断路器的字典。
+	breakerMap map[MID]*breaker
+	// inUseMap 代表组件ID与已被选出但尚未报告结果的调用数的字典。
+	// 组件被注销之后，其记录会一直保留到所有调用都报告了结果为止。
+	inUseMap map[MID]uint64
+	// healthCheck 代表主动健康检查函数。
+	healthCheck HealthCheckFunc
+	rwlock      sync.RWMutex
 }
 
+// NewRegistrar 会创建一个组件注册器。
+// 各类型的组件都使用选择评分最低的实例的负载均衡策略。
 func NewRegistrar() Registrar {
+	registrar, _ := NewRegistrarWithBalancers(nil)
+	return registrar
+}
+
+// NewRegistrarWithBalancers 会创建一个为各类型的组件使用给定负载均衡器的组件注册器。
+// 参数balancers中未指定的类型使用选择评分最低的实例的负载均衡策略。
+func NewRegistrarWithBalancers(balancers map[Type]Balancer) (Registrar, error) {
+	return NewRegistrarWithArgs(RegistrarArgs{Balancers: balancers})
+}
+
+// NewRegistrarWithArgs 会根据给定的参数创建一个组件注册器。
+// 注册器会为每个组件实例维护一个断路器，
+// 获取组件实例时会跳过断路器断开的实例。
+func NewRegistrarWithArgs(args RegistrarArgs) (Registrar, error) {
+	if err := args.Breaker.Check(); err != nil {
+		return nil, err
+	}
+	balancerMap := map[Type]Balancer{}
+	for moduleType := range legalTypeLetterMap {
+		balancerMap[moduleType] = NewMinScoreBalancer()
+	}
+	for moduleType, balancer := range args.Balancers {
+		if !LegalType(moduleType) {
+			errMsg := fmt.Sprintf("illegal module type for balancer: %s", moduleType)
+			return nil, errs.NewIllegalParameterError(errMsg)
+		}
+		if balancer == nil {
+			continue
+		}
+		balancerMap[moduleType] = balancer
+	}
 	return &myRegistrar{
 		moduleTypeMap: map[Type]map[MID]Module{},
-	}
+		balancerMap:   balancerMap,
+		breakerArgs:   args.Breaker,
+		breakerMap:    map[MID]*breaker{},
+		inUseMap:      map[MID]uint64{},
+		healthCheck:   args.HealthCheck,
+	}, nil
 }
 
 func (registrar *myRegistrar) Register(module Module) (bool, error) {
@@ -51,6 +131,7 @@ func (registrar *myRegistrar) Register(module Module) (bool, error) {
 	}
 	modules[mid] = module
 	registrar.moduleTypeMap[moduleType] = modules
+	registrar.breakerMap[mid] = newBreaker(registrar.breakerArgs)
 	return true, nil
 }
 
@@ -66,6 +147,7 @@ func (registrar *myRegistrar) Unregister(mid MID) (bool, error) {
 	if modules, ok := registrar.moduleTypeMap[moduleType]; ok {
 		if _, ok := modules[mid]; ok {
 			delete(modules, mid)
+			delete(registrar.breakerMap, mid)
 			deleted = true
 		}
 	}
@@ -75,24 +157,126 @@ func (registrar *myRegistrar) Unregister(mid MID) (bool, error) {
 // Get 用于获取一个指定类型的组件的实例。
 // 本函数会基于负载均衡策略返回实例。
 func (registrar *myRegistrar) Get(moduleType Type) (Module, error) {
-	modules, err := registrar.GetAllByType(moduleType)
+	return registrar.GetByKey(moduleType, "")
+}
+
+func (registrar *myRegistrar) GetByKey(moduleType Type, key string) (Module, error) {
+	moduleMap, err := registrar.GetAllByType(moduleType)
 	if err != nil {
 		return nil, err
 	}
-	minScore := uint64(0)
-	var selectedModule Module
-	for _, module := range modules {
+	modules := make([]Module, 0, len(moduleMap))
+	available := make([]Module, 0, len(moduleMap))
+	now := time.Now()
+	for mid, module := range moduleMap {
 		SetScore(module)
-		if err != nil {
-			return nil, err
+		modules = append(modules, module)
+		if b := registrar.breakerOf(mid); b == nil || b.available(now) {
+			available = append(available, module)
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].ID() < modules[j].ID()
+	})
+	sort.Slice(available, func(i, j int) bool {
+		return available[i].ID() < available[j].ID()
+	})
+	balancer := registrar.Balancer(moduleType)
+	// 被选中的实例的断路器可能已被并发的选择占满，或者该实例已被并发地注销，
+	// 此时排除该实例并重新选择。
+	for len(available) > 0 {
+		selected := balancer.Select(available, key)
+		if registrar.acquire(selected.ID(), now, false) {
+			return selected, nil
 		}
-		score := module.Score()
-		if minScore == 0 || score < minScore {
-			selectedModule = module
-			minScore = score
+		available = removeModule(available, selected.ID())
+	}
+	// 所有实例的断路器都不可用时，仍然从所有实例中选择，以免数据被丢弃。
+	for len(modules) > 0 {
+		selected := balancer.Select(modules, key)
+		if registrar.acquire(selected.ID(), now, true) {
+			return selected, nil
 		}
+		modules = removeModule(modules, selected.ID())
+	}
+	return nil, ErrNotFoundModuleInstance
+}
+
+// acquire 用于在给定组件仍被注册且其断路器允许调用时占用该组件，
+// 并增加该组件的使用数。参数force为true时不检查断路器。
+// 检查和占用在同一次加锁中完成，以免注销组件时漏掉刚被选出的调用。
+func (registrar *myRegistrar) acquire(mid MID, now time.Time, force bool) bool {
+	registrar.rwlock.Lock()
+	defer registrar.rwlock.Unlock()
+	b, ok := registrar.breakerMap[mid]
+	if !ok {
+		return false
+	}
+	if force {
+		b.forceAcquire()
+	} else if !b.tryAcquire(now) {
+		return false
+	}
+	registrar.inUseMap[mid]++
+	return true
+}
+
+// removeModule 用于从给定的组件实例列表中删除具有给定ID的实例，
+// 并返回新的列表。原列表不会被修改。
+func removeModule(modules []Module, mid MID) []Module {
+	result := make([]Module, 0, len(modules))
+	for _, m := range modules {
+		if m.ID() != mid {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func (registrar *myRegistrar) Report(mid MID, err error) {
+	registrar.rwlock.Lock()
+	if n := registrar.inUseMap[mid]; n > 1 {
+		registrar.inUseMap[mid] = n - 1
+	} else {
+		delete(registrar.inUseMap, mid)
+	}
+	registrar.rwlock.Unlock()
+	if b := registrar.breakerOf(mid); b != nil {
+		b.report(err, time.Now())
+	}
+}
+
+func (registrar *myRegistrar) InUse(mid MID) uint64 {
+	registrar.rwlock.RLock()
+	defer registrar.rwlock.RUnlock()
+	return registrar.inUseMap[mid]
+}
+
+func (registrar *myRegistrar) BreakerSummaries() []BreakerSummaryStruct {
+	registrar.rwlock.RLock()
+	summaries := make([]BreakerSummaryStruct, 0, len(registrar.breakerMap))
+	for mid, b := range registrar.breakerMap {
+		summaries = append(summaries, b.summary(mid))
+	}
+	registrar.rwlock.RUnlock()
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ID < summaries[j].ID
+	})
+	return summaries
+}
+
+// breakerOf 用于获取给定组件的断路器。组件未注册时返回nil。
+func (registrar *myRegistrar) breakerOf(mid MID) *breaker {
+	registrar.rwlock.RLock()
+	defer registrar.rwlock.RUnlock()
+	return registrar.breakerMap[mid]
+}
+
+func (registrar *myRegistrar) Balancer(moduleType Type) Balancer {
+	if balancer, ok := registrar.balancerMap[moduleType]; ok {
+		return balancer
 	}
-	return selectedModule, nil
+	return NewMinScoreBalancer()
 }
 
 // GetAllByType 用于获取指定类型的所有组件实例。
@@ -132,4 +316,6 @@ func (registrar *myRegistrar) Clear() {
 	registrar.rwlock.Lock()
 	defer registrar.rwlock.Unlock()
 	registrar.moduleTypeMap = map[Type]map[MID]Module{}
+	registrar.breakerMap = map[MID]*breaker{}
+	registrar.inUseMap = map[MID]uint64{}
 }
@@ -0,0 +1,75 @@
+package module
+
+import (
+	"testing"
+)
+
+func TestRegistrarInUse(t *testing.T) {
+	registrar := NewRegistrar()
+	downloaders := newFakeDownloaders(t, 2)
+	for _, d := range downloaders {
+		if _, err := registrar.Register(d); err != nil {
+			t.Fatalf("An error occurs when registering module: %s", err)
+		}
+	}
+	m, err := registrar.Get(TYPE_DOWNLOADER)
+	if err != nil {
+		t.Fatalf("An error occurs when getting module: %s", err)
+	}
+	mid := m.ID()
+	if inUse := registrar.InUse(mid); inUse != 1 {
+		t.Fatalf("Inconsistent in-use number: expected: %d, actual: %d", 1, inUse)
+	}
+	if ok, _ := registrar.Unregister(mid); !ok {
+		t.Fatalf("Couldn't unregister the module %q!", mid)
+	}
+	// 已被选出的调用在组件被注销之后仍然会被计数。
+	if inUse := registrar.InUse(mid); inUse != 1 {
+		t.Fatalf("Inconsistent in-use number after unregistering: expected: %d, actual: %d",
+			1, inUse)
+	}
+	for i := 0; i < 10; i++ {
+		other, err := registrar.Get(TYPE_DOWNLOADER)
+		if err != nil {
+			t.Fatalf("An error occurs when getting module: %s", err)
+		}
+		if other.ID() == mid {
+			t.Fatalf("The unregistered module %q was selected!", mid)
+		}
+		registrar.Report(other.ID(), nil)
+	}
+	registrar.Report(mid, nil)
+	if inUse := registrar.InUse(mid); inUse != 0 {
+		t.Fatalf("Inconsistent in-use number after reporting: expected: %d, actual: %d",
+			0, inUse)
+	}
+	// 多余的报告不会使计数变为负数。
+	registrar.Report(mid, nil)
+	if inUse := registrar.InUse(mid); inUse != 0 {
+		t.Fatalf("Inconsistent in-use number after reporting again: expected: %d, actual: %d",
+			0, inUse)
+	}
+}
+
+func TestRegistrarGetUnregistered(t *testing.T) {
+	registrar := NewRegistrar()
+	downloaders := newFakeDownloaders(t, 1)
+	registrar.Register(downloaders[0])
+	registrar.Unregister(downloaders[0].ID())
+	if _, err := registrar.Get(TYPE_DOWNLOADER); err != ErrNotFoundModuleInstance {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v", ErrNotFoundModuleInstance, err)
+	}
+}
+
+func TestRegistrarClearInUse(t *testing.T) {
+	registrar := NewRegistrar()
+	downloaders := newFakeDownloaders(t, 1)
+	registrar.Register(downloaders[0])
+	if _, err := registrar.Get(TYPE_DOWNLOADER); err != nil {
+		t.Fatalf("An error occurs when getting module: %s", err)
+	}
+	registrar.Clear()
+	if inUse := registrar.InUse(downloaders[0].ID()); inUse != 0 {
+		t.Fatalf("Inconsistent in-use number after clearing: expected: %d, actual: %d", 0, inUse)
+	}
+}
@@ -0,0 +1,88 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"errs"
+	"module"
+	"net/http"
+	"time"
+)
+
+// myAnalyzer 代表远程分析器的客户端代理的实现类型。
+type myAnalyzer struct {
+	*myRemoteModule
+	// maxBodyBytes 代表可以被发送的响应体的最大字节数。
+	maxBodyBytes int64
+}
+
+// NewAnalyzer 会创建一个远程分析器的客户端代理。
+// 参数mid中必须包含远程组件服务的网络地址，且与远程分析器的ID相同。
+// 参数client代表调用远程组件服务时所用的HTTP客户端。
+func NewAnalyzer(
+	mid module.MID,
+	client *http.Client,
+	scoreCalculator module.CalculateScore) (module.Analyzer, error) {
+	base, err := newRemoteModule(mid, client, scoreCalculator)
+	if err != nil {
+		return nil, genParameterError(errs.ERROR_TYPE_ANALYZER, err.Error())
+	}
+	return &myAnalyzer{
+		myRemoteModule: base,
+		maxBodyBytes:   DEFAULT_MAX_BODY_BYTES,
+	}, nil
+}
+
+// RespParsers 总会返回nil，因为响应解析函数都位于远程分析器中。
+func (analyzer *myAnalyzer) RespParsers() []module.ParseResponse {
+	return nil
+}
+
+// Analyze 会把给定的响应连同响应体发送给远程分析器并还原分析的结果。
+// 响应体会被读出并关闭。
+func (analyzer *myAnalyzer) Analyze(
+	ctx context.Context, resp *module.Response) (dataList []module.Data, errorList []error) {
+	analyzer.ModuleInternal.IncrHandlingNumber()
+	defer analyzer.ModuleInternal.DecrHandlingNumber()
+	analyzer.ModuleInternal.IncrCalledCount()
+	if resp == nil || !resp.Valid() {
+		errorList = append(errorList,
+			genParameterError(errs.ERROR_TYPE_ANALYZER, "invalid response"))
+		return
+	}
+	respData, err := encodeResponse(resp, analyzer.maxBodyBytes)
+	if err != nil {
+		errorList = append(errorList, genError(errs.ERROR_TYPE_ANALYZER, err.Error()))
+		return
+	}
+	analyzer.ModuleInternal.IncrAcceptedCount()
+	startTime := time.Now()
+	defer func() {
+		analyzer.ModuleInternal.ObserveLatency(time.Since(startTime))
+		if len(errorList) > 0 {
+			analyzer.ModuleInternal.IncrFailedCount()
+		}
+	}()
+	var result analyzeResult
+	err = analyzer.client.call(ctx, METHOD_ANALYZE,
+		analyzeParams{baseParams: analyzer.params(), Response: respData}, &result)
+	if err != nil {
+		errorList = append(errorList, err)
+		return
+	}
+	for _, envelope := range result.Data {
+		data, err := envelope.decode()
+		if err != nil {
+			errorList = append(errorList, genError(errs.ERROR_TYPE_ANALYZER, err.Error()))
+			continue
+		}
+		dataList = append(dataList, data)
+	}
+	for _, errMsg := range result.Errors {
+		errorList = append(errorList, errors.New(errMsg))
+	}
+	if len(errorList) == 0 {
+		analyzer.ModuleInternal.IncrCompletedCount()
+	}
+	return
+}
@@ -0,0 +1,18 @@
+// Package remote 提供了通过HTTP/JSON-RPC调用远程组件的客户端代理，
+// 以及把本地组件暴露为远程组件服务的服务端。
+// 远程组件的ID中的网络地址即为其服务所在的地址。
+package remote
+
+import (
+	"context"
+	"module"
+)
+
+// Module 代表远程组件的客户端代理的接口类型。
+// 由本包创建的下载器、分析器和条目处理管道都实现了该接口。
+type Module interface {
+	module.Module
+	module.HealthChecker
+	// RemoteSummary 用于获取远程组件自身的摘要。
+	RemoteSummary(ctx context.Context) (module.SummaryStruct, error)
+}
@@ -0,0 +1,138 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errs"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"module"
+	"module/stub"
+	"net/http"
+	"sync/atomic"
+)
+
+// MAX_MESSAGE_BYTES 代表远程调用的请求或结果的最大字节数。
+const MAX_MESSAGE_BYTES = 64 << 20
+
+// rpcClient 代表远程组件服务的客户端。
+type rpcClient struct {
+	// endpoint 代表远程组件服务的URL。
+	endpoint   string
+	httpClient http.Client
+	// lastID 代表最近一次调用所用的ID。
+	lastID uint64
+}
+
+// newRPCClient 会创建一个调用给定地址上的远程组件服务的客户端。
+func newRPCClient(addr string, client *http.Client) *rpcClient {
+	return &rpcClient{
+		endpoint:   "http://" + addr + RPC_PATH,
+		httpClient: *client,
+	}
+}
+
+// call 用于调用远程组件服务的方法，并把结果解码到result中。
+// 远程组件处理失败时，错误值的类型为*RPCError。
+func (client *rpcClient) call(
+	ctx context.Context, method string, params interface{}, result interface{}) error {
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := atomic.AddUint64(&client.lastID, 1)
+	reqBytes, err := json.Marshal(rpcRequest{
+		JSONRPC: JSONRPC_VERSION,
+		ID:      id,
+		Method:  method,
+		Params:  paramsBytes,
+	})
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, client.endpoint, bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpResp, err := client.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	respBytes, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, MAX_MESSAGE_BYTES+1))
+	if err != nil {
+		return err
+	}
+	if len(respBytes) > MAX_MESSAGE_BYTES {
+		return fmt.Errorf("too large result of %s (limit: %d bytes)", method, MAX_MESSAGE_BYTES)
+	}
+	var rpcResp rpcResponse
+	if err := json.Unmarshal(respBytes, &rpcResp); err != nil {
+		return fmt.Errorf("invalid result of %s (status code: %d): %s",
+			method, httpResp.StatusCode, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if rpcResp.ID != id {
+		return fmt.Errorf("inconsistent ID in result of %s: expected: %d, actual: %d",
+			method, id, rpcResp.ID)
+	}
+	if result == nil || len(rpcResp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// myRemoteModule 代表远程组件的客户端代理的基础类型。
+// 它在本地记录调用计数和耗时，而不是使用远程组件的计数。
+type myRemoteModule struct {
+	stub.ModuleInternal
+	client *rpcClient
+}
+
+// newRemoteModule 会创建一个远程组件的客户端代理的基础实例。
+// 给定的组件ID中必须包含远程组件服务的网络地址。
+func newRemoteModule(
+	mid module.MID,
+	client *http.Client,
+	scoreCalculator module.CalculateScore) (*myRemoteModule, error) {
+	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
+	if err != nil {
+		return nil, err
+	}
+	if moduleBase.Addr() == "" {
+		errMsg := fmt.Sprintf("no address in MID %q", mid)
+		return nil, errs.NewIllegalParameterError(errMsg)
+	}
+	if client == nil {
+		return nil, errs.NewIllegalParameterError("nil http client")
+	}
+	return &myRemoteModule{
+		ModuleInternal: moduleBase,
+		client:         newRPCClient(moduleBase.Addr(), client),
+	}, nil
+}
+
+// params 用于生成调用本组件时共有的参数。
+func (m *myRemoteModule) params() baseParams {
+	return baseParams{MID: m.ID()}
+}
+
+// HealthCheck 会检查远程组件服务是否可用，以及其中是否存在本组件。
+func (m *myRemoteModule) HealthCheck(ctx context.Context) error {
+	return m.client.call(ctx, METHOD_PING, m.params(), &pingResult{})
+}
+
+// RemoteSummary 用于获取远程组件自身的摘要。
+func (m *myRemoteModule) RemoteSummary(ctx context.Context) (module.SummaryStruct, error) {
+	var result pingResult
+	if err := m.client.call(ctx, METHOD_PING, m.params(), &result); err != nil {
+		return module.SummaryStruct{}, err
+	}
+	return result.Summary, nil
+}
@@ -0,0 +1,271 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"module"
+	"net/http"
+	"time"
+)
+
+// requestData 代表在网络上传输的请求。
+type requestData struct {
+	URL          string                 `json:"url"`
+	Method       string                 `json:"method"`
+	Header       http.Header            `json:"header,omitempty"`
+	Body         []byte                 `json:"body,omitempty"`
+	Depth        uint32                 `json:"depth"`
+	Priority     int32                  `json:"priority,omitempty"`
+	Attempt      uint32                 `json:"attempt,omitempty"`
+	ParentURL    string                 `json:"parent_url,omitempty"`
+	AnchorText   string                 `json:"anchor_text,omitempty"`
+	DiscoveredAt time.Time              `json:"discovered_at"`
+	Attrs        map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// encodeRequest 用于把请求转换为可在网络上传输的形式。
+// HTTP请求体会被完整读出，并在原请求中被替换为内容相同的读取器。
+func encodeRequest(req *module.Request) (*requestData, error) {
+	if req == nil || !req.Valid() {
+		return nil, fmt.Errorf("invalid request")
+	}
+	httpReq := req.HTTPReq()
+	data := &requestData{
+		URL:          httpReq.URL.String(),
+		Method:       httpReq.Method,
+		Header:       httpReq.Header,
+		Depth:        req.Depth(),
+		Priority:     req.Priority(),
+		Attempt:      req.Attempt(),
+		ParentURL:    req.ParentURL(),
+		AnchorText:   req.AnchorText(),
+		DiscoveredAt: req.DiscoveredAt(),
+		Attrs:        req.Attrs(),
+	}
+	if httpReq.Body != nil && httpReq.Body != http.NoBody {
+		body, err := ioutil.ReadAll(httpReq.Body)
+		httpReq.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		data.Body = body
+	}
+	return data, nil
+}
+
+// decode 用于还原请求。
+func (data *requestData) decode() (*module.Request, error) {
+	if data == nil {
+		return nil, fmt.Errorf("nil request data")
+	}
+	method := data.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if len(data.Body) > 0 {
+		body = bytes.NewReader(data.Body)
+	}
+	httpReq, err := http.NewRequest(method, data.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	if data.Header != nil {
+		httpReq.Header = data.Header
+	}
+	req := module.NewRequest(httpReq, data.Depth)
+	req.SetPriority(data.Priority)
+	req.SetAttempt(data.Attempt)
+	req.SetParentURL(data.ParentURL)
+	req.SetAnchorText(data.AnchorText)
+	if !data.DiscoveredAt.IsZero() {
+		req.SetDiscoveredAt(data.DiscoveredAt)
+	}
+	for key, value := range data.Attrs {
+		req.SetAttr(key, value)
+	}
+	return req, nil
+}
+
+// responseData 代表在网络上传输的响应。
+type responseData struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Proto      string      `json:"proto,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+	// URL 代表最终（可能经过重定向）得到该响应的URL。
+	URL     string       `json:"url"`
+	Depth   uint32       `json:"depth"`
+	Request *requestData `json:"request,omitempty"`
+}
+
+// encodeResponse 用于把响应转换为可在网络上传输的形式。
+// 响应体会被读出并关闭，其长度不能超过maxBodyBytes。
+func encodeResponse(resp *module.Response, maxBodyBytes int64) (*responseData, error) {
+	if resp == nil || resp.HTTPResp() == nil {
+		return nil, fmt.Errorf("invalid response")
+	}
+	httpResp := resp.HTTPResp()
+	data := &responseData{
+		StatusCode: httpResp.StatusCode,
+		Status:     httpResp.Status,
+		Proto:      httpResp.Proto,
+		Header:     httpResp.Header,
+		Depth:      resp.Depth(),
+	}
+	if httpResp.Request != nil && httpResp.Request.URL != nil {
+		data.URL = httpResp.Request.URL.String()
+	}
+	if req := resp.Request(); req != nil {
+		reqData, err := encodeRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		data.Request = reqData
+	}
+	if httpResp.Body != nil {
+		defer httpResp.Body.Close()
+		body, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxBodyBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > maxBodyBytes {
+			return nil, fmt.Errorf("too large response body (limit: %d bytes)", maxBodyBytes)
+		}
+		data.Body = body
+	}
+	return data, nil
+}
+
+// decode 用于还原响应。
+// 参数req代表该响应所对应的请求，为nil时会根据响应中的数据还原。
+func (data *responseData) decode(req *module.Request) (*module.Response, error) {
+	if data == nil {
+		return nil, fmt.Errorf("nil response data")
+	}
+	if req == nil && data.Request != nil {
+		var err error
+		if req, err = data.Request.decode(); err != nil {
+			return nil, err
+		}
+	}
+	httpResp := &http.Response{
+		StatusCode:    data.StatusCode,
+		Status:        data.Status,
+		Proto:         data.Proto,
+		Header:        data.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(data.Body)),
+		ContentLength: int64(len(data.Body)),
+	}
+	if httpResp.Header == nil {
+		httpResp.Header = http.Header{}
+	}
+	var httpReq *http.Request
+	if req != nil && req.HTTPReq() != nil {
+		// 复制HTTP请求，以便在不影响原请求的情况下反映重定向后的URL。
+		httpReq = req.HTTPReq().WithContext(req.HTTPReq().Context())
+	} else {
+		var err error
+		if httpReq, err = http.NewRequest(http.MethodGet, data.URL, nil); err != nil {
+			return nil, err
+		}
+	}
+	if data.URL != "" && httpReq.URL.String() != data.URL {
+		finalReq, err := http.NewRequest(httpReq.Method, data.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.URL = finalReq.URL
+		httpReq.Host = finalReq.Host
+	}
+	httpResp.Request = httpReq
+	resp := module.NewResponse(httpResp, data.Depth)
+	resp.SetRequest(req)
+	return resp, nil
+}
+
+// 条目和请求在网络上传输时的种类。
+const (
+	dataKindRequest = "request"
+	dataKindItem    = "item"
+)
+
+// dataEnvelope 代表在网络上传输的分析结果中的数据。
+type dataEnvelope struct {
+	Kind    string       `json:"kind"`
+	Request *requestData `json:"request,omitempty"`
+	Item    module.Item  `json:"item,omitempty"`
+}
+
+// encodeData 用于把分析结果中的数据转换为可在网络上传输的形式。
+func encodeData(data module.Data) (dataEnvelope, error) {
+	switch d := data.(type) {
+	case *module.Request:
+		reqData, err := encodeRequest(d)
+		if err != nil {
+			return dataEnvelope{}, err
+		}
+		return dataEnvelope{Kind: dataKindRequest, Request: reqData}, nil
+	case module.Item:
+		return dataEnvelope{Kind: dataKindItem, Item: d}, nil
+	default:
+		return dataEnvelope{}, fmt.Errorf("unsupported data type %T", data)
+	}
+}
+
+// decode 用于还原分析结果中的数据。
+func (envelope dataEnvelope) decode() (module.Data, error) {
+	switch envelope.Kind {
+	case dataKindRequest:
+		return envelope.Request.decode()
+	case dataKindItem:
+		return decodeItem(envelope.Item)
+	default:
+		return nil, fmt.Errorf("unsupported data kind %q", envelope.Kind)
+	}
+}
+
+// decodeItem 用于还原经过网络传输的条目。
+// 条目中的元数据会被还原为module.ItemMeta类型的值。
+// 注意！其他的值会以JSON解码后的形式出现，例如数字会变为float64类型的值。
+func decodeItem(item module.Item) (module.Item, error) {
+	if item == nil {
+		return nil, fmt.Errorf("nil item")
+	}
+	raw, ok := item[module.ITEM_KEY_META]
+	if !ok {
+		return item, nil
+	}
+	if _, ok := raw.(module.ItemMeta); ok {
+		return item, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var meta module.ItemMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, err
+	}
+	item[module.ITEM_KEY_META] = meta
+	return item, nil
+}
+
+// errorStrings 用于把错误值的列表转换为错误信息的列表。
+func errorStrings(errs []error) []string {
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	return messages
+}
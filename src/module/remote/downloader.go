@@ -0,0 +1,61 @@
+package remote
+
+import (
+	"context"
+	"errs"
+	"module"
+	"net/http"
+	"time"
+)
+
+// myDownloader 代表远程下载器的客户端代理的实现类型。
+type myDownloader struct {
+	*myRemoteModule
+}
+
+// NewDownloader 会创建一个远程下载器的客户端代理。
+// 参数mid中必须包含远程组件服务的网络地址，且与远程下载器的ID相同。
+// 参数client代表调用远程组件服务时所用的HTTP客户端。
+func NewDownloader(
+	mid module.MID,
+	client *http.Client,
+	scoreCalculator module.CalculateScore) (module.Downloader, error) {
+	base, err := newRemoteModule(mid, client, scoreCalculator)
+	if err != nil {
+		return nil, genParameterError(errs.ERROR_TYPE_DOWNLOADER, err.Error())
+	}
+	return &myDownloader{myRemoteModule: base}, nil
+}
+
+// Download 会让远程下载器执行给定的请求。
+// 响应体会被完整地传输到本地，参数ctx被取消时远程调用会被中止。
+func (downloader *myDownloader) Download(
+	ctx context.Context, req *module.Request) (*module.Response, error) {
+	downloader.ModuleInternal.IncrHandlingNumber()
+	defer downloader.ModuleInternal.DecrHandlingNumber()
+	downloader.ModuleInternal.IncrCalledCount()
+	if req == nil || !req.Valid() {
+		return nil, genParameterError(errs.ERROR_TYPE_DOWNLOADER, "invalid request")
+	}
+	reqData, err := encodeRequest(req)
+	if err != nil {
+		return nil, genParameterError(errs.ERROR_TYPE_DOWNLOADER, err.Error())
+	}
+	downloader.ModuleInternal.IncrAcceptedCount()
+	startTime := time.Now()
+	var result downloadResult
+	err = downloader.client.call(ctx, METHOD_DOWNLOAD,
+		downloadParams{baseParams: downloader.params(), Request: reqData}, &result)
+	downloader.ModuleInternal.ObserveLatency(time.Since(startTime))
+	if err != nil {
+		downloader.ModuleInternal.IncrFailedCount()
+		return nil, err
+	}
+	resp, err := result.Response.decode(req)
+	if err != nil {
+		downloader.ModuleInternal.IncrFailedCount()
+		return nil, genError(errs.ERROR_TYPE_DOWNLOADER, err.Error())
+	}
+	downloader.ModuleInternal.IncrCompletedCount()
+	return resp, nil
+}
@@ -0,0 +1,14 @@
+package remote
+
+import "errs"
+
+// genError 用于生成给定类型的爬虫错误值。
+func genError(errType errs.ErrorType, errMsg string) error {
+	return errs.NewCrawlerError(errType, errMsg)
+}
+
+// genParameterError 用于生成给定类型的爬虫参数错误值。
+func genParameterError(errType errs.ErrorType, errMsg string) error {
+	return errs.NewCrawlerErrorBy(errType,
+		errs.NewIllegalParameterError(errMsg))
+}
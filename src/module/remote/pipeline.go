@@ -0,0 +1,94 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"errs"
+	"log"
+	"module"
+	"net/http"
+	"time"
+)
+
+// myPipeline 代表远程条目处理管道的客户端代理的实现类型。
+type myPipeline struct {
+	*myRemoteModule
+}
+
+// NewPipeline 会创建一个远程条目处理管道的客户端代理。
+// 参数mid中必须包含远程组件服务的网络地址，且与远程条目处理管道的ID相同。
+// 参数client代表调用远程组件服务时所用的HTTP客户端。
+func NewPipeline(
+	mid module.MID,
+	client *http.Client,
+	scoreCalculator module.CalculateScore) (module.Pipeline, error) {
+	base, err := newRemoteModule(mid, client, scoreCalculator)
+	if err != nil {
+		return nil, genParameterError(errs.ERROR_TYPE_PIPELINE, err.Error())
+	}
+	return &myPipeline{myRemoteModule: base}, nil
+}
+
+// ItemProcessors 总会返回nil，因为条目处理函数都位于远程条目处理管道中。
+func (pipeline *myPipeline) ItemProcessors() []module.ProcessItem {
+	return nil
+}
+
+// Send 会把给定的条目发送给远程条目处理管道。
+// 注意！条目中的值必须能被编码为JSON。
+func (pipeline *myPipeline) Send(ctx context.Context, item module.Item) (errorList []error) {
+	pipeline.ModuleInternal.IncrHandlingNumber()
+	defer pipeline.ModuleInternal.DecrHandlingNumber()
+	pipeline.ModuleInternal.IncrCalledCount()
+	if item == nil {
+		errorList = append(errorList, genParameterError(errs.ERROR_TYPE_PIPELINE, "nil item"))
+		return
+	}
+	pipeline.ModuleInternal.IncrAcceptedCount()
+	startTime := time.Now()
+	defer func() {
+		pipeline.ModuleInternal.ObserveLatency(time.Since(startTime))
+		if len(errorList) > 0 {
+			pipeline.ModuleInternal.IncrFailedCount()
+		}
+	}()
+	var result sendResult
+	err := pipeline.client.call(ctx, METHOD_SEND,
+		sendParams{baseParams: pipeline.params(), Item: item}, &result)
+	if err != nil {
+		errorList = append(errorList, err)
+		return
+	}
+	for _, errMsg := range result.Errors {
+		errorList = append(errorList, errors.New(errMsg))
+	}
+	if len(errorList) == 0 {
+		pipeline.ModuleInternal.IncrCompletedCount()
+	}
+	return
+}
+
+// FailFast 会返回远程条目处理管道的快速失败设置。
+// 远程调用失败时返回false。
+func (pipeline *myPipeline) FailFast() bool {
+	var result failFastResult
+	err := pipeline.client.call(context.Background(), METHOD_FAIL_FAST,
+		pipeline.params(), &result)
+	if err != nil {
+		log.Printf("Couldn't get the fail fast setting of the remote pipeline %s: %s\n",
+			pipeline.ID(), err)
+		return false
+	}
+	return result.FailFast
+}
+
+// SetFailFast 会设置远程条目处理管道的快速失败设置。
+// 远程调用失败时只会记录日志。
+func (pipeline *myPipeline) SetFailFast(failFast bool) {
+	err := pipeline.client.call(context.Background(), METHOD_SET_FAIL_FAST,
+		failFastParams{baseParams: pipeline.params(), FailFast: failFast}, nil)
+	if err != nil {
+		log.Printf("Couldn't set the fail fast setting of the remote pipeline %s: %s\n",
+			pipeline.ID(), err)
+	}
+}
@@ -0,0 +1,114 @@
+package remote
+
+import (
+	"encoding/json"
+	"module"
+)
+
+// RPC_PATH 代表远程组件服务的HTTP路径。
+const RPC_PATH = "/rpc"
+
+// JSONRPC_VERSION 代表所用的JSON-RPC协议的版本。
+const JSONRPC_VERSION = "2.0"
+
+// 远程组件服务支持的方法的名称。
+const (
+	METHOD_PING          = "Module.Ping"
+	METHOD_DOWNLOAD      = "Downloader.Download"
+	METHOD_ANALYZE       = "Analyzer.Analyze"
+	METHOD_SEND          = "Pipeline.Send"
+	METHOD_FAIL_FAST     = "Pipeline.FailFast"
+	METHOD_SET_FAIL_FAST = "Pipeline.SetFailFast"
+)
+
+// JSON-RPC协议定义的错误代码，以及组件处理失败时的错误代码。
+const (
+	CODE_PARSE_ERROR      = -32700
+	CODE_INVALID_REQUEST  = -32600
+	CODE_METHOD_NOT_FOUND = -32601
+	CODE_INVALID_PARAMS   = -32602
+	CODE_INTERNAL_ERROR   = -32603
+	CODE_MODULE_ERROR     = -32000
+)
+
+// rpcRequest 代表JSON-RPC请求。
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse 代表JSON-RPC响应。
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError 代表远程组件服务返回的错误。
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *RPCError) Error() string {
+	return err.Message
+}
+
+// baseParams 代表所有方法共有的参数。
+type baseParams struct {
+	// MID 代表被调用的组件的ID。
+	MID module.MID `json:"mid"`
+}
+
+// pingResult 代表Module.Ping方法的结果。
+type pingResult struct {
+	Summary module.SummaryStruct `json:"summary"`
+}
+
+// downloadParams 代表Downloader.Download方法的参数。
+type downloadParams struct {
+	baseParams
+	Request *requestData `json:"request"`
+}
+
+// downloadResult 代表Downloader.Download方法的结果。
+type downloadResult struct {
+	Response *responseData `json:"response"`
+}
+
+// analyzeParams 代表Analyzer.Analyze方法的参数。
+type analyzeParams struct {
+	baseParams
+	Response *responseData `json:"response"`
+}
+
+// analyzeResult 代表Analyzer.Analyze方法的结果。
+type analyzeResult struct {
+	Data   []dataEnvelope `json:"data,omitempty"`
+	Errors []string       `json:"errors,omitempty"`
+}
+
+// sendParams 代表Pipeline.Send方法的参数。
+type sendParams struct {
+	baseParams
+	Item module.Item `json:"item"`
+}
+
+// sendResult 代表Pipeline.Send方法的结果。
+type sendResult struct {
+	Errors []string `json:"errors,omitempty"`
+}
+
+// failFastParams 代表Pipeline.SetFailFast方法的参数。
+type failFastParams struct {
+	baseParams
+	FailFast bool `json:"fail_fast"`
+}
+
+// failFastResult 代表Pipeline.FailFast方法的结果。
+type failFastResult struct {
+	FailFast bool `json:"fail_fast"`
+}
@@ -0,0 +1,408 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"module"
+	"module/local/analyzer"
+	"module/local/downloader"
+	"module/local/pipeline"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingDownloader 代表会记录收到的请求的下载器。
+type recordingDownloader struct {
+	module.Downloader
+	reqCh chan *module.Request
+}
+
+func (d *recordingDownloader) Download(
+	ctx context.Context, req *module.Request) (*module.Response, error) {
+	d.reqCh <- req
+	return d.Downloader.Download(ctx, req)
+}
+
+// testService 代表测试用的远程组件服务及其所访问的页面服务器。
+type testService struct {
+	rpcServer  *httptest.Server
+	pageServer *httptest.Server
+	server     Server
+	// reqCh 代表远程下载器收到的请求的通道。
+	reqCh chan *module.Request
+	// itemCh 代表远程条目处理管道收到的条目的通道。
+	itemCh        chan module.Item
+	downloaderMID module.MID
+	analyzerMID   module.MID
+	pipelineMID   module.MID
+}
+
+// newTestService 会创建并启动一个暴露本地下载器、分析器和条目处理管道的远程组件服务。
+// 分析器会把响应体作为条目中的内容，并生成一个指向/next的请求。
+// 条目处理管道会拒绝含有键fail的条目。
+func newTestService(t *testing.T) *testService {
+	ts := &testService{
+		reqCh:  make(chan *module.Request, 10),
+		itemCh: make(chan module.Item, 10),
+	}
+	ts.pageServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Page", r.URL.Path)
+		fmt.Fprintf(w, "content of %s", r.URL.Path)
+	}))
+	ts.rpcServer = httptest.NewUnstartedServer(nil)
+	addr := ts.rpcServer.Listener.Addr()
+	genMID := func(moduleType module.Type) module.MID {
+		mid, err := module.GenMID(moduleType, 1, addr)
+		if err != nil {
+			t.Fatalf("An error occurs when generating MID: %s", err)
+		}
+		return mid
+	}
+	ts.downloaderMID = genMID(module.TYPE_DOWNLOADER)
+	ts.analyzerMID = genMID(module.TYPE_ANALYZER)
+	ts.pipelineMID = genMID(module.TYPE_PIPELINE)
+	d, err := downloader.New(ts.downloaderMID, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating downloader: %s", err)
+	}
+	parser := func(httpResp *http.Response, depth uint32) ([]module.Data, []error) {
+		content, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, []error{err}
+		}
+		nextReq, _ := http.NewRequest(http.MethodGet, ts.pageServer.URL+"/next", nil)
+		req := module.NewRequest(nextReq, depth+1)
+		req.SetAnchorText("next")
+		return []module.Data{module.Item{"content": string(content)}, req}, nil
+	}
+	a, err := analyzer.New(ts.analyzerMID,
+		[]module.ParseResponse{parser}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating analyzer: %s", err)
+	}
+	processor := func(item module.Item) (module.Item, error) {
+		if _, ok := item["fail"]; ok {
+			return nil, errors.New("rejected item")
+		}
+		ts.itemCh <- item
+		return item, nil
+	}
+	p, err := pipeline.New(ts.pipelineMID,
+		[]module.ProcessItem{processor}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating pipeline: %s", err)
+	}
+	ts.server, err = NewServer(&recordingDownloader{Downloader: d, reqCh: ts.reqCh}, a, p)
+	if err != nil {
+		t.Fatalf("An error occurs when creating server: %s", err)
+	}
+	ts.rpcServer.Config.Handler = ts.server
+	ts.rpcServer.Start()
+	return ts
+}
+
+func (ts *testService) close() {
+	ts.rpcServer.Close()
+	ts.pageServer.Close()
+}
+
+// newRequest 会创建一个带有元数据的请求。
+func newRequest(t *testing.T, rawURL string) *module.Request {
+	httpReq, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating HTTP request: %s", err)
+	}
+	httpReq.Header.Set("X-Test", "yes")
+	req := module.NewRequest(httpReq, 1)
+	req.SetPriority(3)
+	req.SetAttempt(2)
+	req.SetParentURL("http://example.com/parent")
+	req.SetAnchorText("anchor")
+	req.SetDiscoveredAt(time.Unix(1000, 0))
+	req.SetAttr("key", "value")
+	return req
+}
+
+func TestRemoteDownload(t *testing.T) {
+	ts := newTestService(t)
+	defer ts.close()
+	d, err := NewDownloader(ts.downloaderMID, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote downloader: %s", err)
+	}
+	req := newRequest(t, ts.pageServer.URL+"/page")
+	resp, err := d.Download(context.Background(), req)
+	if err != nil {
+		t.Fatalf("An error occurs when downloading: %s", err)
+	}
+	// 请求的元数据会被传输到远程下载器。
+	remoteReq := <-ts.reqCh
+	if remoteReq.Depth() != 1 || remoteReq.Priority() != 3 || remoteReq.Attempt() != 2 ||
+		remoteReq.ParentURL() != "http://example.com/parent" ||
+		remoteReq.AnchorText() != "anchor" ||
+		!remoteReq.DiscoveredAt().Equal(time.Unix(1000, 0)) ||
+		remoteReq.Attrs()["key"] != "value" ||
+		remoteReq.HTTPReq().Header.Get("X-Test") != "yes" {
+		t.Fatalf("Inconsistent remote request: %+v", remoteReq.Meta())
+	}
+	httpResp := resp.HTTPResp()
+	body, _ := ioutil.ReadAll(httpResp.Body)
+	if httpResp.StatusCode != http.StatusOK || string(body) != "content of /page" ||
+		httpResp.Header.Get("X-Page") != "/page" {
+		t.Fatalf("Inconsistent response: %d %q %v", httpResp.StatusCode, body, httpResp.Header)
+	}
+	if resp.Depth() != 1 || resp.Request() != req {
+		t.Fatalf("Inconsistent response metadata: depth: %d, request: %v", resp.Depth(), resp.Request())
+	}
+	if counts := d.Counts(); counts.CompletedCount != 1 || counts.FailedCount != 0 {
+		t.Fatalf("Inconsistent counts: %+v", counts)
+	}
+}
+
+func TestRemoteAnalyze(t *testing.T) {
+	ts := newTestService(t)
+	defer ts.close()
+	a, err := NewAnalyzer(ts.analyzerMID, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote analyzer: %s", err)
+	}
+	req := newRequest(t, ts.pageServer.URL+"/page")
+	httpResp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("hello")),
+		Request:    req.HTTPReq(),
+	}
+	resp := module.NewResponse(httpResp, req.Depth())
+	resp.SetRequest(req)
+	dataList, errorList := a.Analyze(context.Background(), resp)
+	if len(errorList) > 0 {
+		t.Fatalf("Some errors occur when analyzing: %v", errorList)
+	}
+	if len(dataList) != 2 {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d", 2, len(dataList))
+	}
+	item, ok := dataList[0].(module.Item)
+	if !ok || item["content"] != "hello" {
+		t.Fatalf("Inconsistent item: %v", dataList[0])
+	}
+	// 条目中的元数据会被还原为module.ItemMeta类型的值。
+	meta, ok := item.Meta()
+	if !ok {
+		t.Fatalf("No item meta in %v", item)
+	}
+	if meta.URL != req.HTTPReq().URL.String() || meta.Depth != 1 || meta.Attempt != 2 ||
+		meta.ParentURL != "http://example.com/parent" || meta.AnchorText != "anchor" ||
+		!meta.DiscoveredAt.Equal(time.Unix(1000, 0)) || meta.Attrs["key"] != "value" {
+		t.Fatalf("Inconsistent item meta: %+v", meta)
+	}
+	nextReq, ok := dataList[1].(*module.Request)
+	if !ok {
+		t.Fatalf("Inconsistent data type: expected: %T, actual: %T", nextReq, dataList[1])
+	}
+	if nextReq.HTTPReq().URL.String() != ts.pageServer.URL+"/next" || nextReq.Depth() != 2 ||
+		nextReq.AnchorText() != "next" || nextReq.ParentURL() != req.HTTPReq().URL.String() {
+		t.Fatalf("Inconsistent request: %s %+v", nextReq.HTTPReq().URL, nextReq.Meta())
+	}
+}
+
+func TestRemoteSend(t *testing.T) {
+	ts := newTestService(t)
+	defer ts.close()
+	p, err := NewPipeline(ts.pipelineMID, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote pipeline: %s", err)
+	}
+	meta := newRequest(t, "http://example.com/page").Meta()
+	errorList := p.Send(context.Background(),
+		module.Item{"n": 1, module.ITEM_KEY_META: meta})
+	if len(errorList) > 0 {
+		t.Fatalf("Some errors occur when sending: %v", errorList)
+	}
+	item := <-ts.itemCh
+	if item["n"] != float64(1) {
+		t.Fatalf("Inconsistent item value: %v", item["n"])
+	}
+	remoteMeta, ok := item.Meta()
+	if !ok || remoteMeta.URL != meta.URL || remoteMeta.Depth != meta.Depth ||
+		remoteMeta.AnchorText != meta.AnchorText {
+		t.Fatalf("Inconsistent item meta: %+v", item[module.ITEM_KEY_META])
+	}
+	errorList = p.Send(context.Background(), module.Item{"fail": true})
+	if len(errorList) != 1 || !strings.Contains(errorList[0].Error(), "rejected item") {
+		t.Fatalf("Inconsistent errors: %v", errorList)
+	}
+	if counts := p.Counts(); counts.CompletedCount != 1 || counts.FailedCount != 1 {
+		t.Fatalf("Inconsistent counts: %+v", counts)
+	}
+	p.SetFailFast(true)
+	if !p.FailFast() {
+		t.Fatal("The fail fast setting hasn't been set remotely!")
+	}
+}
+
+func TestRemoteBodyLimit(t *testing.T) {
+	ts := newTestService(t)
+	defer ts.close()
+	server := ts.server.(*myServer)
+	if server.maxBodyBytes != DEFAULT_MAX_BODY_BYTES {
+		t.Fatalf("Inconsistent body limit: expected: %d, actual: %d",
+			DEFAULT_MAX_BODY_BYTES, server.maxBodyBytes)
+	}
+	// 缩小限制，以免在测试中传输过大的响应体。
+	server.maxBodyBytes = 8
+	d, err := NewDownloader(ts.downloaderMID, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote downloader: %s", err)
+	}
+	_, err = d.Download(context.Background(), newRequest(t, ts.pageServer.URL+"/page"))
+	rpcErr, ok := err.(*RPCError)
+	if !ok || rpcErr.Code != CODE_MODULE_ERROR ||
+		!strings.Contains(rpcErr.Message, "too large response body") {
+		t.Fatalf("Inconsistent error: %v", err)
+	}
+	a, err := NewAnalyzer(ts.analyzerMID, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote analyzer: %s", err)
+	}
+	if a.(*myAnalyzer).maxBodyBytes != DEFAULT_MAX_BODY_BYTES {
+		t.Fatalf("Inconsistent body limit: expected: %d, actual: %d",
+			DEFAULT_MAX_BODY_BYTES, a.(*myAnalyzer).maxBodyBytes)
+	}
+	a.(*myAnalyzer).maxBodyBytes = 4
+	req := newRequest(t, ts.pageServer.URL+"/page")
+	resp := module.NewResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("hello")),
+		Request:    req.HTTPReq(),
+	}, 0)
+	_, errorList := a.Analyze(context.Background(), resp)
+	if len(errorList) != 1 || !strings.Contains(errorList[0].Error(), "too large response body") {
+		t.Fatalf("Inconsistent errors: %v", errorList)
+	}
+}
+
+// repeatReader 代表会无限地读出同一个字节的读取器。
+type repeatReader byte
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestRemoteMessageLimit(t *testing.T) {
+	ts := newTestService(t)
+	defer ts.close()
+	// 过大的JSON-RPC请求会被远程组件服务拒绝。
+	body := io.LimitReader(repeatReader(' '), MAX_MESSAGE_BYTES+1)
+	httpResp, err := http.Post(ts.rpcServer.URL+RPC_PATH, "application/json", body)
+	if err != nil {
+		t.Fatalf("An error occurs when posting: %s", err)
+	}
+	var rpcResp rpcResponse
+	err = json.NewDecoder(httpResp.Body).Decode(&rpcResp)
+	httpResp.Body.Close()
+	if err != nil {
+		t.Fatalf("An error occurs when decoding result: %s", err)
+	}
+	if rpcResp.Error == nil || rpcResp.Error.Code != CODE_INVALID_REQUEST {
+		t.Fatalf("Inconsistent error: %+v", rpcResp.Error)
+	}
+	// 过大的JSON-RPC结果会被客户端拒绝。
+	hugeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, io.LimitReader(repeatReader(' '), MAX_MESSAGE_BYTES+1))
+	}))
+	defer hugeServer.Close()
+	client := newRPCClient(hugeServer.Listener.Addr().String(), &http.Client{})
+	err = client.call(context.Background(), METHOD_PING, baseParams{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "too large result") {
+		t.Fatalf("Inconsistent error: %v", err)
+	}
+}
+
+func TestRemoteInvalidCalls(t *testing.T) {
+	ts := newTestService(t)
+	defer ts.close()
+	client := newRPCClient(ts.rpcServer.Listener.Addr().String(), &http.Client{})
+	unknownMID, _ := module.GenMID(module.TYPE_DOWNLOADER, 2, ts.rpcServer.Listener.Addr())
+	reqData, err := encodeRequest(newRequest(t, ts.pageServer.URL+"/page"))
+	if err != nil {
+		t.Fatalf("An error occurs when encoding request: %s", err)
+	}
+	cases := []struct {
+		name   string
+		method string
+		params interface{}
+		code   int
+	}{
+		{"unknown MID", METHOD_DOWNLOAD,
+			downloadParams{baseParams: baseParams{MID: unknownMID}, Request: reqData},
+			CODE_INVALID_PARAMS},
+		{"wrong module type", METHOD_DOWNLOAD,
+			downloadParams{baseParams: baseParams{MID: ts.analyzerMID}, Request: reqData},
+			CODE_INVALID_PARAMS},
+		{"wrong module type", METHOD_SEND,
+			sendParams{baseParams: baseParams{MID: ts.downloaderMID}, Item: module.Item{}},
+			CODE_INVALID_PARAMS},
+		{"wrong module type", METHOD_ANALYZE,
+			analyzeParams{baseParams: baseParams{MID: ts.pipelineMID}},
+			CODE_INVALID_PARAMS},
+		{"unknown method", "Module.Unknown", baseParams{MID: ts.downloaderMID},
+			CODE_METHOD_NOT_FOUND},
+	}
+	for _, c := range cases {
+		err := client.call(context.Background(), c.method, c.params, nil)
+		rpcErr, ok := err.(*RPCError)
+		if !ok || rpcErr.Code != c.code {
+			t.Fatalf("Inconsistent error for the %s case: expected code: %d, actual: %v",
+				c.name, c.code, err)
+		}
+	}
+	// 远程代理的ID不存在于远程组件服务中时，健康检查会失败。
+	d, err := NewDownloader(unknownMID, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote downloader: %s", err)
+	}
+	if err := d.(Module).HealthCheck(context.Background()); err == nil {
+		t.Fatal("No error when checking the health of an unknown module!")
+	}
+	if _, err := NewDownloader("D1", &http.Client{}, module.CalculateScoreSimple); err == nil {
+		t.Fatal("No error when creating remote downloader without address!")
+	}
+}
+
+func TestRemoteIDMismatch(t *testing.T) {
+	var responseID uint64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rpcReq rpcRequest
+		json.NewDecoder(r.Body).Decode(&rpcReq)
+		json.NewEncoder(w).Encode(rpcResponse{
+			JSONRPC: JSONRPC_VERSION,
+			ID:      rpcReq.ID + responseID,
+			Result:  json.RawMessage(`{"fail_fast":true}`),
+		})
+	}))
+	defer server.Close()
+	client := newRPCClient(server.Listener.Addr().String(), &http.Client{})
+	var result failFastResult
+	if err := client.call(context.Background(), METHOD_FAIL_FAST, baseParams{}, &result); err != nil {
+		t.Fatalf("An error occurs when calling: %s", err)
+	}
+	if !result.FailFast {
+		t.Fatal("Inconsistent result!")
+	}
+	responseID = 1
+	err := client.call(context.Background(), METHOD_FAIL_FAST, baseParams{}, &result)
+	if err == nil || !strings.Contains(err.Error(), "inconsistent ID") {
+		t.Fatalf("Inconsistent error: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errs"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"module"
+	"net/http"
+	"sort"
+)
+
+// DEFAULT_MAX_BODY_BYTES 代表可以被传输的响应体的默认最大字节数。
+const DEFAULT_MAX_BODY_BYTES = 32 << 20
+
+// Server 代表远程组件服务的接口类型。
+// 它会在RPC_PATH上处理JSON-RPC请求，并把调用转交给ID相同的本地组件。
+type Server interface {
+	http.Handler
+	// Modules 用于获取被暴露的组件，按组件ID排序。
+	Modules() []module.Module
+}
+
+// myServer 代表远程组件服务的实现类型。
+type myServer struct {
+	// moduleMap 代表组件ID与被暴露的组件的字典。
+	moduleMap map[module.MID]module.Module
+	// maxBodyBytes 代表可以被传输的响应体的最大字节数。
+	maxBodyBytes int64
+}
+
+// NewServer 会创建一个暴露给定组件的远程组件服务。
+// 组件的ID应该包含该服务所监听的网络地址，以便客户端代理据此找到它。
+func NewServer(modules ...module.Module) (Server, error) {
+	if len(modules) == 0 {
+		return nil, errs.NewIllegalParameterError("empty module list")
+	}
+	moduleMap := map[module.MID]module.Module{}
+	for _, m := range modules {
+		if m == nil {
+			return nil, errs.NewIllegalParameterError("nil module instance")
+		}
+		if _, ok := moduleMap[m.ID()]; ok {
+			errMsg := fmt.Sprintf("duplicate MID: %q", m.ID())
+			return nil, errs.NewIllegalParameterError(errMsg)
+		}
+		moduleMap[m.ID()] = m
+	}
+	return &myServer{
+		moduleMap:    moduleMap,
+		maxBodyBytes: DEFAULT_MAX_BODY_BYTES,
+	}, nil
+}
+
+func (server *myServer) Modules() []module.Module {
+	modules := make([]module.Module, 0, len(server.moduleMap))
+	for _, m := range server.moduleMap {
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].ID() < modules[j].ID()
+	})
+	return modules
+}
+
+func (server *myServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != RPC_PATH {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var rpcResp rpcResponse
+	rpcResp.JSONRPC = JSONRPC_VERSION
+	reqBytes, err := ioutil.ReadAll(io.LimitReader(r.Body, MAX_MESSAGE_BYTES+1))
+	var rpcReq rpcRequest
+	switch {
+	case err != nil:
+		rpcResp.Error = &RPCError{Code: CODE_PARSE_ERROR, Message: err.Error()}
+	case len(reqBytes) > MAX_MESSAGE_BYTES:
+		rpcResp.Error = &RPCError{Code: CODE_INVALID_REQUEST, Message: "too large request"}
+	default:
+		if err := json.Unmarshal(reqBytes, &rpcReq); err != nil {
+			rpcResp.Error = &RPCError{Code: CODE_PARSE_ERROR, Message: err.Error()}
+		} else if rpcReq.JSONRPC != JSONRPC_VERSION || rpcReq.Method == "" {
+			rpcResp.Error = &RPCError{Code: CODE_INVALID_REQUEST, Message: "invalid request"}
+		} else {
+			rpcResp.ID = rpcReq.ID
+			var result interface{}
+			result, rpcResp.Error = server.dispatch(r.Context(), rpcReq)
+			if rpcResp.Error == nil {
+				if rpcResp.Result, err = json.Marshal(result); err != nil {
+					rpcResp.Error = &RPCError{Code: CODE_INTERNAL_ERROR, Message: err.Error()}
+				}
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rpcResp); err != nil {
+		log.Printf("An error occurs when writing the result of %s: %s\n", rpcReq.Method, err)
+	}
+}
+
+// dispatch 用于把给定的JSON-RPC请求转交给相应的组件。
+func (server *myServer) dispatch(ctx context.Context, rpcReq rpcRequest) (interface{}, *RPCError) {
+	switch rpcReq.Method {
+	case METHOD_PING:
+		var params baseParams
+		m, rpcErr := server.lookup(rpcReq.Params, &params, &params)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		return pingResult{Summary: m.Summary()}, nil
+	case METHOD_DOWNLOAD:
+		var params downloadParams
+		m, rpcErr := server.lookup(rpcReq.Params, &params, &params.baseParams)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		downloader, ok := m.(module.Downloader)
+		if !ok {
+			return nil, incorrectTypeError(m, module.TYPE_DOWNLOADER)
+		}
+		return server.download(ctx, downloader, params)
+	case METHOD_ANALYZE:
+		var params analyzeParams
+		m, rpcErr := server.lookup(rpcReq.Params, &params, &params.baseParams)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		analyzer, ok := m.(module.Analyzer)
+		if !ok {
+			return nil, incorrectTypeError(m, module.TYPE_ANALYZER)
+		}
+		return server.analyze(ctx, analyzer, params)
+	case METHOD_SEND:
+		var params sendParams
+		m, rpcErr := server.lookup(rpcReq.Params, &params, &params.baseParams)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		pipeline, ok := m.(module.Pipeline)
+		if !ok {
+			return nil, incorrectTypeError(m, module.TYPE_PIPELINE)
+		}
+		item, err := decodeItem(params.Item)
+		if err != nil {
+			return nil, &RPCError{Code: CODE_INVALID_PARAMS, Message: err.Error()}
+		}
+		return sendResult{Errors: errorStrings(pipeline.Send(ctx, item))}, nil
+	case METHOD_FAIL_FAST, METHOD_SET_FAIL_FAST:
+		var params failFastParams
+		m, rpcErr := server.lookup(rpcReq.Params, &params, &params.baseParams)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		pipeline, ok := m.(module.Pipeline)
+		if !ok {
+			return nil, incorrectTypeError(m, module.TYPE_PIPELINE)
+		}
+		if rpcReq.Method == METHOD_SET_FAIL_FAST {
+			pipeline.SetFailFast(params.FailFast)
+		}
+		return failFastResult{FailFast: pipeline.FailFast()}, nil
+	default:
+		errMsg := fmt.Sprintf("method not found: %s", rpcReq.Method)
+		return nil, &RPCError{Code: CODE_METHOD_NOT_FOUND, Message: errMsg}
+	}
+}
+
+// lookup 用于把参数解码到params中，并根据其中的组件ID找到相应的组件。
+// 参数base代表params中共有的参数部分。
+func (server *myServer) lookup(
+	rawParams json.RawMessage, params interface{}, base *baseParams) (module.Module, *RPCError) {
+	if len(rawParams) == 0 {
+		return nil, &RPCError{Code: CODE_INVALID_PARAMS, Message: "no params"}
+	}
+	if err := json.Unmarshal(rawParams, params); err != nil {
+		return nil, &RPCError{Code: CODE_INVALID_PARAMS, Message: err.Error()}
+	}
+	m, ok := server.moduleMap[base.MID]
+	if !ok {
+		errMsg := fmt.Sprintf("not found module with MID %q", base.MID)
+		return nil, &RPCError{Code: CODE_INVALID_PARAMS, Message: errMsg}
+	}
+	return m, nil
+}
+
+// download 用于让给定的下载器执行请求，并把响应转换为可在网络上传输的形式。
+func (server *myServer) download(
+	ctx context.Context, downloader module.Downloader, params downloadParams) (interface{}, *RPCError) {
+	req, err := params.Request.decode()
+	if err != nil {
+		return nil, &RPCError{Code: CODE_INVALID_PARAMS, Message: err.Error()}
+	}
+	resp, err := downloader.Download(ctx, req)
+	if err != nil {
+		if resp != nil && resp.HTTPResp() != nil && resp.HTTPResp().Body != nil {
+			resp.HTTPResp().Body.Close()
+		}
+		return nil, &RPCError{Code: CODE_MODULE_ERROR, Message: err.Error()}
+	}
+	if resp == nil {
+		return nil, &RPCError{Code: CODE_MODULE_ERROR, Message: "nil response"}
+	}
+	respData, err := encodeResponse(resp, server.maxBodyBytes)
+	if err != nil {
+		return nil, &RPCError{Code: CODE_MODULE_ERROR, Message: err.Error()}
+	}
+	return downloadResult{Response: respData}, nil
+}
+
+// analyze 用于让给定的分析器解析响应，并把结果转换为可在网络上传输的形式。
+func (server *myServer) analyze(
+	ctx context.Context, analyzer module.Analyzer, params analyzeParams) (interface{}, *RPCError) {
+	resp, err := params.Response.decode(nil)
+	if err != nil {
+		return nil, &RPCError{Code: CODE_INVALID_PARAMS, Message: err.Error()}
+	}
+	dataList, errorList := analyzer.Analyze(ctx, resp)
+	result := analyzeResult{Errors: errorStrings(errorList)}
+	for _, data := range dataList {
+		if data == nil {
+			continue
+		}
+		envelope, err := encodeData(data)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.Data = append(result.Data, envelope)
+	}
+	return result, nil
+}
+
+// incorrectTypeError 用于生成组件类型不正确的错误。
+func incorrectTypeError(m module.Module, expected module.Type) *RPCError {
+	errMsg := fmt.Sprintf("the module %q is not a %s", m.ID(), expected)
+	return &RPCError{Code: CODE_INVALID_PARAMS, Message: errMsg}
+}
@@ -1,5 +1,7 @@
 package module
 
+import "time"
+
 type CalculateScore func(counts Counts) uint64
 
 // CalculateScoreSimple 代表简易的组件评分计算函数。
@@ -10,6 +12,28 @@ func CalculateScoreSimple(counts Counts) uint64 {
 		counts.HandlingNumber<<4
 }
 
+// CalculateScorePenalized 代表会惩罚缓慢或易出错的组件的评分计算函数。
+// 它以CalculateScoreSimple的结果为基础：
+// 失败率每增加10%，评分增加40%；
+// 处理耗时的移动平均值每增加100毫秒，评分增加100%（最多按1分钟计算）。
+// 评分越低的组件越会被优先选择。
+func CalculateScorePenalized(counts Counts) uint64 {
+	score := CalculateScoreSimple(counts)
+	if counts.CalledCount > 0 && counts.FailedCount > 0 {
+		failedPermille := counts.FailedCount * 1000 / counts.CalledCount
+		score = score * (1000 + failedPermille*4) / 1000
+	}
+	latency := counts.LatencyEWMA
+	if latency > time.Minute {
+		latency = time.Minute
+	}
+	if latency > 0 {
+		latencyMillis := uint64(latency / time.Millisecond)
+		score = score * (100 + latencyMillis) / 100
+	}
+	return score
+}
+
 // SetScore 用于设置给定组件的评分。
 // 结果值代表是否更新了评分。
 func SetScore(module Module) bool {
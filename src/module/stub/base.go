@@ -1,6 +1,9 @@
 package stub
 
-import "module"
+import (
+	"module"
+	"time"
+)
 
 type ModuleInternal interface {
 	module.Module
@@ -9,5 +12,9 @@ type ModuleInternal interface {
 	IncrCompletedCount()
 	IncrHandlingNumber()
 	DecrHandlingNumber()
+	// IncrFailedCount 用于增加失败计数。
+	IncrFailedCount()
+	// ObserveLatency 用于记录一次处理的耗时。
+	ObserveLatency(latency time.Duration)
 	Clear()
 }
@@ -3,8 +3,10 @@ package stub
 import (
 	"errs"
 	"fmt"
+	"math"
 	"module"
 	"sync/atomic"
+	"time"
 )
 
 type myModule struct {
@@ -16,6 +18,11 @@ type myModule struct {
 	acceptedCount   uint64
 	completedCount  uint64
 	handlingNumber  uint64
+	failedCount     uint64
+	// latencyEWMA 代表处理耗时的指数加权移动平均值的位表示，单位为纳秒。
+	latencyEWMA uint64
+	// latencyBuckets 代表处理耗时直方图中各个桶的计数。
+	latencyBuckets [module.LATENCY_BUCKET_NUMBER]uint64
 }
 
 func NewModuleInternal(
@@ -75,6 +82,8 @@ func (m *myModule) Counts() module.Counts {
 		AcceptedCount:  atomic.LoadUint64(&m.acceptedCount),
 		CompletedCount: atomic.LoadUint64(&m.completedCount),
 		HandlingNumber: atomic.LoadUint64(&m.handlingNumber),
+		FailedCount:    atomic.LoadUint64(&m.failedCount),
+		LatencyEWMA:    m.loadLatencyEWMA(),
 	}
 }
 
@@ -86,10 +95,21 @@ func (m *myModule) Summary() module.SummaryStruct {
 		Accepted:  counts.AcceptedCount,
 		Completed: counts.CompletedCount,
 		Handling:  counts.HandlingNumber,
+		Failed:    counts.FailedCount,
+		Latency:   m.latencySummary(counts.LatencyEWMA),
 		Extra:     nil,
 	}
 }
 
+// latencySummary 用于生成处理耗时的摘要。
+func (m *myModule) latencySummary(ewma time.Duration) module.LatencySummaryStruct {
+	summary := module.LatencySummaryStruct{EWMA: ewma}
+	for i := range m.latencyBuckets {
+		summary.Buckets[i] = atomic.LoadUint64(&m.latencyBuckets[i])
+	}
+	return summary
+}
+
 func (m *myModule) IncrCalledCount() {
 	atomic.AddUint64(&m.calledCount, 1)
 }
@@ -109,9 +129,47 @@ func (m *myModule) DecrHandlingNumber() {
 	atomic.AddUint64(&m.handlingNumber, ^uint64(0))
 }
 
+func (m *myModule) IncrFailedCount() {
+	atomic.AddUint64(&m.failedCount, 1)
+}
+
+func (m *myModule) ObserveLatency(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	atomic.AddUint64(&m.latencyBuckets[module.LatencyBucketIndex(latency)], 1)
+	for {
+		oldBits := atomic.LoadUint64(&m.latencyEWMA)
+		newEWMA := float64(latency)
+		if oldBits != 0 {
+			oldEWMA := math.Float64frombits(oldBits)
+			newEWMA = oldEWMA + module.LATENCY_EWMA_ALPHA*(newEWMA-oldEWMA)
+		}
+		// 0代表尚无样本，因此不能存储为0。
+		newBits := math.Float64bits(math.Max(newEWMA, math.SmallestNonzeroFloat64))
+		if atomic.CompareAndSwapUint64(&m.latencyEWMA, oldBits, newBits) {
+			return
+		}
+	}
+}
+
+// loadLatencyEWMA 用于获取处理耗时的指数加权移动平均值。
+func (m *myModule) loadLatencyEWMA() time.Duration {
+	bits := atomic.LoadUint64(&m.latencyEWMA)
+	if bits == 0 {
+		return 0
+	}
+	return time.Duration(math.Float64frombits(bits))
+}
+
 func (m *myModule) Clear() {
 	atomic.StoreUint64(&m.calledCount, 0)
 	atomic.StoreUint64(&m.acceptedCount, 0)
 	atomic.StoreUint64(&m.completedCount, 0)
 	atomic.StoreUint64(&m.handlingNumber, 0)
+	atomic.StoreUint64(&m.failedCount, 0)
+	atomic.StoreUint64(&m.latencyEWMA, 0)
+	for i := range m.latencyBuckets {
+		atomic.StoreUint64(&m.latencyBuckets[i], 0)
+	}
 }
@@ -2,11 +2,43 @@ package scheduler
 
 import (
 	"module"
+	"time"
+	"toolkit/canonicalizer"
+	"toolkit/linkgraph"
 )
 
 type RequestArgs struct {
 	AcceptedDomains []string `json:"accepted_primary_domains"`
 	MaxDepth        uint32   `json:"max_depth"`
+	// MaxConcurrencyPerHost 代表对同一主机的最大并发请求数。0代表不限制。
+	MaxConcurrencyPerHost uint32 `json:"max_concurrency_per_host"`
+	// MinDelayPerHost 代表对同一主机的两次请求之间的最小间隔时间。
+	MinDelayPerHost time.Duration `json:"min_delay_per_host"`
+	// DelayJitter 代表在最小间隔时间之上随机附加的最大时长。
+	DelayJitter time.Duration `json:"delay_jitter"`
+	// PolitenessByPrimaryDomain 代表是否按主域名而非主机名限制请求。
+	PolitenessByPrimaryDomain bool `json:"politeness_by_primary_domain"`
+	// RobotsUserAgent 代表遵守robots.txt时所用的用户代理名称。为空代表不遵守robots.txt。
+	RobotsUserAgent string `json:"robots_user_agent"`
+	// RobotsTTL 代表robots.txt规则的缓存时长。0代表使用默认值。
+	RobotsTTL time.Duration `json:"robots_ttl"`
+	// AcceptEnqueuedDomains 代表是否把通过Enqueue或EnqueueRequests方法放入的请求的主域名
+	// 添加到可接受的主域名的字典。
+	AcceptEnqueuedDomains bool `json:"accept_enqueued_domains"`
+	// Retry 代表下载失败时的重试参数。
+	Retry RetryArgs `json:"retry"`
+	// Scope 代表URL范围规则相关的参数。
+	// 这些规则会在主域名和深度的检查之后被评估。
+	Scope ScopeArgs `json:"scope"`
+	// Canonicalizer 代表URL规范化器。URL会在去重之前被规范化。
+	// 为nil时使用不移除任何查询参数的默认规范化器。
+	Canonicalizer canonicalizer.Canonicalizer `json:"-"`
+	// Budget 代表爬取预算相关的参数。
+	Budget BudgetArgs `json:"budget"`
+	// DownloadTimeout 代表每个请求的下载时限，其中包括读取响应体的时间，
+	// 但不包括响应等待分析的时间。
+	// 0代表不限制。
+	DownloadTimeout time.Duration `json:"download_timeout"`
 }
 
 // Same 用于判断两个请求相关的参数容器是否相同。
@@ -17,6 +49,28 @@ func (args *RequestArgs) Same(another *RequestArgs) bool {
 	if another.MaxDepth != args.MaxDepth {
 		return false
 	}
+	if another.MaxConcurrencyPerHost != args.MaxConcurrencyPerHost ||
+		another.MinDelayPerHost != args.MinDelayPerHost ||
+		another.DelayJitter != args.DelayJitter ||
+		another.PolitenessByPrimaryDomain != args.PolitenessByPrimaryDomain ||
+		another.RobotsUserAgent != args.RobotsUserAgent ||
+		another.RobotsTTL != args.RobotsTTL ||
+		another.AcceptEnqueuedDomains != args.AcceptEnqueuedDomains ||
+		another.DownloadTimeout != args.DownloadTimeout {
+		return false
+	}
+	if !another.Retry.Same(&args.Retry) {
+		return false
+	}
+	if !another.Scope.Same(&args.Scope) {
+		return false
+	}
+	if (another.Canonicalizer == nil) != (args.Canonicalizer == nil) {
+		return false
+	}
+	if another.Budget != args.Budget {
+		return false
+	}
 	anotherDomains := another.AcceptedDomains
 	anotherDomainsLen := len(anotherDomains)
 	if anotherDomainsLen != len(args.AcceptedDomains) {
@@ -41,12 +95,94 @@ type DataArgs struct {
 	ItemMaxBufferNumber  uint32 `json:"item_max_buffer_number"`
 	ErrorBufferCap       uint32 `json:"error_buffer_cap"`
 	ErrorMaxBufferNumber uint32 `json:"error_max_buffer_number"`
+	// Workers 代表各处理阶段的工作协程的数量。
+	Workers WorkerArgs `json:"workers"`
+	// ReqFrontier 代表请求前沿相关的参数。
+	ReqFrontier FrontierArgs `json:"req_frontier"`
+	// ReqSpillDir 代表请求缓冲池存放溢出请求的目录。
+	// 不为空时使用磁盘缓冲池，内存中的请求数量不会超过
+	// ReqBufferCap与ReqMaxBufferNumber的乘积。
+	ReqSpillDir string `json:"req_spill_dir"`
+	// DeadLetterPath 代表存储死信的JSONL文件的路径。为空代表不存储死信。
+	DeadLetterPath string `json:"dead_letter_path"`
+	// Visited 代表已处理URL集合相关的参数。
+	Visited VisitedArgs `json:"visited"`
+	// LinkGraph 代表记录链接图的存储。为nil代表不记录链接图。
+	// 分析器发现的每个链接都会被记录为一条边，无论其目标URL是否被接受。
+	// 调度器不会关闭该存储。
+	LinkGraph linkgraph.Store `json:"-"`
+}
+
+// Same 用于判断两个数据相关的参数容器是否相同。
+// 其中的函数类型的字段只比较是否为nil。
+func (args *DataArgs) Same(another *DataArgs) bool {
+	if another == nil {
+		return false
+	}
+	if another.ReqBufferCap != args.ReqBufferCap ||
+		another.ReqMaxBufferNumber != args.ReqMaxBufferNumber ||
+		another.RespBufferCap != args.RespBufferCap ||
+		another.RespMaxBufferNumber != args.RespMaxBufferNumber ||
+		another.ItemBufferCap != args.ItemBufferCap ||
+		another.ItemMaxBufferNumber != args.ItemMaxBufferNumber ||
+		another.ErrorBufferCap != args.ErrorBufferCap ||
+		another.ErrorMaxBufferNumber != args.ErrorMaxBufferNumber {
+		return false
+	}
+	if another.ReqSpillDir != args.ReqSpillDir ||
+		another.DeadLetterPath != args.DeadLetterPath {
+		return false
+	}
+	if another.Workers != args.Workers || another.Visited != args.Visited {
+		return false
+	}
+	if (another.LinkGraph == nil) != (args.LinkGraph == nil) {
+		return false
+	}
+	if (another.ReqFrontier.Priority == nil) != (args.ReqFrontier.Priority == nil) ||
+		another.ReqFrontier.HostFairness != args.ReqFrontier.HostFairness {
+		return false
+	}
+	return true
+}
+
+// FrontierArgs 代表请求前沿相关的参数容器的类型。
+type FrontierArgs struct {
+	// Priority 代表请求优先级的计算函数。
+	// 为nil时使用先进先出的请求缓冲池，否则使用优先级前沿。
+	Priority PriorityFunc `json:"-"`
+	// HostFairness 代表是否在各主机之间轮流取出请求。
+	// 只在Priority不为nil时有效。
+	HostFairness bool `json:"host_fairness"`
+}
+
+// WorkerArgs 代表工作协程相关的参数容器的类型。
+// 某个字段为0时，该阶段的工作协程的数量等于对应组件的数量（至少为1）。
+type WorkerArgs struct {
+	// DownloaderNumber 代表下载阶段的工作协程的数量。
+	DownloaderNumber uint32 `json:"downloader_number"`
+	// AnalyzerNumber 代表分析阶段的工作协程的数量。
+	AnalyzerNumber uint32 `json:"analyzer_number"`
+	// PipelineNumber 代表条目处理阶段的工作协程的数量。
+	PipelineNumber uint32 `json:"pipeline_number"`
 }
 
 type ModuleArgs struct {
 	Downloaders []module.Downloader
 	Analyzers   []module.Analyzer
 	Pipelines   []module.Pipeline
+	// Balancers 代表各类型的组件所用的负载均衡器。
+	// 未指定的类型使用选择评分最低的实例的负载均衡策略。
+	// 对于基于一致性哈希的负载均衡器，下载器和分析器以请求的主机名为键。
+	Balancers map[module.Type]module.Balancer
+	// Breaker 代表每个组件实例的断路器的参数。
+	// 断路器断开的组件实例不会被选择，除非同一类型的所有实例的断路器都已断开。
+	Breaker module.BreakerArgs
+	// HealthCheck 代表主动健康检查函数。
+	// 为nil时只检查实现了module.HealthChecker接口的组件。
+	HealthCheck module.HealthCheckFunc
+	// HealthCheckInterval 代表主动健康检查的间隔时间。0代表不执行主动健康检查。
+	HealthCheckInterval time.Duration
 }
 
 type Args interface {
@@ -57,6 +193,27 @@ func (args *RequestArgs) Check() error {
 	if args.AcceptedDomains == nil {
 		return genError("nil accepted primary domain list")
 	}
+	if args.MinDelayPerHost < 0 {
+		return genError("negative min delay per host")
+	}
+	if args.DelayJitter < 0 {
+		return genError("negative delay jitter")
+	}
+	if args.RobotsTTL < 0 {
+		return genError("negative robots.txt TTL")
+	}
+	if args.DownloadTimeout < 0 {
+		return genError("negative download timeout")
+	}
+	if err := args.Retry.Check(); err != nil {
+		return err
+	}
+	if err := args.Scope.Check(); err != nil {
+		return err
+	}
+	if err := args.Budget.Check(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -85,6 +242,31 @@ func (args *DataArgs) Check() error {
 	if args.ErrorMaxBufferNumber == 0 {
 		return genError("zero max error buffer number")
 	}
+	if err := args.Workers.Check(); err != nil {
+		return err
+	}
+	if err := args.Visited.Check(); err != nil {
+		return err
+	}
+	if args.ReqSpillDir != "" && args.ReqFrontier.Priority != nil {
+		return genError("the priority frontier couldn't spill requests to disk")
+	}
+	return nil
+}
+
+// MAX_WORKER_NUMBER 代表单个处理阶段的工作协程的最大数量。
+const MAX_WORKER_NUMBER uint32 = 1024
+
+func (args *WorkerArgs) Check() error {
+	if args.DownloaderNumber > MAX_WORKER_NUMBER {
+		return genError("too many download workers")
+	}
+	if args.AnalyzerNumber > MAX_WORKER_NUMBER {
+		return genError("too many analyze workers")
+	}
+	if args.PipelineNumber > MAX_WORKER_NUMBER {
+		return genError("too many pick workers")
+	}
 	return nil
 }
 
@@ -99,6 +281,12 @@ func (args *ModuleArgs) Check() error {
 	if len(args.Pipelines) == 0 {
 		return genError("empty pipeline list")
 	}
+	if err := args.Breaker.Check(); err != nil {
+		return genErrorByError(err)
+	}
+	if args.HealthCheckInterval < 0 {
+		return genError("negative health check interval")
+	}
 	return nil
 }
 
@@ -107,12 +295,18 @@ type ModuleArgsSummary struct {
 	DownloaderListSize int `json:"downloader_list_size"`
 	AnalyzerListSize   int `json:"analyzer_List_size"`
 	PipelineListSize   int `json:"pipeline_list_size"`
+	// Breaker 代表断路器的参数。
+	Breaker module.BreakerArgs `json:"breaker"`
+	// HealthCheckInterval 代表主动健康检查的间隔时间。
+	HealthCheckInterval time.Duration `json:"health_check_interval"`
 }
 
 func (args *ModuleArgs) Summary() ModuleArgsSummary {
 	return ModuleArgsSummary{
-		DownloaderListSize: len(args.Downloaders),
-		AnalyzerListSize:   len(args.Analyzers),
-		PipelineListSize:   len(args.Pipelines),
+		DownloaderListSize:  len(args.Downloaders),
+		AnalyzerListSize:    len(args.Analyzers),
+		PipelineListSize:    len(args.Pipelines),
+		Breaker:             args.Breaker,
+		HealthCheckInterval: args.HealthCheckInterval,
 	}
 }
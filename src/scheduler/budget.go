@@ -0,0 +1,250 @@
+package scheduler
+
+import (
+	"fmt"
+	"io"
+	"module"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BudgetArgs 代表爬取预算相关的参数。
+// 任何一项预算耗尽后，调度器都不再接受新的请求。
+// 其中，下载字节数或时长的预算耗尽后，尚未开始下载的请求也会被丢弃，
+// 而正在处理的数据会被处理完毕，从而使调度器逐渐变为空闲。
+// 各项预算都从调度器启动时开始计算。
+// 从检查点恢复时，已接受的请求的数量和已下载的字节数会被还原，
+// 检查点中的待处理请求会被重新扣除预算，而时长会重新开始计算。
+type BudgetArgs struct {
+	// MaxRequests 代表最多接受的请求的数量。0代表不限制。
+	MaxRequests uint64 `json:"max_requests,omitempty"`
+	// MaxBytes 代表最多下载的响应体的字节数。0代表不限制。
+	MaxBytes uint64 `json:"max_bytes,omitempty"`
+	// MaxDuration 代表最长的爬取时长。0代表不限制。
+	MaxDuration time.Duration `json:"max_duration,omitempty"`
+	// MaxRequestsPerDomain 代表对每个主域名最多接受的请求的数量。0代表不限制。
+	// 某个主域名的配额耗尽不影响其他主域名。
+	MaxRequestsPerDomain uint64 `json:"max_requests_per_domain,omitempty"`
+}
+
+// Check 用于检查爬取预算相关参数的有效性。
+func (args *BudgetArgs) Check() error {
+	if args.MaxDuration < 0 {
+		return genError("negative max crawl duration")
+	}
+	return nil
+}
+
+// BudgetSummaryStruct 代表爬取预算的摘要类型。
+// 剩余预算为-1代表不限制。
+type BudgetSummaryStruct struct {
+	RemainingRequests int64         `json:"remaining_requests"`
+	RemainingBytes    int64         `json:"remaining_bytes"`
+	RemainingDuration time.Duration `json:"remaining_duration"`
+	// DownloadedBytes 代表已下载的响应体的字节数。
+	DownloadedBytes uint64 `json:"downloaded_bytes"`
+	// QuotaExhaustedDomains 代表配额已耗尽的主域名的数量。
+	QuotaExhaustedDomains uint32 `json:"quota_exhausted_domains"`
+	// Discarded 代表因预算耗尽而被丢弃的已排队请求的数量。
+	Discarded uint64 `json:"discarded"`
+	// Exhausted 代表已耗尽的预算。为空代表尚未耗尽。
+	Exhausted string `json:"exhausted,omitempty"`
+}
+
+// budget 代表爬取预算的记账器。
+type budget struct {
+	args BudgetArgs
+	// startTime 代表开始计算预算的时间。
+	startTime time.Time
+	// requests 代表已接受的请求的数量。
+	requests uint64
+	// domainRequests 代表主域名与已接受的请求数量的字典。
+	domainRequests map[string]uint64
+	// bytes 代表已下载的响应体的字节数。
+	bytes uint64
+	// discarded 代表因预算耗尽而被丢弃的请求的数量。
+	discarded uint64
+	lock      sync.Mutex
+}
+
+// newBudget 会创建一个爬取预算的记账器。
+func newBudget(args BudgetArgs) *budget {
+	return &budget{
+		args:           args,
+		startTime:      time.Now(),
+		domainRequests: map[string]uint64{},
+	}
+}
+
+// start 用于重置所有的计数并开始计算预算。
+func (b *budget) start() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.startTime = time.Now()
+	b.requests = 0
+	b.domainRequests = map[string]uint64{}
+	atomic.StoreUint64(&b.bytes, 0)
+	atomic.StoreUint64(&b.discarded, 0)
+}
+
+// halted 用于判断是否因下载字节数或时长的预算耗尽而应停止下载。
+// 结果值为空字符串代表可以继续下载，否则代表已耗尽的预算。
+func (b *budget) halted() string {
+	if b.args.MaxBytes > 0 && atomic.LoadUint64(&b.bytes) >= b.args.MaxBytes {
+		return "max bytes"
+	}
+	if b.args.MaxDuration > 0 {
+		b.lock.Lock()
+		startTime := b.startTime
+		b.lock.Unlock()
+		if time.Since(startTime) >= b.args.MaxDuration {
+			return "max duration"
+		}
+	}
+	return ""
+}
+
+// exhausted 用于获取已耗尽的预算。为空字符串代表尚未耗尽。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (b *budget) exhausted() string {
+	if b.args.MaxRequests > 0 && b.requests >= b.args.MaxRequests {
+		return "max requests"
+	}
+	return b.halted()
+}
+
+// take 用于为给定的请求扣除预算。
+// 参数primaryDomain代表请求所属的主域名。
+// 结果值为空字符串代表已扣除，否则代表请求因预算耗尽而被拒绝的原因。
+func (b *budget) take(req *module.Request, primaryDomain string) string {
+	if b.args.MaxDuration > 0 || b.args.MaxBytes > 0 {
+		if reason := b.halted(); reason != "" {
+			return fmt.Sprintf("The crawl budget %q is exhausted. (URL: %s)",
+				reason, req.HTTPReq().URL)
+		}
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.args.MaxRequests > 0 && b.requests >= b.args.MaxRequests {
+		return fmt.Sprintf("The crawl budget %q is exhausted. (URL: %s)",
+			"max requests", req.HTTPReq().URL)
+	}
+	if b.args.MaxRequestsPerDomain > 0 &&
+		b.domainRequests[primaryDomain] >= b.args.MaxRequestsPerDomain {
+		return fmt.Sprintf("The quota of primary domain %q is exhausted. (URL: %s)",
+			primaryDomain, req.HTTPReq().URL)
+	}
+	b.requests++
+	b.domainRequests[primaryDomain]++
+	return ""
+}
+
+// snapshot 用于把预算的计数存入给定的检查点。
+// 参数pending代表检查点中的待处理请求。
+// 它们已扣除的预算不会被存入，而会在恢复时被重新扣除。
+func (b *budget) snapshot(cp *checkpoint, pending []*module.Request) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	bs := &budgetSnapshot{
+		Requests:       b.requests,
+		DomainRequests: make(map[string]uint64, len(b.domainRequests)),
+		Bytes:          atomic.LoadUint64(&b.bytes),
+	}
+	for pd, number := range b.domainRequests {
+		bs.DomainRequests[pd] = number
+	}
+	for _, req := range pending {
+		if bs.Requests > 0 {
+			bs.Requests--
+		}
+		pd, _ := getPrimaryDomain(getReqHost(req.HTTPReq()))
+		if number := bs.DomainRequests[pd]; number > 1 {
+			bs.DomainRequests[pd] = number - 1
+		} else {
+			delete(bs.DomainRequests, pd)
+		}
+	}
+	cp.Budget = bs
+}
+
+// restore 用于从给定的检查点还原预算的计数。
+// 注意！必须在start方法之后调用本方法，否则还原的计数会被重置！
+func (b *budget) restore(cp *checkpoint) {
+	if cp.Budget == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.requests = cp.Budget.Requests
+	b.domainRequests = make(map[string]uint64, len(cp.Budget.DomainRequests))
+	for pd, number := range cp.Budget.DomainRequests {
+		b.domainRequests[pd] = number
+	}
+	atomic.StoreUint64(&b.bytes, cp.Budget.Bytes)
+}
+
+// addBytes 用于记录已下载的字节数。
+func (b *budget) addBytes(n uint64) {
+	atomic.AddUint64(&b.bytes, n)
+}
+
+// discard 用于记录一个因预算耗尽而被丢弃的请求。
+func (b *budget) discard() {
+	atomic.AddUint64(&b.discarded, 1)
+}
+
+func (b *budget) summary() BudgetSummaryStruct {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	summary := BudgetSummaryStruct{
+		RemainingRequests: -1,
+		RemainingBytes:    -1,
+		RemainingDuration: -1,
+		DownloadedBytes:   atomic.LoadUint64(&b.bytes),
+		Discarded:         atomic.LoadUint64(&b.discarded),
+		Exhausted:         b.exhausted(),
+	}
+	if b.args.MaxRequests > 0 {
+		summary.RemainingRequests = remaining(b.args.MaxRequests, b.requests)
+	}
+	if b.args.MaxBytes > 0 {
+		summary.RemainingBytes = remaining(b.args.MaxBytes, summary.DownloadedBytes)
+	}
+	if b.args.MaxDuration > 0 {
+		summary.RemainingDuration = b.args.MaxDuration - time.Since(b.startTime)
+		if summary.RemainingDuration < 0 {
+			summary.RemainingDuration = 0
+		}
+	}
+	if b.args.MaxRequestsPerDomain > 0 {
+		for _, number := range b.domainRequests {
+			if number >= b.args.MaxRequestsPerDomain {
+				summary.QuotaExhaustedDomains++
+			}
+		}
+	}
+	return summary
+}
+
+// remaining 用于计算剩余的预算。
+func remaining(max uint64, used uint64) int64 {
+	if used >= max {
+		return 0
+	}
+	return int64(max - used)
+}
+
+// countingBody 代表会把读出的字节数计入预算的响应体。
+type countingBody struct {
+	io.ReadCloser
+	budget *budget
+}
+
+func (body *countingBody) Read(p []byte) (n int, err error) {
+	n, err = body.ReadCloser.Read(p)
+	if n > 0 {
+		body.budget.addBytes(uint64(n))
+	}
+	return
+}
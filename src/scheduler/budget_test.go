@@ -0,0 +1,232 @@
+package scheduler
+
+import (
+	"bytes"
+	"module"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBudgetTake(t *testing.T) {
+	cases := []struct {
+		name string
+		args BudgetArgs
+		// domains 代表依次被扣除预算的请求所属的主域名。
+		domains []string
+		// rejected 代表各请求被拒绝的原因中应包含的内容。为空代表应被接受。
+		rejected []string
+	}{
+		{
+			name:     "unlimited",
+			args:     BudgetArgs{},
+			domains:  []string{"a.com", "a.com", "b.com"},
+			rejected: []string{"", "", ""},
+		},
+		{
+			name:     "max requests",
+			args:     BudgetArgs{MaxRequests: 2},
+			domains:  []string{"a.com", "b.com", "c.com"},
+			rejected: []string{"", "", `"max requests"`},
+		},
+		{
+			name:    "per domain",
+			args:    BudgetArgs{MaxRequestsPerDomain: 2},
+			domains: []string{"a.com", "a.com", "b.com", "a.com", "b.com", "b.com"},
+			rejected: []string{"", "", "", `primary domain "a.com"`,
+				"", `primary domain "b.com"`},
+		},
+		{
+			// 被配额拒绝的请求不会占用总预算。
+			name:     "both",
+			args:     BudgetArgs{MaxRequests: 3, MaxRequestsPerDomain: 1},
+			domains:  []string{"a.com", "a.com", "b.com", "c.com", "d.com"},
+			rejected: []string{"", `primary domain "a.com"`, "", "", `"max requests"`},
+		},
+	}
+	for _, c := range cases {
+		b := newBudget(c.args)
+		for i, domain := range c.domains {
+			reason := b.take(newTestReq(t, "http://www."+domain+"/", 0), domain)
+			if c.rejected[i] == "" && reason != "" {
+				t.Fatalf("The request #%d was rejected in the %s case: %s", i, c.name, reason)
+			}
+			if c.rejected[i] != "" && !strings.Contains(reason, c.rejected[i]) {
+				t.Fatalf("Inconsistent reason for request #%d in the %s case: expected: %q, actual: %q",
+					i, c.name, c.rejected[i], reason)
+			}
+		}
+	}
+}
+
+func TestBudgetHalted(t *testing.T) {
+	b := newBudget(BudgetArgs{MaxBytes: 10})
+	b.addBytes(9)
+	if reason := b.halted(); reason != "" {
+		t.Fatalf("The budget was halted: %s", reason)
+	}
+	b.addBytes(1)
+	if reason := b.halted(); reason != "max bytes" {
+		t.Fatalf("Inconsistent halted reason: expected: %q, actual: %q", "max bytes", reason)
+	}
+	if reason := b.take(newTestReq(t, "http://a.com/", 0), "a.com"); !strings.Contains(reason, `"max bytes"`) {
+		t.Fatalf("Inconsistent reason: %q", reason)
+	}
+	b = newBudget(BudgetArgs{MaxDuration: 20 * time.Millisecond})
+	b.start()
+	if reason := b.halted(); reason != "" {
+		t.Fatalf("The budget was halted: %s", reason)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if reason := b.halted(); reason != "max duration" {
+		t.Fatalf("Inconsistent halted reason: expected: %q, actual: %q", "max duration", reason)
+	}
+}
+
+func TestBudgetSummary(t *testing.T) {
+	b := newBudget(BudgetArgs{MaxRequests: 5, MaxBytes: 100, MaxRequestsPerDomain: 2})
+	for _, domain := range []string{"a.com", "a.com", "b.com"} {
+		b.take(newTestReq(t, "http://"+domain+"/", 0), domain)
+	}
+	b.addBytes(40)
+	b.discard()
+	summary := b.summary()
+	if summary.RemainingRequests != 2 || summary.RemainingBytes != 60 ||
+		summary.RemainingDuration != -1 || summary.DownloadedBytes != 40 ||
+		summary.QuotaExhaustedDomains != 1 || summary.Discarded != 1 ||
+		summary.Exhausted != "" {
+		t.Fatalf("Inconsistent summary: %+v", summary)
+	}
+}
+
+func TestBudgetSnapshot(t *testing.T) {
+	b := newBudget(BudgetArgs{})
+	reqs := []*module.Request{
+		newTestReq(t, "http://a.com/1", 0),
+		newTestReq(t, "http://www.a.com/2", 0),
+		newTestReq(t, "http://b.com/1", 0),
+	}
+	for _, req := range reqs {
+		pd, _ := getPrimaryDomain(getReqHost(req.HTTPReq()))
+		b.take(req, pd)
+	}
+	b.addBytes(123)
+	// 待处理请求已扣除的预算不会被存入检查点。
+	cp := &checkpoint{}
+	b.snapshot(cp, reqs[1:])
+	bs := cp.Budget
+	if bs == nil || bs.Requests != 1 || bs.Bytes != 123 ||
+		len(bs.DomainRequests) != 1 || bs.DomainRequests["a.com"] != 1 {
+		t.Fatalf("Inconsistent budget snapshot: %+v", bs)
+	}
+	restored := newBudget(BudgetArgs{})
+	restored.start()
+	restored.restore(cp)
+	if restored.requests != 1 || restored.domainRequests["a.com"] != 1 ||
+		atomic.LoadUint64(&restored.bytes) != 123 {
+		t.Fatalf("Inconsistent restored budget: requests: %d, domain requests: %v, bytes: %d",
+			restored.requests, restored.domainRequests, restored.bytes)
+	}
+	// 没有预算计数的检查点不会改变计数。
+	restored.restore(&checkpoint{})
+	if restored.requests != 1 {
+		t.Fatalf("Inconsistent requests: expected: %d, actual: %d", 1, restored.requests)
+	}
+}
+
+func TestResumeChargesBudget(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	var itemCount uint32
+	modules := newTestModules(t, srv.URL, func(item module.Item) {
+		atomic.AddUint32(&itemCount, 1)
+	})
+	sched := newTestScheduler(t, RequestArgs{
+		MaxDepth: 1,
+		Budget:   BudgetArgs{MaxRequests: 3},
+	}, modules)
+	defer sched.Stop()
+	// 检查点中已接受了2个请求，因此2个待处理请求中只有1个还能被下载。
+	cp := &checkpoint{
+		Version:         checkpointVersion,
+		AcceptedDomains: []string{"127.0.0.1"},
+		VisitedURLs:     []string{srv.URL + "/p0", srv.URL + "/p1", srv.URL + "/p2"},
+		PendingRequests: []requestSnapshot{
+			{URL: srv.URL + "/p1", Depth: 1},
+			{URL: srv.URL + "/p2", Depth: 1},
+		},
+		Budget: &budgetSnapshot{Requests: 2},
+	}
+	var buffer bytes.Buffer
+	if err := writeCheckpoint(&buffer, cp); err != nil {
+		t.Fatalf("An error occurs when writing checkpoint: %s", err)
+	}
+	if err := sched.ResumeFrom(&buffer); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	waitForIdle(t, sched)
+	if actual := atomic.LoadUint32(&itemCount); actual != 1 {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", 1, actual)
+	}
+	summary := sched.(*myScheduler).budget.summary()
+	if summary.RemainingRequests != 0 || summary.Exhausted != "max requests" {
+		t.Fatalf("Inconsistent budget summary: %+v", summary)
+	}
+}
+
+func TestHaltedRequestsKeptInCheckpoint(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	var itemCount uint32
+	modules := newTestModules(t, srv.URL, func(item module.Item) {
+		atomic.AddUint32(&itemCount, 1)
+	})
+	sched := newTestScheduler(t, RequestArgs{
+		MaxDepth: 1,
+		Budget:   BudgetArgs{MaxBytes: 1},
+	}, modules)
+	defer sched.Stop()
+	// 只有一个下载工作协程，因此下载第一个请求之后剩余的请求都会因预算耗尽而不被下载。
+	urls := []string{srv.URL + "/p1", srv.URL + "/p2", srv.URL + "/p3"}
+	cp := &checkpoint{
+		Version:         checkpointVersion,
+		AcceptedDomains: []string{"127.0.0.1"},
+		VisitedURLs:     append([]string{srv.URL + "/p0"}, urls...),
+		PendingRequests: []requestSnapshot{
+			{URL: urls[0], Depth: 1},
+			{URL: urls[1], Depth: 1},
+			{URL: urls[2], Depth: 1},
+		},
+	}
+	var buffer bytes.Buffer
+	if err := writeCheckpoint(&buffer, cp); err != nil {
+		t.Fatalf("An error occurs when writing checkpoint: %s", err)
+	}
+	if err := sched.ResumeFrom(&buffer); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	waitForIdle(t, sched)
+	if actual := atomic.LoadUint32(&itemCount); actual != 1 {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", 1, actual)
+	}
+	if summary := sched.(*myScheduler).budget.summary(); summary.Discarded != 2 {
+		t.Fatalf("Inconsistent discarded number: expected: %d, actual: %d",
+			2, summary.Discarded)
+	}
+	buffer.Reset()
+	if err := sched.Checkpoint(&buffer); err != nil {
+		t.Fatalf("An error occurs when generating checkpoint: %s", err)
+	}
+	saved, err := readCheckpoint(&buffer)
+	if err != nil {
+		t.Fatalf("An error occurs when reading checkpoint: %s", err)
+	}
+	if len(saved.PendingRequests) != 2 {
+		t.Fatalf("Inconsistent pending request number: expected: %d, actual: %d",
+			2, len(saved.PendingRequests))
+	}
+	if saved.Budget == nil || saved.Budget.Requests != 1 {
+		t.Fatalf("Inconsistent budget snapshot: %+v", saved.Budget)
+	}
+}
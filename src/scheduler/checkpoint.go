@@ -0,0 +1,212 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"module"
+	"net/http"
+	"sort"
+	"time"
+	"toolkit/buffer"
+)
+
+// checkpointVersion 代表检查点数据格式的版本。
+const checkpointVersion uint32 = 1
+
+// checkpoint 代表调度器检查点的结构。
+type checkpoint struct {
+	// Version 代表检查点数据格式的版本。
+	Version uint32 `json:"version"`
+	// RequestArgs 代表生成检查点时使用的请求相关参数。
+	RequestArgs RequestArgs `json:"request_args"`
+	// AcceptedDomains 代表可接受的主域名的列表。
+	// 其中包括从首次请求中获得的主域名。
+	AcceptedDomains []string `json:"accepted_domains"`
+	// VisitedURLs 代表已处理的URL的列表。
+	VisitedURLs []string `json:"visited_urls"`
+	// VisitedFilter 代表序列化后的记录已处理URL的布隆过滤器。
+	// 仅在使用布隆过滤器记录已处理的URL时存在。
+	VisitedFilter []byte `json:"visited_filter,omitempty"`
+	// PendingRequests 代表尚未下载完毕的请求的列表。
+	PendingRequests []requestSnapshot `json:"pending_requests"`
+	// Budget 代表爬取预算的计数。
+	// 较早版本的检查点中没有它，此时只有待处理请求会被扣除预算。
+	Budget *budgetSnapshot `json:"budget,omitempty"`
+}
+
+// budgetSnapshot 代表爬取预算的计数的快照。
+// 其中不包括为待处理请求扣除的预算，它们会在恢复时被重新扣除。
+type budgetSnapshot struct {
+	// Requests 代表已接受的请求的数量。
+	Requests uint64 `json:"requests"`
+	// DomainRequests 代表主域名与已接受的请求数量的字典。
+	DomainRequests map[string]uint64 `json:"domain_requests,omitempty"`
+	// Bytes 代表已下载的响应体的字节数。
+	Bytes uint64 `json:"bytes"`
+}
+
+// requestSnapshot 代表请求的快照。
+// 注意！HTTP请求体不会被保存。
+type requestSnapshot struct {
+	URL      string      `json:"url"`
+	Method   string      `json:"method"`
+	Header   http.Header `json:"header,omitempty"`
+	Depth    uint32      `json:"depth"`
+	Priority int32       `json:"priority,omitempty"`
+	Attempt  uint32      `json:"attempt,omitempty"`
+	// ParentURL 代表发现该请求的页面的URL。
+	ParentURL    string                 `json:"parent_url,omitempty"`
+	AnchorText   string                 `json:"anchor_text,omitempty"`
+	DiscoveredAt time.Time              `json:"discovered_at"`
+	Attrs        map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// newRequestSnapshot 用于生成给定请求的快照。
+func newRequestSnapshot(req *module.Request) (requestSnapshot, bool) {
+	if req == nil || !req.Valid() {
+		return requestSnapshot{}, false
+	}
+	httpReq := req.HTTPReq()
+	return requestSnapshot{
+		URL:          httpReq.URL.String(),
+		Method:       httpReq.Method,
+		Header:       httpReq.Header,
+		Depth:        req.Depth(),
+		Priority:     req.Priority(),
+		Attempt:      req.Attempt(),
+		ParentURL:    req.ParentURL(),
+		AnchorText:   req.AnchorText(),
+		DiscoveredAt: req.DiscoveredAt(),
+		Attrs:        req.Attrs(),
+	}, true
+}
+
+// toRequest 用于根据快照还原请求。
+func (rs requestSnapshot) toRequest() (*module.Request, error) {
+	method := rs.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	httpReq, err := http.NewRequest(method, rs.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rs.Header != nil {
+		httpReq.Header = rs.Header
+	}
+	req := module.NewRequest(httpReq, rs.Depth)
+	req.SetPriority(rs.Priority)
+	req.SetAttempt(rs.Attempt)
+	req.SetParentURL(rs.ParentURL)
+	req.SetAnchorText(rs.AnchorText)
+	if !rs.DiscoveredAt.IsZero() {
+		req.SetDiscoveredAt(rs.DiscoveredAt)
+	}
+	for key, value := range rs.Attrs {
+		req.SetAttr(key, value)
+	}
+	return req, nil
+}
+
+// requestCodec 代表请求的编解码器。
+// 磁盘缓冲池用它把请求以快照的形式存放到磁盘上。
+type requestCodec struct{}
+
+func (requestCodec) Encode(datum interface{}) ([]byte, error) {
+	req, ok := datum.(*module.Request)
+	if !ok {
+		return nil, fmt.Errorf("incorrect request type: %T", datum)
+	}
+	rs, ok := newRequestSnapshot(req)
+	if !ok {
+		return nil, fmt.Errorf("invalid request: %v", req)
+	}
+	return json.Marshal(rs)
+}
+
+func (requestCodec) Decode(data []byte) (interface{}, error) {
+	var rs requestSnapshot
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, err
+	}
+	return rs.toRequest()
+}
+
+// genCheckpoint 用于生成调度器当前状态的检查点。
+func (sched *myScheduler) genCheckpoint() *checkpoint {
+	cp := &checkpoint{
+		Version:         checkpointVersion,
+		RequestArgs:     sched.requestArgs,
+		AcceptedDomains: []string{},
+		VisitedURLs:     []string{},
+		PendingRequests: []requestSnapshot{},
+	}
+	sched.acceptedDomainMap.Range(func(key string, element interface{}) bool {
+		cp.AcceptedDomains = append(cp.AcceptedDomains, key)
+		return true
+	})
+	sched.visitedURLs.snapshot(cp)
+	var pendingReqs []*module.Request
+	seen := map[string]struct{}{}
+	collect := func(key string, element interface{}) bool {
+		req, ok := element.(*module.Request)
+		if !ok {
+			return true
+		}
+		if _, ok := seen[key]; ok {
+			return true
+		}
+		if rs, ok := newRequestSnapshot(req); ok {
+			seen[key] = struct{}{}
+			cp.PendingRequests = append(cp.PendingRequests, rs)
+			pendingReqs = append(pendingReqs, req)
+		}
+		return true
+	}
+	sched.pendingReqMap.Range(collect)
+	// 磁盘请求缓冲池中的请求不在待处理的请求的字典中。
+	// 调度器停止之后，它们已被记录为待处理的请求，而缓冲池已被关闭。
+	if pool := sched.diskReqPool(); pool != nil {
+		err := pool.Range(func(datum interface{}) bool {
+			if req, ok := datum.(*module.Request); ok && req.Valid() {
+				collect(req.HTTPReq().URL.String(), req)
+			}
+			return true
+		})
+		if err != nil && err != buffer.ErrClosedBufferPool {
+			log.Printf("Couldn't read the requests in the request buffer pool: %s\n", err)
+		}
+	}
+	// 因预算耗尽而未被下载的请求也需要在恢复时以更多的预算下载。
+	sched.discardedReqMap.Range(collect)
+	sched.budget.snapshot(cp, pendingReqs)
+	sort.Strings(cp.AcceptedDomains)
+	sort.Slice(cp.PendingRequests, func(i, j int) bool {
+		if cp.PendingRequests[i].Depth != cp.PendingRequests[j].Depth {
+			return cp.PendingRequests[i].Depth < cp.PendingRequests[j].Depth
+		}
+		return cp.PendingRequests[i].URL < cp.PendingRequests[j].URL
+	})
+	return cp
+}
+
+// writeCheckpoint 用于把检查点写入给定的写入器。
+func writeCheckpoint(w io.Writer, cp *checkpoint) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(cp)
+}
+
+// readCheckpoint 用于从给定的读取器读出检查点。
+func readCheckpoint(r io.Reader) (*checkpoint, error) {
+	cp := &checkpoint{}
+	if err := json.NewDecoder(r).Decode(cp); err != nil {
+		return nil, fmt.Errorf("couldn't decode checkpoint: %s", err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version: %d", cp.Version)
+	}
+	return cp, nil
+}
@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"module"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gateDownloader 代表会在下载之前等待放行的下载器。
+type gateDownloader struct {
+	module.Downloader
+	// release 被关闭之后才会开始下载。
+	release chan struct{}
+}
+
+func (downloader *gateDownloader) Download(
+	ctx context.Context, req *module.Request) (*module.Response, error) {
+	select {
+	case <-downloader.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return downloader.Downloader.Download(ctx, req)
+}
+
+// checkpointURLs 用于获取调度器当前的检查点中的待处理请求的URL。
+func checkpointURLs(t *testing.T, sched Scheduler) []string {
+	var buffer bytes.Buffer
+	if err := sched.Checkpoint(&buffer); err != nil {
+		t.Fatalf("An error occurs when generating checkpoint: %s", err)
+	}
+	cp, err := readCheckpoint(&buffer)
+	if err != nil {
+		t.Fatalf("An error occurs when reading checkpoint: %s", err)
+	}
+	var urls []string
+	for _, rs := range cp.PendingRequests {
+		urls = append(urls, rs.URL)
+	}
+	return urls
+}
+
+// newSpillingTestScheduler 会创建一个使用磁盘请求缓冲池的测试用调度器。
+// 请求缓冲池的内存中只能存放一个请求，其余的请求都会被存放到给定的目录中。
+// 结果值release被关闭之后下载器才会开始下载。
+func newSpillingTestScheduler(
+	t *testing.T, modules testModules, dirPath string) (sched Scheduler, release chan struct{}) {
+	release = make(chan struct{})
+	modules.downloader = &gateDownloader{Downloader: modules.downloader, release: release}
+	dataArgs := newTestDataArgs()
+	dataArgs.ReqBufferCap = 1
+	dataArgs.ReqMaxBufferNumber = 1
+	dataArgs.ReqSpillDir = dirPath
+	sched = NewScheduler()
+	err := sched.Init(RequestArgs{
+		AcceptedDomains: []string{"127.0.0.1"},
+		MaxDepth:        1,
+	}, dataArgs, ModuleArgs{
+		Downloaders: []module.Downloader{modules.downloader},
+		Analyzers:   []module.Analyzer{modules.analyzer},
+		Pipelines:   []module.Pipeline{modules.pipeline},
+	})
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	return sched, release
+}
+
+// resumeWithPendingReqs 会让调度器从一个具有给定数量的待处理请求的检查点恢复，
+// 并等待下载工作协程取出请求并开始下载。结果值是待处理请求的URL的有序列表。
+func resumeWithPendingReqs(
+	t *testing.T, sched Scheduler, srvURL string, number int) []string {
+	cp := &checkpoint{
+		Version:         checkpointVersion,
+		AcceptedDomains: []string{"127.0.0.1"},
+		VisitedURLs:     []string{srvURL + "/p0"},
+	}
+	var urls []string
+	for i := 1; i <= number; i++ {
+		url := fmt.Sprintf("%s/p%d", srvURL, i)
+		cp.VisitedURLs = append(cp.VisitedURLs, url)
+		cp.PendingRequests = append(cp.PendingRequests, requestSnapshot{URL: url, Depth: 1})
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	var buffer bytes.Buffer
+	if err := writeCheckpoint(&buffer, cp); err != nil {
+		t.Fatalf("An error occurs when writing checkpoint: %s", err)
+	}
+	if err := sched.ResumeFrom(&buffer); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for sched.(*myScheduler).downloadWorkers.busyNumber() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("No request is being downloaded!")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return urls
+}
+
+func TestCheckpointWithDiskReqPool(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	var itemCount uint32
+	modules := newTestModules(t, srv.URL, func(item module.Item) {
+		atomic.AddUint32(&itemCount, 1)
+	})
+	dirPath, _ := ioutil.TempDir("", "scheduler_test")
+	defer os.RemoveAll(dirPath)
+	sched, release := newSpillingTestScheduler(t, modules, dirPath)
+	defer sched.Stop()
+	number := 20
+	expectedURLs := resumeWithPendingReqs(t, sched, srv.URL, number)
+	mySched := sched.(*myScheduler)
+	// 磁盘请求缓冲池中的请求不应被记录在待处理的请求的字典中。
+	workerNumber := uint64(mySched.downloadWorkers.workerNumber())
+	if length := mySched.pendingReqMap.Len(); length > workerNumber {
+		t.Fatalf("Too many requests in the pending request map: %d (worker number: %d)",
+			length, workerNumber)
+	}
+	if actual := mySched.work.number(workRequest); actual != uint64(number) {
+		t.Fatalf("Inconsistent outstanding request number: expected: %d, actual: %d",
+			number, actual)
+	}
+	if urls := checkpointURLs(t, sched); !sameStrings(urls, expectedURLs) {
+		t.Fatalf("Inconsistent pending request URLs: expected: %v, actual: %v",
+			expectedURLs, urls)
+	}
+	close(release)
+	waitForIdle(t, sched)
+	if actual := atomic.LoadUint32(&itemCount); actual != uint32(number) {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", number, actual)
+	}
+	if urls := checkpointURLs(t, sched); len(urls) != 0 {
+		t.Fatalf("Inconsistent pending request URLs: expected: %v, actual: %v",
+			[]string{}, urls)
+	}
+}
+
+func TestStopKeepsDiskReqPoolInCheckpoint(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	modules := newTestModules(t, srv.URL, nil)
+	dirPath, _ := ioutil.TempDir("", "scheduler_test")
+	defer os.RemoveAll(dirPath)
+	sched, _ := newSpillingTestScheduler(t, modules, dirPath)
+	resumeWithPendingReqs(t, sched, srv.URL, 10)
+	if err := sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping scheduler: %s", err)
+	}
+	// 停止时仍在磁盘请求缓冲池中的请求都应被保存在检查点中。
+	workerNumber := int(sched.(*myScheduler).downloadWorkers.workerNumber())
+	if urls := checkpointURLs(t, sched); len(urls) < 10-workerNumber {
+		t.Fatalf("Too few pending requests in the checkpoint: %d (expected at least %d)",
+			len(urls), 10-workerNumber)
+	}
+}
+
+func TestDroppedSpilledRequestsSettled(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	var itemCount uint32
+	modules := newTestModules(t, srv.URL, func(item module.Item) {
+		atomic.AddUint32(&itemCount, 1)
+	})
+	dirPath, _ := ioutil.TempDir("", "scheduler_test")
+	defer os.RemoveAll(dirPath)
+	sched, release := newSpillingTestScheduler(t, modules, dirPath)
+	defer sched.Stop()
+	resumeWithPendingReqs(t, sched, srv.URL, 10)
+	// 删除尚未被读取的段文件，使其中的请求都被丢弃。
+	paths, _ := filepath.Glob(filepath.Join(dirPath, "*", "*.seg"))
+	if len(paths) == 0 {
+		t.Fatal("No segment file!")
+	}
+	for _, path := range paths {
+		os.Remove(path)
+	}
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	if actual := atomic.LoadUint32(&itemCount); actual != 1 {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", 1, actual)
+	}
+}
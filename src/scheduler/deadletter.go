@@ -0,0 +1,300 @@
+package scheduler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errs"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"module"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeadLetterKind 代表死信的种类。
+type DeadLetterKind string
+
+const (
+	// DEAD_LETTER_KIND_REQUEST 代表下载失败的请求。
+	DEAD_LETTER_KIND_REQUEST DeadLetterKind = "request"
+	// DEAD_LETTER_KIND_RESPONSE 代表无法分析的响应。
+	DEAD_LETTER_KIND_RESPONSE DeadLetterKind = "response"
+	// DEAD_LETTER_KIND_ITEM 代表被快速失败的条目处理管道拒绝的条目。
+	DEAD_LETTER_KIND_ITEM DeadLetterKind = "item"
+)
+
+// DeadLetter 代表无法被处理的请求、响应或条目的记录。
+type DeadLetter struct {
+	Kind DeadLetterKind `json:"kind"`
+	// URL 代表请求的URL。对于响应，代表得到该响应的请求的URL。
+	URL    string      `json:"url,omitempty"`
+	Method string      `json:"method,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	// Body 代表请求体。只有可以被重新读取的请求体才会被记录。
+	Body    []byte `json:"body,omitempty"`
+	Depth   uint32 `json:"depth"`
+	Attempt uint32 `json:"attempt,omitempty"`
+	// ParentURL 代表发现该请求的页面的URL。
+	ParentURL  string `json:"parent_url,omitempty"`
+	AnchorText string `json:"anchor_text,omitempty"`
+	// Item 代表被拒绝的条目。无法被编码为JSON的值会被替换为其类型的名称。
+	Item      module.Item    `json:"item,omitempty"`
+	ErrorType errs.ErrorType `json:"error_type"`
+	Error     string         `json:"error"`
+	MID       module.MID     `json:"mid,omitempty"`
+	Time      time.Time      `json:"time"`
+}
+
+// HTTPReq 用于根据死信还原HTTP请求。
+// 只有请求和响应种类的死信才能被还原。
+func (dl *DeadLetter) HTTPReq() (*http.Request, error) {
+	if dl.URL == "" {
+		return nil, fmt.Errorf("no URL in the %s dead letter", dl.Kind)
+	}
+	method := dl.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if len(dl.Body) > 0 {
+		body = bytes.NewReader(dl.Body)
+	}
+	httpReq, err := http.NewRequest(method, dl.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range dl.Header {
+		httpReq.Header[key] = values
+	}
+	return httpReq, nil
+}
+
+// Request 用于根据死信还原请求，其中包括请求的深度、父页面的URL和锚文本。
+// 还原的请求的尝试次数会重新从0开始计算。
+func (dl *DeadLetter) Request() (*module.Request, error) {
+	httpReq, err := dl.HTTPReq()
+	if err != nil {
+		return nil, err
+	}
+	req := module.NewRequest(httpReq, dl.Depth)
+	req.SetParentURL(dl.ParentURL)
+	req.SetAnchorText(dl.AnchorText)
+	return req, nil
+}
+
+// newDeadLetter 用于生成死信。
+func newDeadLetter(kind DeadLetterKind, err error, mid module.MID) *DeadLetter {
+	crawlerError := toCrawlerError(err, mid)
+	return &DeadLetter{
+		Kind:      kind,
+		ErrorType: crawlerError.Type(),
+		Error:     err.Error(),
+		MID:       mid,
+		Time:      time.Now(),
+	}
+}
+
+// setHTTPReq 用于把给定的HTTP请求的信息记入死信。
+func (dl *DeadLetter) setHTTPReq(httpReq *http.Request) {
+	if httpReq == nil || httpReq.URL == nil {
+		return
+	}
+	dl.URL = httpReq.URL.String()
+	dl.Method = httpReq.Method
+	dl.Header = httpReq.Header
+	if httpReq.GetBody != nil {
+		if body, err := httpReq.GetBody(); err == nil {
+			dl.Body, _ = ioutil.ReadAll(body)
+			body.Close()
+		}
+	}
+}
+
+// setReqMeta 用于把给定的请求的元数据记入死信。
+func (dl *DeadLetter) setReqMeta(req *module.Request) {
+	dl.Attempt = req.Attempt()
+	dl.ParentURL = req.ParentURL()
+	dl.AnchorText = req.AnchorText()
+}
+
+// setItem 用于把给定的条目记入死信。
+func (dl *DeadLetter) setItem(item module.Item) {
+	dl.Item = module.Item{}
+	for key, value := range item {
+		if _, err := json.Marshal(value); err != nil {
+			value = fmt.Sprintf("%T", value)
+		}
+		dl.Item[key] = value
+	}
+}
+
+// ReadDeadLetters 会从给定的读取器读出所有死信。
+// 读取器中的内容应为每行一条死信的JSON。
+func ReadDeadLetters(r io.Reader) ([]*DeadLetter, error) {
+	var deadLetters []*DeadLetter
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		dl := &DeadLetter{}
+		if err := json.Unmarshal([]byte(line), dl); err != nil {
+			return deadLetters, fmt.Errorf("couldn't decode dead letter at line %d: %s",
+				lineNumber, err)
+		}
+		deadLetters = append(deadLetters, dl)
+	}
+	return deadLetters, scanner.Err()
+}
+
+// deadLetterStore 代表把死信追加到本地JSONL文件中的存储。
+type deadLetterStore struct {
+	path    string
+	file    *os.File
+	encoder *json.Encoder
+	// total 代表已存储的死信的数量。
+	total uint64
+	lock  sync.Mutex
+}
+
+// newDeadLetterStore 会创建一个死信存储。
+// 若给定路径的文件已存在，新的死信会被追加到其末尾。
+func newDeadLetterStore(path string) (*deadLetterStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &deadLetterStore{
+		path:    path,
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// put 用于存储给定的死信。
+func (store *deadLetterStore) put(dl *DeadLetter) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.file == nil {
+		return fmt.Errorf("the dead letter store has been closed")
+	}
+	if err := store.encoder.Encode(dl); err != nil {
+		return err
+	}
+	atomic.AddUint64(&store.total, 1)
+	return nil
+}
+
+// close 用于关闭死信存储。
+func (store *deadLetterStore) close() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.file == nil {
+		return nil
+	}
+	err := store.file.Close()
+	store.file = nil
+	store.encoder = nil
+	return err
+}
+
+// number 用于获取已存储的死信的数量。
+func (store *deadLetterStore) number() uint64 {
+	if store == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&store.total)
+}
+
+// initDeadLetterStore 用于按照给定的路径初始化死信存储。
+// 如果已有死信存储，就先关闭它。
+func (sched *myScheduler) initDeadLetterStore(path string) error {
+	if sched.deadLetters != nil {
+		sched.deadLetters.close()
+		sched.deadLetters = nil
+	}
+	if path == "" {
+		return nil
+	}
+	store, err := newDeadLetterStore(path)
+	if err != nil {
+		return genErrorByError(err)
+	}
+	sched.deadLetters = store
+	log.Printf("-- Dead letter store: %s\n", path)
+	return nil
+}
+
+// sendDeadLetter 用于存储给定的死信。未配置死信存储时什么也不做。
+func (sched *myScheduler) sendDeadLetter(dl *DeadLetter) {
+	if sched.deadLetters == nil {
+		return
+	}
+	if err := sched.deadLetters.put(dl); err != nil {
+		log.Printf("Couldn't store the dead letter: %s (kind: %s, URL: %s)\n",
+			err, dl.Kind, dl.URL)
+	}
+}
+
+// sendReqDeadLetter 用于存储下载失败的请求。
+func (sched *myScheduler) sendReqDeadLetter(
+	req *module.Request, err error, mid module.MID) {
+	dl := newDeadLetter(DEAD_LETTER_KIND_REQUEST, err, mid)
+	dl.setHTTPReq(req.HTTPReq())
+	dl.Depth = req.Depth()
+	dl.setReqMeta(req)
+	sched.sendDeadLetter(dl)
+}
+
+// sendRespDeadLetter 用于存储无法分析的响应。
+// 响应体不会被存储，只记录得到该响应的请求。
+func (sched *myScheduler) sendRespDeadLetter(
+	resp *module.Response, errList []error, mid module.MID) {
+	if errList = nonNilErrors(errList); len(errList) == 0 {
+		return
+	}
+	dl := newDeadLetter(DEAD_LETTER_KIND_RESPONSE, errList[0], mid)
+	dl.Error = joinErrors(errList)
+	if httpResp := resp.HTTPResp(); httpResp != nil {
+		dl.setHTTPReq(httpResp.Request)
+	}
+	dl.Depth = resp.Depth()
+	if req := resp.Request(); req != nil {
+		dl.setReqMeta(req)
+	}
+	sched.sendDeadLetter(dl)
+}
+
+// sendItemDeadLetter 用于存储被条目处理管道拒绝的条目。
+func (sched *myScheduler) sendItemDeadLetter(
+	item module.Item, errList []error, mid module.MID) {
+	if errList = nonNilErrors(errList); len(errList) == 0 {
+		return
+	}
+	dl := newDeadLetter(DEAD_LETTER_KIND_ITEM, errList[0], mid)
+	dl.Error = joinErrors(errList)
+	dl.setItem(item)
+	sched.sendDeadLetter(dl)
+}
+
+// joinErrors 用于把多个错误值的信息合并为一个字符串。
+func joinErrors(errList []error) string {
+	msgs := make([]string, 0, len(errList))
+	for _, err := range errList {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
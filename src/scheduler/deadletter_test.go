@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"module"
+	"net/http"
+	"testing"
+)
+
+func TestDeadLetterRequest(t *testing.T) {
+	httpReq, _ := http.NewRequest(http.MethodPost, "http://example.com/form",
+		bytes.NewReader([]byte("a=1")))
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req := module.NewRequest(httpReq, 2)
+	req.SetAttempt(3)
+	req.SetParentURL("http://example.com/")
+	req.SetAnchorText("form")
+	dl := newDeadLetter(DEAD_LETTER_KIND_REQUEST, errors.New("timeout"), "D1")
+	dl.setHTTPReq(req.HTTPReq())
+	dl.Depth = req.Depth()
+	dl.setReqMeta(req)
+	// 死信会以JSON的形式被存储。
+	data, err := json.Marshal(dl)
+	if err != nil {
+		t.Fatalf("An error occurs when encoding dead letter: %s", err)
+	}
+	var decoded DeadLetter
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("An error occurs when decoding dead letter: %s", err)
+	}
+	restored, err := decoded.Request()
+	if err != nil {
+		t.Fatalf("An error occurs when restoring request: %s", err)
+	}
+	restoredHTTPReq := restored.HTTPReq()
+	if restoredHTTPReq.Method != http.MethodPost ||
+		restoredHTTPReq.URL.String() != "http://example.com/form" ||
+		restoredHTTPReq.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Fatalf("Inconsistent HTTP request: %s %s %v",
+			restoredHTTPReq.Method, restoredHTTPReq.URL, restoredHTTPReq.Header)
+	}
+	body, _ := ioutil.ReadAll(restoredHTTPReq.Body)
+	if string(body) != "a=1" {
+		t.Fatalf("Inconsistent body: expected: %q, actual: %q", "a=1", body)
+	}
+	if restored.Depth() != 2 || restored.Attempt() != 0 ||
+		restored.ParentURL() != "http://example.com/" || restored.AnchorText() != "form" {
+		t.Fatalf("Inconsistent request metadata: depth: %d, attempt: %d, parent: %s, anchor: %s",
+			restored.Depth(), restored.Attempt(), restored.ParentURL(), restored.AnchorText())
+	}
+	// 原请求的请求体不会因被记入死信而被读出。
+	body, _ = ioutil.ReadAll(httpReq.Body)
+	if string(body) != "a=1" {
+		t.Fatalf("The original body has been consumed: %q", body)
+	}
+	if _, err := (&DeadLetter{Kind: DEAD_LETTER_KIND_ITEM}).Request(); err == nil {
+		t.Fatal("No error when restoring request from an item dead letter!")
+	}
+}
+
+func TestDeadLetterNilErrors(t *testing.T) {
+	sched := &myScheduler{}
+	httpReq, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	resp := module.NewResponse(&http.Response{Request: httpReq}, 0)
+	// 组件返回的错误值列表中的nil不能导致恐慌。
+	sched.sendRespDeadLetter(resp, []error{nil}, "A1")
+	sched.sendItemDeadLetter(module.Item{}, []error{nil, errors.New("rejected")}, "P1")
+	errList := nonNilErrors([]error{nil, errors.New("a"), nil, errors.New("b")})
+	if len(errList) != 2 || joinErrors(errList) != "a; b" {
+		t.Fatalf("Inconsistent errors: %v", errList)
+	}
+	if errList := nonNilErrors([]error{nil}); len(errList) != 0 {
+		t.Fatalf("Inconsistent errors: %v", errList)
+	}
+}
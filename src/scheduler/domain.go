@@ -1,60 +1,67 @@
 package scheduler
 
 import (
-	"regexp"
+	"fmt"
+	"net"
+	"net/http"
 	"strings"
+	"toolkit/publicsuffix"
 )
 
-var regexpForIP = regexp.MustCompile(`((?:(?:25[0-5]|2[0-4]\d|[01]?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|[01]?\d?\d))`)
-
-var regexpForDomains = []*regexp.Regexp{
-	// *.xx or *.xxx.xx
-	regexp.MustCompile(`\.(com|com\.\w{2})$`),
-	regexp.MustCompile(`\.(gov|gov\.\w{2})$`),
-	regexp.MustCompile(`\.(net|net\.\w{2})$`),
-	regexp.MustCompile(`\.(org|org\.\w{2})$`),
-	// *.xx
-	regexp.MustCompile(`\.me$`),
-	regexp.MustCompile(`\.biz$`),
-	regexp.MustCompile(`\.info$`),
-	regexp.MustCompile(`\.name$`),
-	regexp.MustCompile(`\.mobi$`),
-	regexp.MustCompile(`\.so$`),
-	regexp.MustCompile(`\.asia$`),
-	regexp.MustCompile(`\.tel$`),
-	regexp.MustCompile(`\.tv$`),
-	regexp.MustCompile(`\.cc$`),
-	regexp.MustCompile(`\.co$`),
-	regexp.MustCompile(`\.\w{2}$`),
-}
-
 // getPrimaryDomain 用于获取给定主机名的主域名。
+// 主机名可以带有端口号，也可以是IPv4或IPv6地址（IPv6地址可以带有方括号）。
+// 对于IP地址，主域名就是其规范形式。
+// 对于域名，主域名是按照公共后缀列表得到的可注册域名，
+// 并且是小写的ASCII形式，其中的国际化标签会被转换为punycode编码。
 func getPrimaryDomain(host string) (string, error) {
-	host = strings.TrimSpace(host)
-	if host == "" {
+	hostname := splitHostname(host)
+	if hostname == "" {
 		return "", genError("empty host")
 	}
-	if regexpForIP.MatchString(host) {
-		return host, nil
-	}
-	var suffixIndex int
-	for _, re := range regexpForDomains {
-		pos := re.FindStringIndex(host)
-		if pos != nil {
-			suffixIndex = pos[0]
-			break
-		}
-	}
-	if suffixIndex > 0 {
-		var pdIndex int
-		firstPart := host[:suffixIndex]
-		index := strings.LastIndex(firstPart, ".")
-		if index < 0 {
-			pdIndex = 0
-		} else {
-			pdIndex = index + 1
-		}
-		return host[pdIndex:], nil
-	}
-	return "", genError("unrecognized host")
+	if ip := net.ParseIP(hostname); ip != nil {
+		return ip.String(), nil
+	}
+	pd, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		return "", genError(fmt.Sprintf("unrecognized host %q: %s", host, err))
+	}
+	return pd, nil
+}
+
+// splitHostname 用于去掉给定主机名中的端口号以及IPv6地址的方括号。
+func splitHostname(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimPrefix(strings.TrimSuffix(host, "]"), "[")
+	// 去掉IPv6地址中的区域标识。
+	if i := strings.LastIndex(host, "%"); i >= 0 && strings.Contains(host, ":") {
+		host = host[:i]
+	}
+	return host
+}
+
+// normalizeDomain 用于把给定的主域名转换为与getPrimaryDomain的结果一致的形式。
+func normalizeDomain(domain string) string {
+	hostname := splitHostname(domain)
+	if ip := net.ParseIP(hostname); ip != nil {
+		return ip.String()
+	}
+	if ascii, err := publicsuffix.ToASCII(hostname); err == nil {
+		return ascii
+	}
+	return hostname
+}
+
+// getReqHost 用于获取给定HTTP请求的主机名。
+// 请求的Host字段为空时使用其URL中的主机名。
+func getReqHost(httpReq *http.Request) string {
+	if httpReq.Host != "" {
+		return httpReq.Host
+	}
+	if httpReq.URL != nil {
+		return httpReq.URL.Host
+	}
+	return ""
 }
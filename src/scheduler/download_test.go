@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"module"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ctxBody 代表在给定的上下文结束之后就无法再读取的响应体。
+type ctxBody struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+func (body *ctxBody) Read(p []byte) (int, error) {
+	if err := body.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return body.ReadCloser.Read(p)
+}
+
+// ctxDownloader 代表其响应体只能在下载的上下文结束之前被读取的下载器。
+type ctxDownloader struct {
+	module.Downloader
+}
+
+func (downloader *ctxDownloader) Download(
+	ctx context.Context, req *module.Request) (*module.Response, error) {
+	resp, err := downloader.Downloader.Download(ctx, req)
+	if resp != nil && resp.HTTPResp() != nil && resp.HTTPResp().Body != nil {
+		httpResp := resp.HTTPResp()
+		httpResp.Body = &ctxBody{ReadCloser: httpResp.Body, ctx: ctx}
+	}
+	return resp, err
+}
+
+func TestDownloadTimeoutExcludesAnalysisBacklog(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	var itemNumber uint32
+	modules := newTestModules(t, srv.URL, func(item module.Item) {
+		atomic.AddUint32(&itemNumber, 1)
+	})
+	// 下载的上下文结束之后，响应在等待分析期间就无法再读取响应体了。
+	modules.downloader = &ctxDownloader{Downloader: modules.downloader}
+	var maxDepth uint32 = 2
+	sched := newTestScheduler(t, RequestArgs{
+		MaxDepth:        maxDepth,
+		DownloadTimeout: time.Minute,
+	}, modules)
+	defer sched.Stop()
+	var errorNumber uint32
+	go func() {
+		for err := range sched.ErrorChan() {
+			t.Logf("An error occurs when crawling: %s", err)
+			atomic.AddUint32(&errorNumber, 1)
+		}
+	}()
+	httpReq, _ := http.NewRequest(http.MethodGet, srv.URL+"/p0", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	if number := atomic.LoadUint32(&errorNumber); number > 0 {
+		t.Fatalf("There are %d error(s) when crawling!", number)
+	}
+	expected := uint32(testPageNumber(maxDepth))
+	if actual := atomic.LoadUint32(&itemNumber); actual != expected {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", expected, actual)
+	}
+}
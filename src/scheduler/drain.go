@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// drainCheckInterval 代表优雅停止时检查各阶段是否已排空的间隔时间。
+const drainCheckInterval = 10 * time.Millisecond
+
+// StopReport 代表优雅停止调度器的报告。
+type StopReport struct {
+	// Drained 代表各阶段是否已在期限内排空。
+	Drained bool `json:"drained"`
+	// Elapsed 代表排空所用的时间。
+	Elapsed time.Duration `json:"elapsed"`
+	// RejectedRequests 代表停止期间被发现但未被放入请求缓冲池的新请求的数量。
+	// 它们的URL也会被列入DiscardedRequestURLs。
+	RejectedRequests uint64 `json:"rejected_requests"`
+	// DiscardedRequestURLs 代表尚未下载的请求的URL的列表，
+	// 其中包括停止期间被发现的新请求。
+	// 这些请求仍会被保存在检查点中。
+	DiscardedRequestURLs []string `json:"discarded_request_urls,omitempty"`
+	// DiscardedResponses 代表被丢弃的尚未解析的响应的数量。
+	DiscardedResponses uint64 `json:"discarded_responses"`
+	// DiscardedItems 代表被丢弃的尚未处理的条目的数量。
+	DiscardedItems uint64 `json:"discarded_items"`
+	// InterruptedWorkers 代表停止时仍在处理数据的工作协程的数量。
+	InterruptedWorkers uint32 `json:"interrupted_workers"`
+}
+
+// StopGracefully 会优雅地停止调度器。
+// 调度器会先停止接受和下载新的请求，
+// 然后等待已下载的响应和已生成的条目流经分析器和条目处理管道，
+// 直到各阶段排空或者参数ctx被取消，最后再停止调度器。
+// 结果值中的报告会列出所有被丢弃的数据。
+func (sched *myScheduler) StopGracefully(ctx context.Context) (report StopReport, err error) {
+	log.Println("Stop scheduler gracefully...")
+	// 检查状态。
+	log.Println("Check status for stop...")
+	if ctx == nil {
+		err = genParameterError("nil context")
+		return
+	}
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_STOPPING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_STOPPED
+		}
+		sched.statusLock.Unlock()
+	}()
+	if err != nil {
+		return
+	}
+	atomic.StoreUint32(&sched.draining, 1)
+	// 已暂停的调度器也需要排空。
+	sched.pauseGate.open()
+	startTime := time.Now()
+	report.Drained = sched.waitForDrain(ctx)
+	report.Elapsed = time.Since(startTime)
+	report.InterruptedWorkers = sched.downloadWorkers.busyNumber() +
+		sched.analyzeWorkers.busyNumber() +
+		sched.pickWorkers.busyNumber()
+	report.DiscardedResponses = sched.work.number(workResponse)
+	report.DiscardedItems = sched.work.number(workItem)
+	sched.shutdown()
+	report.RejectedRequests = atomic.LoadUint64(&sched.drainRejected)
+	report.DiscardedRequestURLs = sched.pendingURLs()
+	log.Printf("Scheduler has been stopped. (drained: %v, elapsed: %s, "+
+		"discarded requests: %d, responses: %d, items: %d)\n",
+		report.Drained, report.Elapsed, len(report.DiscardedRequestURLs),
+		report.DiscardedResponses, report.DiscardedItems)
+	return report, nil
+}
+
+// isDraining 用于判断调度器是否正在排空。
+func (sched *myScheduler) isDraining() bool {
+	return atomic.LoadUint32(&sched.draining) == 1
+}
+
+// waitForDrain 用于等待响应和条目的处理阶段排空。
+// 结果值为false代表在排空之前参数ctx已被取消。
+func (sched *myScheduler) waitForDrain(ctx context.Context) bool {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	// 连续两次检查都已排空才算排空，
+	// 以免漏掉刚从请求缓冲池中取出的请求。
+	var drainedCount int
+	for {
+		if sched.drained() {
+			drainedCount++
+			if drainedCount >= 2 {
+				return true
+			}
+		} else {
+			drainedCount = 0
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// drained 用于判断响应和条目的处理阶段是否已排空。
+// 尚未下载的请求不在考虑之列。
+func (sched *myScheduler) drained() bool {
+	if sched.downloadWorkers.busyNumber() > 0 ||
+		sched.analyzeWorkers.busyNumber() > 0 ||
+		sched.pickWorkers.busyNumber() > 0 {
+		return false
+	}
+	return sched.work.number(workResponse) == 0 &&
+		sched.work.number(workItem) == 0
+}
+
+// pendingURLs 用于获取所有待处理的请求的URL。
+func (sched *myScheduler) pendingURLs() []string {
+	var urls []string
+	sched.pendingReqMap.Range(func(key string, element interface{}) bool {
+		urls = append(urls, key)
+		return true
+	})
+	sort.Strings(urls)
+	return urls
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"module"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// gateAnalyzer 代表会在分析之前等待放行的分析器。
+type gateAnalyzer struct {
+	module.Analyzer
+	// entered 会在开始分析时被写入。
+	entered chan struct{}
+	// release 被关闭之后才会继续分析。
+	release chan struct{}
+}
+
+func (analyzer *gateAnalyzer) Analyze(
+	ctx context.Context, resp *module.Response) ([]module.Data, []error) {
+	analyzer.entered <- struct{}{}
+	<-analyzer.release
+	return analyzer.Analyzer.Analyze(ctx, resp)
+}
+
+func TestStopGracefullyKeepsDiscoveredRequests(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	modules := newTestModules(t, srv.URL, nil)
+	analyzer := &gateAnalyzer{
+		Analyzer: modules.analyzer,
+		entered:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	modules.analyzer = analyzer
+	sched := newTestScheduler(t, RequestArgs{MaxDepth: 1}, modules)
+	defer sched.Stop()
+	httpReq, _ := http.NewRequest(http.MethodGet, srv.URL+"/p0", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	select {
+	case <-analyzer.entered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("The response has not been analyzed!")
+	}
+	type result struct {
+		report StopReport
+		err    error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		report, err := sched.StopGracefully(ctx)
+		resultChan <- result{report, err}
+	}()
+	// 在排空期间才放行分析器，它发现的请求都是停止期间的新请求。
+	for !sched.(*myScheduler).isDraining() {
+		time.Sleep(time.Millisecond)
+	}
+	close(analyzer.release)
+	r := <-resultChan
+	if r.err != nil {
+		t.Fatalf("An error occurs when stopping scheduler gracefully: %s", r.err)
+	}
+	if !r.report.Drained {
+		t.Fatal("The scheduler was not drained!")
+	}
+	if r.report.RejectedRequests != 3 {
+		t.Fatalf("Inconsistent rejected request number: expected: %d, actual: %d",
+			3, r.report.RejectedRequests)
+	}
+	expectedURLs := []string{srv.URL + "/p1", srv.URL + "/p2", srv.URL + "/p3"}
+	if !sameStrings(r.report.DiscardedRequestURLs, expectedURLs) {
+		t.Fatalf("Inconsistent discarded request URLs: expected: %v, actual: %v",
+			expectedURLs, r.report.DiscardedRequestURLs)
+	}
+	var buffer bytes.Buffer
+	if err := sched.Checkpoint(&buffer); err != nil {
+		t.Fatalf("An error occurs when generating checkpoint: %s", err)
+	}
+	cp, err := readCheckpoint(&buffer)
+	if err != nil {
+		t.Fatalf("An error occurs when reading checkpoint: %s", err)
+	}
+	var pendingURLs []string
+	for _, rs := range cp.PendingRequests {
+		pendingURLs = append(pendingURLs, rs.URL)
+	}
+	if !sameStrings(pendingURLs, expectedURLs) {
+		t.Fatalf("Inconsistent pending request URLs: expected: %v, actual: %v",
+			expectedURLs, pendingURLs)
+	}
+}
@@ -25,32 +25,24 @@ func genParameterError(errMsg string) error {
 		errs.NewIllegalParameterError(errMsg))
 }
 
+// nonNilErrors 用于去掉给定错误值列表中的nil。
+// 组件返回的错误值列表中可能含有nil，它们不代表任何错误。
+func nonNilErrors(errList []error) []error {
+	var result []error
+	for _, err := range errList {
+		if err != nil {
+			result = append(result, err)
+		}
+	}
+	return result
+}
+
 // sendError 用于向错误缓冲池发送错误值。
 func sendError(err error, mid module.MID, errorBufferPool buffer.Pool) bool {
 	if err == nil || errorBufferPool == nil || errorBufferPool.Closed() {
 		return false
 	}
-	var crawlerError errs.CrawlerError
-	var ok bool
-	crawlerError, ok = err.(errs.CrawlerError)
-	if !ok {
-		var moduleType module.Type
-		var errorType errs.ErrorType
-		ok, moduleType = module.GetType(mid)
-		if !ok {
-			errorType = errs.ERROR_TYPE_SCHEDULER
-		} else {
-			switch moduleType {
-			case module.TYPE_DOWNLOADER:
-				errorType = errs.ERROR_TYPE_DOWNLOADER
-			case module.TYPE_ANALYZER:
-				errorType = errs.ERROR_TYPE_ANALYZER
-			case module.TYPE_PIPELINE:
-				errorType = errs.ERROR_TYPE_PIPELINE
-			}
-		}
-		crawlerError = errs.NewCrawlerError(errorType, err.Error())
-	}
+	crawlerError := toCrawlerError(err, mid)
 	if errorBufferPool.Closed() {
 		return false
 	}
@@ -61,3 +53,26 @@ func sendError(err error, mid module.MID, errorBufferPool buffer.Pool) bool {
 	}(crawlerError)
 	return true
 }
+
+// toCrawlerError 用于把给定的错误值转换为爬虫错误值。
+// 错误类型由给定组件ID所代表的组件类型决定。
+func toCrawlerError(err error, mid module.MID) errs.CrawlerError {
+	if crawlerError, ok := err.(errs.CrawlerError); ok {
+		return crawlerError
+	}
+	var errorType errs.ErrorType
+	ok, moduleType := module.GetType(mid)
+	if !ok {
+		errorType = errs.ERROR_TYPE_SCHEDULER
+	} else {
+		switch moduleType {
+		case module.TYPE_DOWNLOADER:
+			errorType = errs.ERROR_TYPE_DOWNLOADER
+		case module.TYPE_ANALYZER:
+			errorType = errs.ERROR_TYPE_ANALYZER
+		case module.TYPE_PIPELINE:
+			errorType = errs.ERROR_TYPE_PIPELINE
+		}
+	}
+	return errs.NewCrawlerError(errorType, err.Error())
+}
@@ -0,0 +1,246 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errs"
+	"fmt"
+	"module"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"toolkit/buffer"
+)
+
+// PriorityFunc 代表请求优先级计算函数的类型。
+// 结果值越大，请求越优先被取出。
+// 结果值相同的请求按照放入的先后顺序取出。
+// 任何用户自定义的评分函数都可以作为最佳优先策略使用。
+type PriorityFunc func(req *module.Request) float64
+
+// PriorityBreadthFirst 代表广度优先的优先级计算函数。
+// 深度越小的请求越优先。
+func PriorityBreadthFirst(req *module.Request) float64 {
+	return -float64(req.Depth())
+}
+
+// PriorityByRequest 代表直接使用请求自身优先级的计算函数。
+func PriorityByRequest(req *module.Request) float64 {
+	return float64(req.Priority())
+}
+
+// PriorityFrontier 代表按优先级取出请求的请求前沿的接口类型。
+// 它实现了buffer.Pool接口，因此可以代替请求缓冲池。
+// 与buffer.Pool的默认实现不同，它的Get方法会在没有请求时阻塞，
+// 而Put方法会在已满时阻塞，直到前沿被关闭。
+type PriorityFrontier interface {
+	buffer.Pool
+	// HostNumber 会返回当前有待处理请求的主机的数量。
+	HostNumber() uint32
+}
+
+// frontierItem 代表请求前沿中的元素。
+type frontierItem struct {
+	req      *module.Request
+	priority float64
+	// seq 代表放入的顺序号。
+	seq uint64
+}
+
+// frontierQueue 代表基于堆的请求优先级队列。
+type frontierQueue []*frontierItem
+
+func (q frontierQueue) Len() int {
+	return len(q)
+}
+
+func (q frontierQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q frontierQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *frontierQueue) Push(x interface{}) {
+	*q = append(*q, x.(*frontierItem))
+}
+
+func (q *frontierQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+// myPriorityFrontier 代表请求前沿的实现类型。
+type myPriorityFrontier struct {
+	// bufferCap 和 maxBufferNumber 的乘积代表前沿的容量。
+	bufferCap       uint32
+	maxBufferNumber uint32
+	priority        PriorityFunc
+	// hostFairness 代表是否在各主机之间轮流取出请求。
+	hostFairness bool
+	// queues 代表主机与其优先级队列的字典。
+	// 不要求公平性时，所有请求都放在键为空字符串的队列中。
+	queues map[string]*frontierQueue
+	// hosts 代表有待处理请求的主机的轮转列表。
+	hosts []string
+	// next 代表下一次取出请求时轮到的主机在hosts中的索引。
+	next     int
+	total    uint64
+	seq      uint64
+	closed   uint32
+	lock     sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+}
+
+// NewPriorityFrontier 会创建一个请求前沿。
+// 参数bufferCap和maxBufferNumber的含义与buffer.NewPool的相同，
+// 二者的乘积代表前沿的容量。
+// 参数priority代表优先级计算函数，为nil时使用PriorityByRequest。
+// 参数hostFairness代表是否在各主机之间轮流取出请求。
+func NewPriorityFrontier(
+	bufferCap uint32,
+	maxBufferNumber uint32,
+	priority PriorityFunc,
+	hostFairness bool) (PriorityFrontier, error) {
+	if bufferCap == 0 {
+		errMsg := fmt.Sprintf("illegal buffer cap for priority frontier: %d", bufferCap)
+		return nil, errs.NewIllegalParameterError(errMsg)
+	}
+	if maxBufferNumber == 0 {
+		errMsg := fmt.Sprintf("illegal max buffer number for priority frontier: %d", maxBufferNumber)
+		return nil, errs.NewIllegalParameterError(errMsg)
+	}
+	if priority == nil {
+		priority = PriorityByRequest
+	}
+	frontier := &myPriorityFrontier{
+		bufferCap:       bufferCap,
+		maxBufferNumber: maxBufferNumber,
+		priority:        priority,
+		hostFairness:    hostFairness,
+		queues:          map[string]*frontierQueue{},
+	}
+	frontier.notEmpty = sync.NewCond(&frontier.lock)
+	frontier.notFull = sync.NewCond(&frontier.lock)
+	return frontier, nil
+}
+
+func (frontier *myPriorityFrontier) BufferCap() uint32 {
+	return frontier.bufferCap
+}
+
+func (frontier *myPriorityFrontier) MaxBufferNumber() uint32 {
+	return frontier.maxBufferNumber
+}
+
+// BufferNumber 会返回内部优先级队列的数量。
+func (frontier *myPriorityFrontier) BufferNumber() uint32 {
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	return uint32(len(frontier.queues))
+}
+
+func (frontier *myPriorityFrontier) HostNumber() uint32 {
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	return uint32(len(frontier.hosts))
+}
+
+func (frontier *myPriorityFrontier) Total() uint64 {
+	return atomic.LoadUint64(&frontier.total)
+}
+
+func (frontier *myPriorityFrontier) capacity() uint64 {
+	return uint64(frontier.bufferCap) * uint64(frontier.maxBufferNumber)
+}
+
+// hostKey 用于获取给定请求所属的队列的键。
+func (frontier *myPriorityFrontier) hostKey(req *module.Request) string {
+	if !frontier.hostFairness || !req.Valid() {
+		return ""
+	}
+	return strings.ToLower(req.HTTPReq().URL.Host)
+}
+
+func (frontier *myPriorityFrontier) Put(datum interface{}) error {
+	req, ok := datum.(*module.Request)
+	if !ok || req == nil {
+		errMsg := fmt.Sprintf("incorrect request type for priority frontier: %T", datum)
+		return errs.NewIllegalParameterError(errMsg)
+	}
+	item := &frontierItem{
+		req:      req,
+		priority: frontier.priority(req),
+	}
+	key := frontier.hostKey(req)
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	for !frontier.Closed() && atomic.LoadUint64(&frontier.total) >= frontier.capacity() {
+		frontier.notFull.Wait()
+	}
+	if frontier.Closed() {
+		return buffer.ErrClosedBufferPool
+	}
+	frontier.seq++
+	item.seq = frontier.seq
+	queue := frontier.queues[key]
+	if queue == nil {
+		queue = &frontierQueue{}
+		frontier.queues[key] = queue
+		frontier.hosts = append(frontier.hosts, key)
+	}
+	heap.Push(queue, item)
+	atomic.AddUint64(&frontier.total, 1)
+	frontier.notEmpty.Signal()
+	return nil
+}
+
+func (frontier *myPriorityFrontier) Get() (datum interface{}, err error) {
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	for !frontier.Closed() && len(frontier.hosts) == 0 {
+		frontier.notEmpty.Wait()
+	}
+	if frontier.Closed() {
+		return nil, buffer.ErrClosedBufferPool
+	}
+	if frontier.next >= len(frontier.hosts) {
+		frontier.next = 0
+	}
+	key := frontier.hosts[frontier.next]
+	queue := frontier.queues[key]
+	item := heap.Pop(queue).(*frontierItem)
+	if queue.Len() == 0 {
+		// 移除已没有请求的主机，轮转索引自然指向下一个主机。
+		delete(frontier.queues, key)
+		frontier.hosts = append(frontier.hosts[:frontier.next], frontier.hosts[frontier.next+1:]...)
+	} else {
+		frontier.next++
+	}
+	atomic.AddUint64(&frontier.total, ^uint64(0))
+	frontier.notFull.Signal()
+	return item.req, nil
+}
+
+func (frontier *myPriorityFrontier) Close() bool {
+	if !atomic.CompareAndSwapUint32(&frontier.closed, 0, 1) {
+		return false
+	}
+	frontier.lock.Lock()
+	frontier.notEmpty.Broadcast()
+	frontier.notFull.Broadcast()
+	frontier.lock.Unlock()
+	return true
+}
+
+func (frontier *myPriorityFrontier) Closed() bool {
+	return atomic.LoadUint32(&frontier.closed) == 1
+}
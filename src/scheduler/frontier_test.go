@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"module"
+	"testing"
+	"time"
+	"toolkit/buffer"
+)
+
+// frontierReq 代表放入请求前沿的测试请求的描述。
+type frontierReq struct {
+	url      string
+	priority int32
+	depth    uint32
+}
+
+// newFrontierReqs 会根据给定的描述创建请求。
+func newFrontierReqs(t *testing.T, descs []frontierReq) []*module.Request {
+	reqs := make([]*module.Request, len(descs))
+	for i, desc := range descs {
+		reqs[i] = newTestReq(t, desc.url, desc.depth)
+		reqs[i].SetPriority(desc.priority)
+	}
+	return reqs
+}
+
+// drainFrontier 用于放入给定的请求，然后取出所有请求并返回它们的URL。
+func drainFrontier(
+	t *testing.T, frontier PriorityFrontier, reqs []*module.Request) []string {
+	for _, req := range reqs {
+		if err := frontier.Put(req); err != nil {
+			t.Fatalf("An error occurs when putting request: %s", err)
+		}
+	}
+	urls := make([]string, 0, len(reqs))
+	for frontier.Total() > 0 {
+		datum, err := frontier.Get()
+		if err != nil {
+			t.Fatalf("An error occurs when getting request: %s", err)
+		}
+		urls = append(urls, datum.(*module.Request).HTTPReq().URL.String())
+	}
+	return urls
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPriorityFrontierOrder(t *testing.T) {
+	cases := []struct {
+		name     string
+		priority PriorityFunc
+		reqs     []frontierReq
+		expected []string
+	}{
+		{
+			name:     "by request",
+			priority: nil,
+			reqs: []frontierReq{
+				{url: "http://a/1", priority: 1},
+				{url: "http://a/2", priority: 3},
+				{url: "http://a/3", priority: 2},
+				{url: "http://a/4", priority: 3},
+				{url: "http://a/5", priority: -1},
+			},
+			expected: []string{"http://a/2", "http://a/4", "http://a/3", "http://a/1", "http://a/5"},
+		},
+		{
+			// 优先级相同的请求按照放入的先后顺序取出。
+			name:     "fifo",
+			priority: PriorityByRequest,
+			reqs: []frontierReq{
+				{url: "http://a/1"},
+				{url: "http://b/2"},
+				{url: "http://a/3"},
+				{url: "http://c/4"},
+			},
+			expected: []string{"http://a/1", "http://b/2", "http://a/3", "http://c/4"},
+		},
+		{
+			name:     "breadth first",
+			priority: PriorityBreadthFirst,
+			reqs: []frontierReq{
+				{url: "http://a/1", depth: 2, priority: 9},
+				{url: "http://a/2", depth: 0},
+				{url: "http://a/3", depth: 1},
+				{url: "http://a/4", depth: 0},
+			},
+			expected: []string{"http://a/2", "http://a/4", "http://a/3", "http://a/1"},
+		},
+	}
+	for _, c := range cases {
+		frontier, err := NewPriorityFrontier(10, 1, c.priority, false)
+		if err != nil {
+			t.Fatalf("An error occurs when creating priority frontier: %s", err)
+		}
+		actual := drainFrontier(t, frontier, newFrontierReqs(t, c.reqs))
+		if !sameStrings(actual, c.expected) {
+			t.Fatalf("Inconsistent order for the %s case: expected: %v, actual: %v",
+				c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestPriorityFrontierHostFairness(t *testing.T) {
+	reqs := newFrontierReqs(t, []frontierReq{
+		{url: "http://a/1"},
+		{url: "http://a/2", priority: 1},
+		{url: "http://a/3"},
+		{url: "http://b/1"},
+		{url: "http://c/1"},
+		{url: "http://C/2"},
+	})
+	frontier, err := NewPriorityFrontier(10, 1, nil, true)
+	if err != nil {
+		t.Fatalf("An error occurs when creating priority frontier: %s", err)
+	}
+	for _, req := range reqs {
+		if err := frontier.Put(req); err != nil {
+			t.Fatalf("An error occurs when putting request: %s", err)
+		}
+	}
+	if number := frontier.HostNumber(); number != 3 {
+		t.Fatalf("Inconsistent host number: expected: %d, actual: %d", 3, number)
+	}
+	// 各主机轮流取出请求，同一主机的请求仍然按照优先级取出。
+	expected := []string{
+		"http://a/2", "http://b/1", "http://c/1", "http://a/1", "http://C/2", "http://a/3"}
+	actual := drainFrontier(t, frontier, nil)
+	if !sameStrings(actual, expected) {
+		t.Fatalf("Inconsistent order: expected: %v, actual: %v", expected, actual)
+	}
+	if number := frontier.HostNumber(); number != 0 {
+		t.Fatalf("Inconsistent host number: expected: %d, actual: %d", 0, number)
+	}
+}
+
+func TestPriorityFrontierBlocking(t *testing.T) {
+	if _, err := NewPriorityFrontier(0, 1, nil, false); err == nil {
+		t.Fatal("No error when creating priority frontier with zero buffer cap!")
+	}
+	if _, err := NewPriorityFrontier(1, 0, nil, false); err == nil {
+		t.Fatal("No error when creating priority frontier with zero max buffer number!")
+	}
+	frontier, err := NewPriorityFrontier(1, 1, nil, false)
+	if err != nil {
+		t.Fatalf("An error occurs when creating priority frontier: %s", err)
+	}
+	if err := frontier.Put("not a request"); err == nil {
+		t.Fatal("No error when putting a datum of incorrect type!")
+	}
+	reqs := newFrontierReqs(t, []frontierReq{{url: "http://a/1"}, {url: "http://a/2"}})
+	frontier.Put(reqs[0])
+	// 前沿已满时Put方法会阻塞，直到有请求被取出。
+	putDone := make(chan error, 1)
+	go func() {
+		putDone <- frontier.Put(reqs[1])
+	}()
+	select {
+	case <-putDone:
+		t.Fatal("The put into a full frontier didn't block!")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if datum, _ := frontier.Get(); datum != reqs[0] {
+		t.Fatalf("Inconsistent request: expected: %v, actual: %v", reqs[0], datum)
+	}
+	if err := <-putDone; err != nil {
+		t.Fatalf("An error occurs when putting request: %s", err)
+	}
+	frontier.Get()
+	// 前沿为空时Get方法会阻塞，直到前沿被关闭。
+	getDone := make(chan error, 1)
+	go func() {
+		_, err := frontier.Get()
+		getDone <- err
+	}()
+	select {
+	case <-getDone:
+		t.Fatal("The get from an empty frontier didn't block!")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !frontier.Close() {
+		t.Fatal("Couldn't close the frontier!")
+	}
+	if err := <-getDone; err != buffer.ErrClosedBufferPool {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v", buffer.ErrClosedBufferPool, err)
+	}
+	if frontier.Close() {
+		t.Fatal("The frontier was closed twice!")
+	}
+	if err := frontier.Put(reqs[0]); err != buffer.ErrClosedBufferPool {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v", buffer.ErrClosedBufferPool, err)
+	}
+}
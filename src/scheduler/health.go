@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"context"
+	"module"
+	"time"
+)
+
+// checkHealth 会按照给定的间隔时间对所有已注册的组件执行主动健康检查，
+// 直到调度器停止。间隔时间为0时不执行。
+// 每一轮检查的时限等于间隔时间。
+func (sched *myScheduler) checkHealth(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sched.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			ctx, cancel := context.WithTimeout(sched.ctx, interval)
+			sched.registrar.CheckHealth(ctx)
+			cancel()
+		}
+	}()
+}
+
+// reportErrors 用于向注册器报告对给定组件的一次调用的结果。
+// 参数errs为空代表调用成功，否则以其中的第一个错误代表失败。
+func (sched *myScheduler) reportErrors(mid module.MID, errs []error) {
+	var err error
+	if len(errs) > 0 {
+		err = errs[0]
+	}
+	sched.registrar.Report(mid, err)
+}
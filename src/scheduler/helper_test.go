@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"module"
+	"module/local/analyzer"
+	"module/local/downloader"
+	"module/local/pipeline"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// 待完成的工作的数量变为负数时直接引发恐慌，以便暴露重复完成工作的问题。
+	negativeWorkHandler = func(kind workKind, count int64) {
+		panic(fmt.Sprintf("the outstanding %s number is negative: %d", kind, count))
+	}
+}
+
+// newTestReq 会创建一个具有给定URL和深度的GET请求。
+func newTestReq(t *testing.T, rawURL string, depth uint32) *module.Request {
+	httpReq, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating HTTP request: %s", err)
+	}
+	return module.NewRequest(httpReq, depth)
+}
+
+// newTestServer 会创建一个测试用的HTTP服务器。
+// 路径为/p<n>的页面的内容为n，并链接到路径为/p<3n+1>、/p<3n+2>和/p<3n+3>的页面。
+// 参数padding代表页面内容之后的空白字符的数量。
+func newTestServer(padding int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p"))
+		fmt.Fprintf(w, "%d%s", n, strings.Repeat(" ", padding))
+	}))
+}
+
+// testPageNumber 用于计算测试服务器上深度不超过给定值的页面的数量。
+func testPageNumber(maxDepth uint32) int {
+	number, width := 0, 1
+	for depth := uint32(0); depth <= maxDepth; depth++ {
+		number += width
+		width *= 3
+	}
+	return number
+}
+
+// testModules 代表测试用的组件。
+type testModules struct {
+	downloader module.Downloader
+	analyzer   module.Analyzer
+	pipeline   module.Pipeline
+}
+
+// newTestModules 会创建访问给定测试服务器的组件。
+// 参数process会被条目处理管道调用，可以为nil。
+func newTestModules(t *testing.T, srvURL string, process func(item module.Item)) testModules {
+	genMID := func(moduleType module.Type, sn uint64) module.MID {
+		mid, err := module.GenMID(moduleType, sn, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when generating MID: %s", err)
+		}
+		return mid
+	}
+	d, err := downloader.New(genMID(module.TYPE_DOWNLOADER, 1), &http.Client{},
+		module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating downloader: %s", err)
+	}
+	parser := func(httpResp *http.Response, depth uint32) ([]module.Data, []error) {
+		content, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, []error{err}
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, []error{err}
+		}
+		data := []module.Data{module.Item{"n": n}}
+		for i := 1; i <= 3; i++ {
+			httpReq, _ := http.NewRequest(http.MethodGet,
+				fmt.Sprintf("%s/p%d", srvURL, n*3+i), nil)
+			data = append(data, module.NewRequest(httpReq, depth+1))
+		}
+		return data, nil
+	}
+	a, err := analyzer.New(genMID(module.TYPE_ANALYZER, 2),
+		[]module.ParseResponse{parser}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating analyzer: %s", err)
+	}
+	p, err := pipeline.New(genMID(module.TYPE_PIPELINE, 3),
+		[]module.ProcessItem{func(item module.Item) (module.Item, error) {
+			if process != nil {
+				process(item)
+			}
+			return item, nil
+		}}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating pipeline: %s", err)
+	}
+	return testModules{downloader: d, analyzer: a, pipeline: p}
+}
+
+// newTestDataArgs 会创建测试用的数据相关的参数。
+func newTestDataArgs() DataArgs {
+	return DataArgs{
+		ReqBufferCap:         50,
+		ReqMaxBufferNumber:   1000,
+		RespBufferCap:        50,
+		RespMaxBufferNumber:  10,
+		ItemBufferCap:        50,
+		ItemMaxBufferNumber:  100,
+		ErrorBufferCap:       50,
+		ErrorMaxBufferNumber: 1,
+	}
+}
+
+// newTestScheduler 会创建一个以给定组件爬取本机的测试服务器的调度器。
+func newTestScheduler(t *testing.T, requestArgs RequestArgs, modules testModules) Scheduler {
+	if requestArgs.AcceptedDomains == nil {
+		requestArgs.AcceptedDomains = []string{"127.0.0.1"}
+	}
+	sched := NewScheduler()
+	err := sched.Init(requestArgs, newTestDataArgs(), ModuleArgs{
+		Downloaders: []module.Downloader{modules.downloader},
+		Analyzers:   []module.Analyzer{modules.analyzer},
+		Pipelines:   []module.Pipeline{modules.pipeline},
+	})
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	return sched
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"module"
+	"time"
+)
+
+// AddModule 会在调度器运行期间注册给定的组件。
+// 各阶段的工作协程的数量未由参数指定时，
+// 会在组件的数量超过工作协程的数量后增加一个工作协程。
+func (sched *myScheduler) AddModule(m module.Module) error {
+	if m == nil {
+		return genParameterError("nil module")
+	}
+	sched.moduleLock.Lock()
+	defer sched.moduleLock.Unlock()
+	sched.statusLock.RLock()
+	defer sched.statusLock.RUnlock()
+	if err := checkStatusForModuleChange(sched.status); err != nil {
+		return err
+	}
+	ok, err := sched.registrar.Register(m)
+	if err != nil {
+		return genErrorByError(err)
+	}
+	if !ok {
+		errMsg := fmt.Sprintf("the module with MID %q has been registered", m.ID())
+		return genParameterError(errMsg)
+	}
+	_, moduleType := module.GetType(m.ID())
+	moduleMap, _ := sched.registrar.GetAllByType(moduleType)
+	gauge, worker := sched.workerOf(moduleType)
+	if gauge.grow(len(moduleMap)) {
+		// 调度器已开始调度时需要立即启动新增的工作协程。
+		if sched.status == SCHED_STATUS_STARTED || sched.status == SCHED_STATUS_PAUSED {
+			go worker()
+		}
+	}
+	log.Printf("The module has been added. (MID: %s, workers: %d)\n",
+		m.ID(), gauge.workerNumber())
+	return nil
+}
+
+// RemoveModule 会在调度器运行期间注销给定ID的组件。
+// 被注销的组件不会再被选择，但已开始的处理会继续进行。
+// 参数drain为true时会等待该组件已被选出的调用都完成，或者调度器停止。
+// 每种组件至少要保留一个，且工作协程的数量不会因此减少。
+func (sched *myScheduler) RemoveModule(mid module.MID, drain bool) error {
+	m, err := sched.unregisterModule(mid)
+	if err != nil {
+		return err
+	}
+	log.Printf("The module has been removed. (MID: %s)\n", mid)
+	if !drain {
+		return nil
+	}
+	if !sched.waitForModule(m) {
+		errMsg := fmt.Sprintf("the scheduler was stopped before the module %q was drained", mid)
+		return genError(errMsg)
+	}
+	log.Printf("The removed module has been drained. (MID: %s)\n", mid)
+	return nil
+}
+
+// unregisterModule 用于注销给定ID的组件并返回该组件。
+func (sched *myScheduler) unregisterModule(mid module.MID) (module.Module, error) {
+	ok, moduleType := module.GetType(mid)
+	if !ok {
+		errMsg := fmt.Sprintf("illegal MID: %q", mid)
+		return nil, genParameterError(errMsg)
+	}
+	sched.moduleLock.Lock()
+	defer sched.moduleLock.Unlock()
+	sched.statusLock.RLock()
+	defer sched.statusLock.RUnlock()
+	if err := checkStatusForModuleChange(sched.status); err != nil {
+		return nil, err
+	}
+	moduleMap, _ := sched.registrar.GetAllByType(moduleType)
+	m, ok := moduleMap[mid]
+	if !ok {
+		errMsg := fmt.Sprintf("not found module with MID %q", mid)
+		return nil, genParameterError(errMsg)
+	}
+	if len(moduleMap) == 1 {
+		errMsg := fmt.Sprintf("couldn't remove the last %s (MID: %s)", moduleType, mid)
+		return nil, genError(errMsg)
+	}
+	if _, err := sched.registrar.Unregister(mid); err != nil {
+		return nil, genErrorByError(err)
+	}
+	return m, nil
+}
+
+// waitForModule 用于等待给定组件已被选出的调用都报告了结果。
+// 注册器会在选出组件时计数，因此在注销之前被选中但尚未开始处理的调用也会被等待。
+// 结果值为false代表调度器在此之前已停止。
+func (sched *myScheduler) waitForModule(m module.Module) bool {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for {
+		if sched.registrar.InUse(m.ID()) == 0 {
+			return true
+		}
+		select {
+		case <-sched.ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// workerOf 用于获取处理给定类型的组件的工作协程的计量器和函数。
+func (sched *myScheduler) workerOf(moduleType module.Type) (*workerGauge, func()) {
+	switch moduleType {
+	case module.TYPE_DOWNLOADER:
+		return sched.downloadWorkers, sched.downloadWorker
+	case module.TYPE_ANALYZER:
+		return sched.analyzeWorkers, sched.analyzeWorker
+	default:
+		return sched.pickWorkers, sched.pickWorker
+	}
+}
+
+// checkStatusForModuleChange 用于检查调度器在给定状态下是否可以增减组件。
+// 调度器必须已被初始化，且不处于正在启动、正在停止或正在暂停的状态。
+func checkStatusForModuleChange(status Status) error {
+	switch status {
+	case SCHED_STATUS_INITIALIZED, SCHED_STATUS_STARTED,
+		SCHED_STATUS_PAUSED, SCHED_STATUS_STOPPED:
+		return nil
+	case SCHED_STATUS_UNINITIALIZED:
+		return genError("the scheduler has not yet been initialized!")
+	default:
+		errMsg := fmt.Sprintf("couldn't change modules when the scheduler is %s",
+			GetStatusDescription(status))
+		return genError(errMsg)
+	}
+}
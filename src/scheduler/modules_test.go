@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"module"
+	"module/local/downloader"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestDownloader 会创建一个具有给定序列号的下载器。
+func newTestDownloader(t *testing.T, sn uint64) module.Downloader {
+	mid, err := module.GenMID(module.TYPE_DOWNLOADER, sn, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when generating MID: %s", err)
+	}
+	d, err := downloader.New(mid, &http.Client{}, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating downloader: %s", err)
+	}
+	return d
+}
+
+// waitForIdle 用于等待调度器空闲。
+// 缓冲池的Get方法会忙等，工作协程较多时下载会明显变慢，因此等待的时限较长。
+func waitForIdle(t *testing.T, sched Scheduler) {
+	deadline := time.Now().Add(15 * time.Second)
+	for !sched.Idle() {
+		if time.Now().After(deadline) {
+			t.Fatal("The scheduler is not idle!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWorkerGaugeGrow(t *testing.T) {
+	cases := []struct {
+		configured   uint32
+		moduleNumber int
+		grown        bool
+		number       uint32
+	}{
+		{0, 1, false, 1},
+		{0, 2, true, 2},
+		{0, 5, true, 2},
+		{3, 5, false, 3},
+	}
+	for _, c := range cases {
+		gauge := newWorkerGauge(c.configured, 1)
+		if grown := gauge.grow(c.moduleNumber); grown != c.grown {
+			t.Fatalf("Inconsistent growth for %d configured workers and %d modules: "+
+				"expected: %v, actual: %v", c.configured, c.moduleNumber, c.grown, grown)
+		}
+		if number := gauge.workerNumber(); number != c.number {
+			t.Fatalf("Inconsistent worker number for %d configured workers and %d modules: "+
+				"expected: %d, actual: %d", c.configured, c.moduleNumber, c.number, number)
+		}
+	}
+}
+
+func TestAddModule(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	var itemCount uint32
+	modules := newTestModules(t, srv.URL, func(item module.Item) {
+		atomic.AddUint32(&itemCount, 1)
+	})
+	sched := newTestScheduler(t, RequestArgs{MaxDepth: 1}, modules)
+	defer sched.Stop()
+	if err := sched.AddModule(nil); err == nil {
+		t.Fatal("No error when adding nil module!")
+	}
+	if err := sched.AddModule(modules.downloader); err == nil {
+		t.Fatal("No error when adding a registered module!")
+	}
+	workers := sched.(*myScheduler).downloadWorkers
+	if number := workers.workerNumber(); number != 1 {
+		t.Fatalf("Inconsistent worker number: expected: %d, actual: %d", 1, number)
+	}
+	if err := sched.AddModule(newTestDownloader(t, 4)); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+	if number := workers.workerNumber(); number != 2 {
+		t.Fatalf("Inconsistent worker number: expected: %d, actual: %d", 2, number)
+	}
+	httpReq, _ := http.NewRequest(http.MethodGet, srv.URL+"/p0", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	// 在调度器运行期间增加的组件也会使工作协程增加。
+	if err := sched.AddModule(newTestDownloader(t, 5)); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+	if number := workers.workerNumber(); number != 3 {
+		t.Fatalf("Inconsistent worker number: expected: %d, actual: %d", 3, number)
+	}
+	waitForIdle(t, sched)
+	expected := uint32(testPageNumber(1))
+	if actual := atomic.LoadUint32(&itemCount); actual != expected {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", expected, actual)
+	}
+}
+
+func TestRemoveModule(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	modules := newTestModules(t, srv.URL, nil)
+	sched := newTestScheduler(t, RequestArgs{MaxDepth: 0}, modules)
+	defer sched.Stop()
+	httpReq, _ := http.NewRequest(http.MethodGet, srv.URL+"/p0", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	waitForIdle(t, sched)
+	if err := sched.RemoveModule("X1", false); err == nil {
+		t.Fatal("No error when removing module with illegal MID!")
+	}
+	if err := sched.RemoveModule(newTestDownloader(t, 4).ID(), false); err == nil {
+		t.Fatal("No error when removing an unregistered module!")
+	}
+	if err := sched.RemoveModule(modules.analyzer.ID(), false); err == nil {
+		t.Fatal("No error when removing the last analyzer!")
+	}
+	if err := sched.AddModule(newTestDownloader(t, 4)); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+	// 模拟一个已被选出但尚未开始下载的调用，
+	// 例如工作协程在选出下载器之后正在等待礼貌性限制。
+	registrar := sched.(*myScheduler).registrar
+	m, err := registrar.Get(module.TYPE_DOWNLOADER)
+	if err != nil {
+		t.Fatalf("An error occurs when getting downloader: %s", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- sched.RemoveModule(m.ID(), true)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("The module was removed before its selected call was reported! (error: %v)", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	registrar.Report(m.ID(), nil)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("An error occurs when removing module: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The module was not drained after its selected call was reported!")
+	}
+	moduleMap, _ := registrar.GetAllByType(module.TYPE_DOWNLOADER)
+	if _, ok := moduleMap[m.ID()]; ok || len(moduleMap) != 1 {
+		t.Fatalf("Inconsistent downloaders after removing %q: %v", m.ID(), moduleMap)
+	}
+}
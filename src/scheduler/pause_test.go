@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"module"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPauseHoldsTakenData(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	modules := newTestModules(t, srv.URL, nil)
+	sched := newTestScheduler(t, RequestArgs{MaxDepth: 0}, modules)
+	defer sched.Stop()
+	httpReq, _ := http.NewRequest(http.MethodGet, srv.URL+"/p0", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	// 等待种子请求处理完毕，以使各个工作协程都阻塞于缓冲池的Get方法。
+	deadline := time.Now().Add(5 * time.Second)
+	for !sched.Idle() {
+		if time.Now().After(deadline) {
+			t.Fatal("The scheduler is not idle!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	called := modules.downloader.CalledCount()
+	if err := sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+	// 模拟在暂停期间被放回请求缓冲池的请求，例如被推迟或等待重试的请求。
+	httpReq, _ = http.NewRequest(http.MethodGet, srv.URL+"/p1", nil)
+	sched.(*myScheduler).putReq(module.NewRequest(httpReq, 0))
+	time.Sleep(100 * time.Millisecond)
+	if actual := modules.downloader.CalledCount(); actual != called {
+		t.Fatalf("Some requests were downloaded during the pause: expected: %d, actual: %d",
+			called, actual)
+	}
+	if err := sched.Resume(); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for modules.downloader.CalledCount() == called {
+		if time.Now().After(deadline) {
+			t.Fatal("The held request was not downloaded after resumption!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
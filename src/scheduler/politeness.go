@@ -0,0 +1,251 @@
+package scheduler
+
+import (
+	"math/rand"
+	"module"
+	"sort"
+	"sync"
+	"time"
+)
+
+// politenessSweepInterval 代表清除空闲主机的访问状态的间隔时间。
+const politenessSweepInterval = time.Minute
+
+// hostState 代表对单个主机（或主域名）的访问状态。
+type hostState struct {
+	// inFlight 代表正在进行的请求的数量。
+	inFlight uint32
+	// requests 代表已发出的请求的总数。
+	requests uint64
+	// nextTime 代表允许发出下一个请求的最早时间。
+	nextTime time.Time
+	// waiting 代表因并发数达到上限而等待的请求的队列。
+	waiting []*module.Request
+	// delayed 代表因间隔时间未到而被推迟的请求的队列。
+	delayed []*module.Request
+	// timer 代表在下一个时间点放行delayed中最早的请求的定时器。
+	// 它仅在delayed不为空时存在。
+	timer *time.Timer
+	// granted 代表已被定时器放行并已占用了时间点的请求的集合。
+	// 它们被重新放入请求缓冲池后再次到来时不必再等待。
+	granted map[*module.Request]struct{}
+}
+
+// idle 用于判断该主机是否已没有任何需要保留的状态。
+func (state *hostState) idle(now time.Time) bool {
+	return state.inFlight == 0 &&
+		len(state.waiting) == 0 &&
+		len(state.delayed) == 0 &&
+		!now.Before(state.nextTime)
+}
+
+// politeness 代表按主机限制请求频率的礼貌层。
+type politeness struct {
+	// maxConcurrency 代表对同一主机的最大并发请求数。0代表不限制。
+	maxConcurrency uint32
+	// minDelay 代表对同一主机的两次请求之间的最小间隔时间。
+	minDelay time.Duration
+	// jitter 代表间隔时间的最大随机附加值。
+	jitter time.Duration
+	// byPrimaryDomain 代表是否按主域名而非主机名分组。
+	byPrimaryDomain bool
+	// requeue 代表把被推迟的请求重新放入请求缓冲池的函数。
+	requeue func(req *module.Request)
+	// crawlDelay 代表获取给定请求所在主机要求的间隔时间的函数。可以为nil。
+	crawlDelay func(req *module.Request) time.Duration
+	// hosts 代表主机与其访问状态的字典。
+	hosts map[string]*hostState
+	// deferred 代表当前被推迟的请求的总数。
+	deferred uint64
+	// lastSweep 代表上一次清除空闲主机的访问状态的时间。
+	lastSweep time.Time
+	lock      sync.Mutex
+}
+
+// newPoliteness 会创建一个礼貌层实例。
+func newPoliteness(
+	requestArgs RequestArgs, requeue func(req *module.Request)) *politeness {
+	return &politeness{
+		maxConcurrency:  requestArgs.MaxConcurrencyPerHost,
+		minDelay:        requestArgs.MinDelayPerHost,
+		jitter:          requestArgs.DelayJitter,
+		byPrimaryDomain: requestArgs.PolitenessByPrimaryDomain,
+		requeue:         requeue,
+		hosts:           map[string]*hostState{},
+		lastSweep:       time.Now(),
+	}
+}
+
+// key 用于获取给定请求所属的分组的键。
+func (p *politeness) key(req *module.Request) string {
+	host := getReqHost(req.HTTPReq())
+	if p.byPrimaryDomain {
+		if pd, err := getPrimaryDomain(host); err == nil {
+			return pd
+		}
+	}
+	return host
+}
+
+// admit 用于判断给定的请求现在是否可以被下载。
+// 若结果值为false，则该请求已被推迟，
+// 并会在条件满足时通过requeue函数被重新放入请求缓冲池。
+// 因间隔时间未到而被推迟的请求会在同一主机的队列中排队，
+// 并由该主机唯一的定时器按照间隔时间逐个放行。
+// 若结果值为true，则在下载完成后必须调用release方法。
+func (p *politeness) admit(req *module.Request) bool {
+	key := p.key(req)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	if now.Sub(p.lastSweep) >= politenessSweepInterval {
+		p.sweep(now)
+	}
+	state := p.hosts[key]
+	if state == nil {
+		state = &hostState{granted: map[*module.Request]struct{}{}}
+		p.hosts[key] = state
+	}
+	if p.maxConcurrency > 0 && state.inFlight >= p.maxConcurrency {
+		// 等待并发名额的请求错过了其占用的时间点，再次到来时需要重新等待。
+		delete(state.granted, req)
+		state.waiting = append(state.waiting, req)
+		p.deferred++
+		return false
+	}
+	if _, ok := state.granted[req]; ok {
+		// 该请求的时间点已在放行时被占用。
+		delete(state.granted, req)
+	} else {
+		// 已有请求在排队时，新的请求也要排队，以免插队。
+		if wait := state.nextTime.Sub(now); wait > 0 || len(state.delayed) > 0 {
+			state.delayed = append(state.delayed, req)
+			p.deferred++
+			if state.timer == nil {
+				state.timer = time.AfterFunc(wait, func() { p.fire(key, state) })
+			}
+			return false
+		}
+		state.nextTime = now.Add(p.delay(req))
+	}
+	state.inFlight++
+	state.requests++
+	return true
+}
+
+// delay 用于计算给定请求之后对同一主机的下一次请求所需的间隔时间。
+func (p *politeness) delay(req *module.Request) time.Duration {
+	delay := p.minDelay
+	if p.crawlDelay != nil {
+		if crawlDelay := p.crawlDelay(req); crawlDelay > delay {
+			delay = crawlDelay
+		}
+	}
+	if p.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.jitter)))
+	}
+	return delay
+}
+
+// fire 会在给定主机的下一个时间点到来时放行其队列中最早的请求。
+// 被放行的请求会占用该时间点，因此即使它没有再次到来，
+// 队列中的其他请求也会在之后的时间点被逐个放行。
+func (p *politeness) fire(key string, state *hostState) {
+	p.lock.Lock()
+	state.timer = nil
+	if len(state.delayed) == 0 {
+		p.lock.Unlock()
+		return
+	}
+	now := time.Now()
+	if wait := state.nextTime.Sub(now); wait > 0 {
+		state.timer = time.AfterFunc(wait, func() { p.fire(key, state) })
+		p.lock.Unlock()
+		return
+	}
+	req := state.delayed[0]
+	state.delayed[0] = nil
+	state.delayed = state.delayed[1:]
+	p.deferred--
+	state.granted[req] = struct{}{}
+	state.nextTime = now.Add(p.delay(req))
+	if len(state.delayed) > 0 {
+		state.timer = time.AfterFunc(state.nextTime.Sub(now),
+			func() { p.fire(key, state) })
+	}
+	p.lock.Unlock()
+	p.requeue(req)
+}
+
+// sweep 用于清除空闲主机的访问状态，以免主机的字典无限增长。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (p *politeness) sweep(now time.Time) {
+	for key, state := range p.hosts {
+		if state.idle(now) {
+			delete(p.hosts, key)
+		}
+	}
+	p.lastSweep = now
+}
+
+// release 用于在给定请求下载完成后释放其占用的并发名额。
+// 若有请求在等待同一主机的并发名额，就把最早的一个重新放入请求缓冲池。
+func (p *politeness) release(req *module.Request) {
+	key := p.key(req)
+	p.lock.Lock()
+	state := p.hosts[key]
+	if state == nil {
+		p.lock.Unlock()
+		return
+	}
+	if state.inFlight > 0 {
+		state.inFlight--
+	}
+	var next *module.Request
+	if len(state.waiting) > 0 {
+		next = state.waiting[0]
+		state.waiting[0] = nil
+		state.waiting = state.waiting[1:]
+		p.deferred--
+	}
+	p.lock.Unlock()
+	if next != nil {
+		p.requeue(next)
+	}
+}
+
+// deferredNumber 用于获取当前被推迟的请求的总数。
+func (p *politeness) deferredNumber() uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.deferred
+}
+
+// HostSummaryStruct 代表单个主机（或主域名）的访问摘要类型。
+type HostSummaryStruct struct {
+	Host     string `json:"host"`
+	InFlight uint32 `json:"in_flight"`
+	Deferred uint32 `json:"deferred"`
+	Requests uint64 `json:"requests"`
+}
+
+// summary 用于获取所有主机的访问摘要。
+// 空闲主机的访问状态会被定期清除，因此不会出现在摘要中。
+func (p *politeness) summary() []HostSummaryStruct {
+	p.lock.Lock()
+	summaries := make([]HostSummaryStruct, 0, len(p.hosts))
+	for host, state := range p.hosts {
+		summaries = append(summaries, HostSummaryStruct{
+			Host:     host,
+			InFlight: state.inFlight,
+			Deferred: uint32(len(state.waiting) + len(state.delayed)),
+			Requests: state.requests,
+		})
+	}
+	p.lock.Unlock()
+	sort.Slice(summaries,
+		func(i, j int) bool {
+			return summaries[i].Host < summaries[j].Host
+		})
+	return summaries
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"fmt"
+	"module"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPolitenessDelayQueue(t *testing.T) {
+	minDelay := 50 * time.Millisecond
+	var lock sync.Mutex
+	var requeued int
+	var admitted []string
+	var admitTimes []time.Time
+	var p *politeness
+	p = newPoliteness(RequestArgs{MinDelayPerHost: minDelay}, func(req *module.Request) {
+		// 模拟下载阶段的工作协程再次取出被推迟的请求。
+		lock.Lock()
+		requeued++
+		lock.Unlock()
+		if p.admit(req) {
+			lock.Lock()
+			admitted = append(admitted, req.HTTPReq().URL.Path)
+			admitTimes = append(admitTimes, time.Now())
+			lock.Unlock()
+			p.release(req)
+		}
+	})
+	number := 5
+	if !p.admit(newTestReq(t, "http://a.com/0", 0)) {
+		t.Fatal("The first request was deferred!")
+	}
+	for i := 1; i < number; i++ {
+		if p.admit(newTestReq(t, fmt.Sprintf("http://a.com/%d", i), 0)) {
+			t.Fatalf("The request #%d was not deferred!", i)
+		}
+	}
+	if deferred := p.deferredNumber(); deferred != uint64(number-1) {
+		t.Fatalf("Inconsistent deferred number: expected: %d, actual: %d", number-1, deferred)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for p.deferredNumber() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Some requests are still deferred: %d", p.deferredNumber())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	// 每个被推迟的请求只会被放行一次，且按照被推迟的顺序放行。
+	if requeued != number-1 {
+		t.Fatalf("Inconsistent requeued number: expected: %d, actual: %d", number-1, requeued)
+	}
+	for i, path := range admitted {
+		if expected := fmt.Sprintf("/%d", i+1); path != expected {
+			t.Fatalf("Inconsistent admission order: %v", admitted)
+		}
+	}
+	if len(admitted) != number-1 {
+		t.Fatalf("Inconsistent admitted number: expected: %d, actual: %d", number-1, len(admitted))
+	}
+	for i := 1; i < len(admitTimes); i++ {
+		if interval := admitTimes[i].Sub(admitTimes[i-1]); interval < minDelay*4/5 {
+			t.Fatalf("Too short interval between admissions: %s", interval)
+		}
+	}
+}
+
+func TestPolitenessConcurrency(t *testing.T) {
+	requeued := make(chan *module.Request, 1)
+	p := newPoliteness(RequestArgs{MaxConcurrencyPerHost: 1}, func(req *module.Request) {
+		requeued <- req
+	})
+	first := newTestReq(t, "http://a.com/0", 0)
+	second := newTestReq(t, "http://a.com/1", 0)
+	if !p.admit(first) {
+		t.Fatal("The first request was deferred!")
+	}
+	if p.admit(second) {
+		t.Fatal("The second request was not deferred!")
+	}
+	// 其他主机不受影响。
+	if !p.admit(newTestReq(t, "http://b.com/0", 0)) {
+		t.Fatal("The request for another host was deferred!")
+	}
+	summaries := p.summary()
+	if len(summaries) != 2 || summaries[0].InFlight != 1 || summaries[0].Deferred != 1 {
+		t.Fatalf("Inconsistent host summaries: %+v", summaries)
+	}
+	p.release(first)
+	select {
+	case req := <-requeued:
+		if req != second {
+			t.Fatalf("Inconsistent requeued request: expected: %v, actual: %v", second, req)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The waiting request was not requeued!")
+	}
+	if !p.admit(second) {
+		t.Fatal("The requeued request was deferred!")
+	}
+}
+
+func TestPolitenessSweep(t *testing.T) {
+	p := newPoliteness(RequestArgs{}, func(req *module.Request) {})
+	for _, host := range []string{"a.com", "b.com", "c.com"} {
+		req := newTestReq(t, "http://"+host+"/0", 0)
+		if !p.admit(req) {
+			t.Fatalf("The request for %s was deferred!", host)
+		}
+		if host != "b.com" {
+			p.release(req)
+		}
+	}
+	p.lastSweep = time.Now().Add(-politenessSweepInterval)
+	p.admit(newTestReq(t, "http://d.com/0", 0))
+	// 只有仍有请求在进行中的主机和新的主机会被保留。
+	summaries := p.summary()
+	if len(summaries) != 2 || summaries[0].Host != "b.com" || summaries[1].Host != "d.com" {
+		t.Fatalf("Inconsistent host summaries after sweeping: %+v", summaries)
+	}
+}
@@ -0,0 +1,239 @@
+package scheduler
+
+import (
+	"context"
+	"errs"
+	"fmt"
+	"io"
+	"math/rand"
+	"module"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DEFAULT_RETRY_BASE_DELAY 代表首次重试前的默认基础等待时间。
+	DEFAULT_RETRY_BASE_DELAY = time.Second
+	// DEFAULT_RETRY_MAX_DELAY 代表重试前的默认最大等待时间。
+	DEFAULT_RETRY_MAX_DELAY = time.Minute
+)
+
+// defaultRetryableStatusCodes 代表默认的可重试的HTTP状态码。
+var defaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryArgs 代表下载失败时的重试参数。
+type RetryArgs struct {
+	// MaxAttempts 代表每个请求最多被下载的次数（包括首次下载）。
+	// 0或1代表不重试。
+	MaxAttempts uint32 `json:"max_attempts"`
+	// BaseDelay 代表首次重试前的基础等待时间。
+	// 之后每次重试的等待时间都会加倍。0代表使用默认值。
+	BaseDelay time.Duration `json:"base_delay"`
+	// MaxDelay 代表重试前的最大等待时间。0代表使用默认值。
+	// 若响应的Retry-After头要求的等待时间超过它，请求会被放弃。
+	MaxDelay time.Duration `json:"max_delay"`
+	// RetryableStatusCodes 代表可重试的HTTP状态码的列表。
+	// 为空时使用默认值，即429、500、502、503和504。
+	RetryableStatusCodes []int `json:"retryable_status_codes"`
+}
+
+// Check 用于检查重试参数的有效性。
+func (args *RetryArgs) Check() error {
+	if args.BaseDelay < 0 {
+		return genError("negative retry base delay")
+	}
+	if args.MaxDelay < 0 {
+		return genError("negative retry max delay")
+	}
+	if args.BaseDelay > 0 && args.MaxDelay > 0 && args.BaseDelay > args.MaxDelay {
+		return genError("retry base delay is greater than max delay")
+	}
+	for _, code := range args.RetryableStatusCodes {
+		if code < 100 || code > 599 {
+			return genError(fmt.Sprintf("illegal retryable status code: %d", code))
+		}
+	}
+	return nil
+}
+
+// Same 用于判断当前的重试参数与另一份是否相同。
+func (args *RetryArgs) Same(another *RetryArgs) bool {
+	if another == nil {
+		return false
+	}
+	if another.MaxAttempts != args.MaxAttempts ||
+		another.BaseDelay != args.BaseDelay ||
+		another.MaxDelay != args.MaxDelay {
+		return false
+	}
+	if len(another.RetryableStatusCodes) != len(args.RetryableStatusCodes) {
+		return false
+	}
+	for i, code := range another.RetryableStatusCodes {
+		if code != args.RetryableStatusCodes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// retrier 代表按照重试策略重新下载失败请求的重试器。
+type retrier struct {
+	maxAttempts uint32
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	statusCodes map[int]bool
+	// requeue 代表把需重试的请求重新放入请求缓冲池的函数。
+	requeue func(req *module.Request)
+	// retried 代表已安排的重试的次数。
+	retried uint64
+	// abandoned 代表用尽重试机会后被放弃的请求的数量。
+	abandoned uint64
+	// waiting 代表正在等待重试的请求的数量。
+	waiting int64
+}
+
+// newRetrier 会创建一个重试器。
+func newRetrier(args RetryArgs, requeue func(req *module.Request)) *retrier {
+	r := &retrier{
+		maxAttempts: args.MaxAttempts,
+		baseDelay:   args.BaseDelay,
+		maxDelay:    args.MaxDelay,
+		statusCodes: map[int]bool{},
+		requeue:     requeue,
+	}
+	if r.baseDelay == 0 {
+		r.baseDelay = DEFAULT_RETRY_BASE_DELAY
+	}
+	if r.maxDelay == 0 {
+		r.maxDelay = DEFAULT_RETRY_MAX_DELAY
+	}
+	if r.baseDelay > r.maxDelay {
+		r.baseDelay = r.maxDelay
+	}
+	statusCodes := args.RetryableStatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = defaultRetryableStatusCodes
+	}
+	for _, code := range statusCodes {
+		r.statusCodes[code] = true
+	}
+	return r
+}
+
+// enabled 用于判断是否启用了重试。
+func (r *retrier) enabled() bool {
+	return r.maxAttempts > 1
+}
+
+// retryableStatus 用于判断给定的HTTP状态码是否可重试。
+func (r *retrier) retryableStatus(code int) bool {
+	return r.statusCodes[code]
+}
+
+// backoff 用于计算第attempt次下载失败后的等待时间。
+// 等待时间按指数增长，并在其后一半范围内随机抖动。
+func (r *retrier) backoff(attempt uint32) time.Duration {
+	delay := r.baseDelay
+	for i := uint32(1); i < attempt && delay < r.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.maxDelay {
+		delay = r.maxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retry 用于为下载失败的请求安排重试。
+// 参数retryAfter代表服务端通过Retry-After头要求的等待时间，0代表未要求。
+// 结果值为true代表已安排重试，请求会在等待后通过requeue函数被重新放入请求缓冲池。
+// 结果值为false代表未启用重试或请求已被放弃。
+func (r *retrier) retry(req *module.Request, retryAfter time.Duration) bool {
+	if !r.enabled() {
+		return false
+	}
+	if req.Attempt() >= r.maxAttempts || retryAfter > r.maxDelay {
+		atomic.AddUint64(&r.abandoned, 1)
+		return false
+	}
+	delay := r.backoff(req.Attempt())
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	atomic.AddUint64(&r.retried, 1)
+	atomic.AddInt64(&r.waiting, 1)
+	time.AfterFunc(delay, func() {
+		atomic.AddInt64(&r.waiting, -1)
+		r.requeue(req)
+	})
+	return true
+}
+
+// waitingNumber 用于获取正在等待重试的请求的数量。
+func (r *retrier) waitingNumber() int64 {
+	return atomic.LoadInt64(&r.waiting)
+}
+
+// retriedNumber 用于获取已安排的重试的次数。
+func (r *retrier) retriedNumber() uint64 {
+	return atomic.LoadUint64(&r.retried)
+}
+
+// abandonedNumber 用于获取被放弃的请求的数量。
+func (r *retrier) abandonedNumber() uint64 {
+	return atomic.LoadUint64(&r.abandoned)
+}
+
+// isRetryableError 用于判断给定的下载错误是否可重试。
+// 网络错误和连接被意外关闭的错误可重试，
+// 而组件返回的爬虫错误（如参数错误）不可重试。
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(errs.CrawlerError); ok {
+		return false
+	}
+	if err == context.Canceled {
+		return false
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter 用于解析Retry-After头的值。
+// 该值可以是秒数，也可以是HTTP日期。无法解析时返回0。
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"errs"
+	"io"
+	"module"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"  ", 0},
+		{"120", 2 * time.Minute},
+		{" 3 ", 3 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{"1.5", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0},
+		{now.Format(http.TimeFormat), 0},
+		// RFC 850格式的HTTP日期。
+		{"Tuesday, 02-Jan-24 03:05:05 GMT", time.Minute},
+	}
+	for _, c := range cases {
+		if actual := parseRetryAfter(c.value, now); actual != c.expected {
+			t.Fatalf("Inconsistent delay for %q: expected: %s, actual: %s",
+				c.value, c.expected, actual)
+		}
+	}
+}
+
+func TestRetrierBackoff(t *testing.T) {
+	r := newRetrier(RetryArgs{
+		MaxAttempts: 10,
+		BaseDelay:   time.Second,
+		MaxDelay:    10 * time.Second,
+	}, nil)
+	cases := []struct {
+		attempt uint32
+		min     time.Duration
+		max     time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{4, 4 * time.Second, 8 * time.Second},
+		{5, 5 * time.Second, 10 * time.Second},
+		{100, 5 * time.Second, 10 * time.Second},
+	}
+	for _, c := range cases {
+		for i := 0; i < 100; i++ {
+			delay := r.backoff(c.attempt)
+			if delay < c.min || delay > c.max {
+				t.Fatalf("Inconsistent delay for attempt %d: expected: [%s, %s], actual: %s",
+					c.attempt, c.min, c.max, delay)
+			}
+		}
+	}
+}
+
+func TestNewRetrierDefaults(t *testing.T) {
+	cases := []struct {
+		args      RetryArgs
+		baseDelay time.Duration
+		maxDelay  time.Duration
+	}{
+		{RetryArgs{}, DEFAULT_RETRY_BASE_DELAY, DEFAULT_RETRY_MAX_DELAY},
+		{RetryArgs{BaseDelay: 2 * time.Minute}, DEFAULT_RETRY_MAX_DELAY, DEFAULT_RETRY_MAX_DELAY},
+		{RetryArgs{MaxDelay: 100 * time.Millisecond}, 100 * time.Millisecond, 100 * time.Millisecond},
+	}
+	for _, c := range cases {
+		r := newRetrier(c.args, nil)
+		if r.baseDelay != c.baseDelay || r.maxDelay != c.maxDelay {
+			t.Fatalf("Inconsistent delays for %+v: expected: %s/%s, actual: %s/%s",
+				c.args, c.baseDelay, c.maxDelay, r.baseDelay, r.maxDelay)
+		}
+	}
+	r := newRetrier(RetryArgs{}, nil)
+	for _, code := range []int{429, 500, 502, 503, 504} {
+		if !r.retryableStatus(code) {
+			t.Fatalf("The status code %d is not retryable by default!", code)
+		}
+	}
+	if r.retryableStatus(http.StatusNotFound) {
+		t.Fatalf("The status code %d is retryable by default!", http.StatusNotFound)
+	}
+}
+
+func TestRetryArgsCheck(t *testing.T) {
+	cases := []struct {
+		args  RetryArgs
+		valid bool
+	}{
+		{RetryArgs{}, true},
+		{RetryArgs{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}, true},
+		{RetryArgs{BaseDelay: -1}, false},
+		{RetryArgs{MaxDelay: -1}, false},
+		{RetryArgs{BaseDelay: time.Minute, MaxDelay: time.Second}, false},
+		{RetryArgs{RetryableStatusCodes: []int{503}}, true},
+		{RetryArgs{RetryableStatusCodes: []int{99}}, false},
+		{RetryArgs{RetryableStatusCodes: []int{600}}, false},
+	}
+	for _, c := range cases {
+		if err := c.args.Check(); (err == nil) != c.valid {
+			t.Fatalf("Inconsistent check result for %+v: expected valid: %v, actual error: %v",
+				c.args, c.valid, err)
+		}
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{errors.New("unknown"), false},
+		{context.Canceled, false},
+		{io.EOF, true},
+		{io.ErrUnexpectedEOF, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&net.DNSError{Err: "no such host", IsTimeout: true}, true},
+		{errs.NewCrawlerError(errs.ERROR_TYPE_DOWNLOADER, "illegal parameter"), false},
+		{genError("scheduler error"), false},
+	}
+	for _, c := range cases {
+		if actual := isRetryableError(c.err); actual != c.retryable {
+			t.Fatalf("Inconsistent result for error %v: expected: %v, actual: %v",
+				c.err, c.retryable, actual)
+		}
+	}
+}
+
+func TestRetrierRetry(t *testing.T) {
+	requeued := make(chan *module.Request, 1)
+	r := newRetrier(RetryArgs{
+		MaxAttempts: 2,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}, func(req *module.Request) {
+		requeued <- req
+	})
+	httpReq, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req := module.NewRequest(httpReq, 0)
+	req.SetAttempt(1)
+	// Retry-After头要求的等待时间超过最大等待时间时，请求会被放弃。
+	if r.retry(req, 2*time.Second) {
+		t.Fatal("The request was retried beyond the max delay!")
+	}
+	if !r.retry(req, 0) {
+		t.Fatal("The request was not retried!")
+	}
+	if waiting := r.waitingNumber(); waiting != 1 {
+		t.Fatalf("Inconsistent waiting number: expected: %d, actual: %d", 1, waiting)
+	}
+	select {
+	case actual := <-requeued:
+		if actual != req {
+			t.Fatalf("Inconsistent requeued request: expected: %v, actual: %v", req, actual)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The request was not requeued!")
+	}
+	if waiting := r.waitingNumber(); waiting != 0 {
+		t.Fatalf("Inconsistent waiting number: expected: %d, actual: %d", 0, waiting)
+	}
+	// 用尽重试机会的请求会被放弃。
+	req.SetAttempt(2)
+	if r.retry(req, 0) {
+		t.Fatal("The request was retried beyond the max attempts!")
+	}
+	if retried, abandoned := r.retriedNumber(), r.abandonedNumber(); retried != 1 || abandoned != 2 {
+		t.Fatalf("Inconsistent numbers: expected retried: %d, abandoned: %d; "+
+			"actual retried: %d, abandoned: %d", 1, 2, retried, abandoned)
+	}
+	if newRetrier(RetryArgs{MaxAttempts: 1}, nil).retry(req, 0) {
+		t.Fatal("The request was retried when retrying is disabled!")
+	}
+}
@@ -0,0 +1,406 @@
+package scheduler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"module"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DEFAULT_ROBOTS_TTL 代表robots.txt规则的默认缓存时长。
+	DEFAULT_ROBOTS_TTL = 24 * time.Hour
+	// robotsErrorTTL 代表获取robots.txt失败时的结果的缓存时长。
+	robotsErrorTTL = time.Minute
+	// robotsMaxSize 代表robots.txt的最大读取字节数。
+	robotsMaxSize = 500 * 1024
+	// robotsSweepInterval 代表清除已过期的robots.txt缓存条目的间隔时间。
+	robotsSweepInterval = time.Minute
+)
+
+// robotsRule 代表robots.txt中的一条Allow或Disallow规则。
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsRules 代表适用于某个用户代理的robots.txt规则集。
+type robotsRules struct {
+	// allowAll 代表是否允许访问所有路径。
+	allowAll bool
+	// disallowAll 代表是否禁止访问所有路径。
+	disallowAll bool
+	// temporary 代表禁止访问所有路径是否只是因为暂时无法获取robots.txt。
+	temporary bool
+	rules     []robotsRule
+	// crawlDelay 代表两次请求之间的间隔时间。
+	crawlDelay time.Duration
+}
+
+// allowed 用于判断给定的路径是否允许访问。
+// 匹配长度最长的规则生效，长度相同时Allow规则优先。
+func (rr *robotsRules) allowed(path string) bool {
+	if rr.disallowAll {
+		return false
+	}
+	if rr.allowAll || path == "/robots.txt" {
+		return true
+	}
+	matchedLen := -1
+	allowed := true
+	for _, rule := range rr.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		ruleLen := len(rule.pattern)
+		if ruleLen > matchedLen || (ruleLen == matchedLen && rule.allow) {
+			matchedLen = ruleLen
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsGroup 代表robots.txt中针对一组用户代理的记录。
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// parseRobots 用于解析robots.txt的内容，
+// 并返回适用于给定用户代理的规则集。
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	// lastWasAgent 代表上一个有效行是否为User-agent行。
+	var lastWasAgent bool
+	scanner := bufio.NewScanner(io.LimitReader(r, robotsMaxSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+		index := strings.Index(line, ":")
+		if index < 0 {
+			continue
+		}
+		field := strings.ToLower(strings.TrimSpace(line[:index]))
+		value := strings.TrimSpace(line[index+1:])
+		switch field {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{
+					allow:   field == "allow",
+					pattern: value,
+					re:      compileRobotsPattern(value),
+				})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		default:
+			// 忽略Sitemap等与用户代理无关的字段。
+			continue
+		}
+		lastWasAgent = false
+	}
+	return selectRobotsGroup(groups, userAgent)
+}
+
+// selectRobotsGroup 用于选出与给定用户代理最匹配的记录，
+// 若没有匹配的记录，就使用针对“*”的记录。
+func selectRobotsGroup(groups []*robotsGroup, userAgent string) *robotsRules {
+	userAgent = strings.ToLower(userAgent)
+	var selected []*robotsGroup
+	var selectedLen int
+	var wildcard []*robotsGroup
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			if agent == "*" {
+				wildcard = append(wildcard, group)
+				continue
+			}
+			if agent == "" || !strings.Contains(userAgent, agent) {
+				continue
+			}
+			if len(agent) > selectedLen {
+				selected = []*robotsGroup{group}
+				selectedLen = len(agent)
+			} else if len(agent) == selectedLen {
+				selected = append(selected, group)
+			}
+		}
+	}
+	if len(selected) == 0 {
+		selected = wildcard
+	}
+	rules := &robotsRules{}
+	if len(selected) == 0 {
+		rules.allowAll = true
+		return rules
+	}
+	for _, group := range selected {
+		rules.rules = append(rules.rules, group.rules...)
+		if group.crawlDelay > rules.crawlDelay {
+			rules.crawlDelay = group.crawlDelay
+		}
+	}
+	return rules
+}
+
+// compileRobotsPattern 用于把robots.txt中的路径模式编译为正则表达式。
+// 模式中的“*”代表任意字符序列，末尾的“$”代表路径的结尾。
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// robotsFetcher 代表获取robots.txt的函数的类型。
+// 参数robotsURL代表robots.txt的完整URL。
+type robotsFetcher func(ctx context.Context, robotsURL string) (*http.Response, error)
+
+// robotsEntry 代表robots.txt缓存中的条目。
+type robotsEntry struct {
+	rules  *robotsRules
+	expire time.Time
+	// ready 会在规则获取完毕后被关闭。
+	ready chan struct{}
+}
+
+// robotsCache 代表robots.txt规则的缓存。
+type robotsCache struct {
+	// userAgent 代表需要遵守的规则所针对的用户代理。
+	userAgent string
+	// ttl 代表规则的缓存时长。
+	ttl   time.Duration
+	fetch robotsFetcher
+	// entries 代表“协议://主机”与缓存条目的字典。
+	entries map[string]*robotsEntry
+	// blocked 代表被robots.txt禁止访问的URL的数量。
+	blocked uint64
+	// deferred 代表因暂时无法获取robots.txt而未被允许访问的URL的数量。
+	deferred uint64
+	// lastSweep 代表上一次清除已过期的缓存条目的时间。
+	lastSweep time.Time
+	lock      sync.Mutex
+}
+
+// newRobotsCache 会创建一个robots.txt规则缓存。
+// 若参数userAgent为空，就会返回nil，表示不遵守robots.txt。
+func newRobotsCache(
+	userAgent string, ttl time.Duration, fetch robotsFetcher) *robotsCache {
+	if userAgent == "" {
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = DEFAULT_ROBOTS_TTL
+	}
+	return &robotsCache{
+		userAgent: userAgent,
+		ttl:       ttl,
+		fetch:     fetch,
+		entries:   map[string]*robotsEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+// rules 用于获取给定协议和主机的规则集。
+// 若缓存中没有有效的规则，就会获取并解析robots.txt。
+func (rc *robotsCache) rules(
+	ctx context.Context, scheme string, host string) *robotsRules {
+	key := strings.ToLower(scheme) + "://" + strings.ToLower(host)
+	rc.lock.Lock()
+	if now := time.Now(); now.Sub(rc.lastSweep) >= robotsSweepInterval {
+		rc.sweep(now)
+	}
+	entry := rc.entries[key]
+	if entry != nil {
+		select {
+		case <-entry.ready:
+			if time.Now().Before(entry.expire) {
+				rc.lock.Unlock()
+				return entry.rules
+			}
+			entry = nil
+		default:
+		}
+	}
+	if entry != nil {
+		// 其他goroutine正在获取规则。
+		rc.lock.Unlock()
+		select {
+		case <-entry.ready:
+			return entry.rules
+		case <-ctx.Done():
+			return &robotsRules{allowAll: true}
+		}
+	}
+	entry = &robotsEntry{ready: make(chan struct{})}
+	rc.entries[key] = entry
+	rc.lock.Unlock()
+	rules, ttl := rc.load(ctx, key+"/robots.txt")
+	entry.rules = rules
+	entry.expire = time.Now().Add(ttl)
+	close(entry.ready)
+	return rules
+}
+
+// sweep 用于清除已过期的缓存条目，以免主机的字典无限增长。
+// 正在获取规则的条目不会被清除。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (rc *robotsCache) sweep(now time.Time) {
+	for key, entry := range rc.entries {
+		select {
+		case <-entry.ready:
+			if !now.Before(entry.expire) {
+				delete(rc.entries, key)
+			}
+		default:
+		}
+	}
+	rc.lastSweep = now
+}
+
+// load 用于获取和解析robots.txt，并返回规则集及其缓存时长。
+// 按照RFC 9309，robots.txt不存在时允许访问所有路径，
+// 无法获取时禁止访问所有路径。
+func (rc *robotsCache) load(
+	ctx context.Context, robotsURL string) (*robotsRules, time.Duration) {
+	httpResp, err := rc.fetch(ctx, robotsURL)
+	if err != nil || httpResp == nil {
+		return &robotsRules{disallowAll: true, temporary: true}, robotsErrorTTL
+	}
+	if httpResp.Body != nil {
+		defer httpResp.Body.Close()
+	}
+	switch {
+	case httpResp.StatusCode >= 200 && httpResp.StatusCode < 300:
+		if httpResp.Body == nil {
+			return &robotsRules{allowAll: true}, rc.ttl
+		}
+		return parseRobots(httpResp.Body, rc.userAgent), rc.ttl
+	case httpResp.StatusCode >= 400 && httpResp.StatusCode < 500:
+		return &robotsRules{allowAll: true}, rc.ttl
+	default:
+		return &robotsRules{disallowAll: true, temporary: true}, robotsErrorTTL
+	}
+}
+
+// allowed 用于判断给定的HTTP请求是否被robots.txt允许。
+// 结果值temporary为true代表只是因为暂时无法获取robots.txt而不允许。
+func (rc *robotsCache) allowed(
+	ctx context.Context, httpReq *http.Request) (allowed bool, temporary bool) {
+	reqURL := httpReq.URL
+	rules := rc.rules(ctx, reqURL.Scheme, reqURL.Host)
+	path := reqURL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if reqURL.RawQuery != "" {
+		path += "?" + reqURL.RawQuery
+	}
+	if rules.allowed(path) {
+		return true, false
+	}
+	if rules.temporary {
+		atomic.AddUint64(&rc.deferred, 1)
+	} else {
+		atomic.AddUint64(&rc.blocked, 1)
+	}
+	return false, rules.temporary
+}
+
+// crawlDelay 用于获取给定HTTP请求所在主机的已缓存的Crawl-delay值。
+// 本方法不会触发robots.txt的获取。
+func (rc *robotsCache) crawlDelay(httpReq *http.Request) time.Duration {
+	reqURL := httpReq.URL
+	key := strings.ToLower(reqURL.Scheme) + "://" + strings.ToLower(reqURL.Host)
+	rc.lock.Lock()
+	entry := rc.entries[key]
+	rc.lock.Unlock()
+	if entry == nil {
+		return 0
+	}
+	select {
+	case <-entry.ready:
+		return entry.rules.crawlDelay
+	default:
+		return 0
+	}
+}
+
+// blockedNumber 用于获取被robots.txt禁止访问的URL的数量。
+func (rc *robotsCache) blockedNumber() uint64 {
+	return atomic.LoadUint64(&rc.blocked)
+}
+
+// deferredNumber 用于获取因暂时无法获取robots.txt而未被允许访问的URL的数量。
+func (rc *robotsCache) deferredNumber() uint64 {
+	return atomic.LoadUint64(&rc.deferred)
+}
+
+// fetchRobots 会通过已注册的下载器获取robots.txt。
+func (sched *myScheduler) fetchRobots(
+	ctx context.Context, robotsURL string) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("User-Agent", sched.requestArgs.RobotsUserAgent)
+	m, err := sched.registrar.Get(module.TYPE_DOWNLOADER)
+	if err != nil || m == nil {
+		errMsg := fmt.Sprintf("couldn't get a downloader for robots.txt: %s", err)
+		return nil, genError(errMsg)
+	}
+	downloader, ok := m.(module.Downloader)
+	if !ok {
+		errMsg := fmt.Sprintf("incorrect downloader type: %T (MID: %s)",
+			m, m.ID())
+		sched.registrar.Report(m.ID(), genError(errMsg))
+		return nil, genError(errMsg)
+	}
+	resp, err := downloader.Download(ctx, module.NewRequest(httpReq, 0))
+	sched.registrar.Report(m.ID(), err)
+	if err != nil {
+		sendError(err, m.ID(), sched.errorBufferPool)
+		return nil, err
+	}
+	if resp == nil || resp.HTTPResp() == nil {
+		errMsg := fmt.Sprintf("nil response for robots.txt (MID: %s, URL: %s)",
+			m.ID(), robotsURL)
+		return nil, genError(errMsg)
+	}
+	return resp.HTTPResp(), nil
+}
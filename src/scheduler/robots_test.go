@@ -0,0 +1,268 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"module"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc9309Robots 代表RFC 9309第5.1节中的示例。
+const rfc9309Robots = `User-Agent: *
+Disallow: *.gif$
+Disallow: /example/
+Allow: /publications/
+
+User-Agent: foobot
+Disallow:/
+Allow:/example/page.html
+Allow:/example/allowed.gif
+
+User-Agent: barbot
+User-Agent: bazbot
+Disallow: /example/page.html
+
+User-Agent: quxbot
+
+EOF`
+
+func TestParseRobots(t *testing.T) {
+	cases := []struct {
+		userAgent string
+		path      string
+		allowed   bool
+	}{
+		{"otherbot", "/", true},
+		{"otherbot", "/a.gif", false},
+		{"otherbot", "/a.gif?x=1", true},
+		{"otherbot", "/example/page.html", false},
+		{"otherbot", "/publications/a.gif", true},
+		{"otherbot", "/publications/a.html", true},
+		{"FooBot/1.0", "/", false},
+		{"FooBot/1.0", "/example/page.html", true},
+		{"FooBot/1.0", "/example/allowed.gif", true},
+		{"FooBot/1.0", "/example/other.html", false},
+		{"FooBot/1.0", "/robots.txt", true},
+		{"barbot", "/example/page.html", false},
+		{"barbot", "/example/a.gif", true},
+		{"bazbot", "/example/page.html", false},
+		{"quxbot", "/example/page.html", true},
+		{"quxbot", "/a.gif", true},
+	}
+	for _, c := range cases {
+		rules := parseRobots(strings.NewReader(rfc9309Robots), c.userAgent)
+		if actual := rules.allowed(c.path); actual != c.allowed {
+			t.Fatalf("Inconsistent result for %q and %s: expected: %v, actual: %v",
+				c.path, c.userAgent, c.allowed, actual)
+		}
+	}
+}
+
+func TestParseRobotsLongestMatch(t *testing.T) {
+	// 前两条规则取自RFC 9309第5.2节中的示例。
+	content := `User-Agent: foobot
+Allow: /example/page/
+Disallow: /example/page/disallowed.gif
+Allow: /same
+Disallow: /same
+Disallow: /*.php$
+Allow: /public/*.php$
+`
+	cases := map[string]bool{
+		"/example/page/":                 true,
+		"/example/page/a.html":           true,
+		"/example/page/disallowed.gif":   false,
+		"/example/page/disallowed.gif2":  false,
+		"/same":                          true,
+		"/index.php":                     false,
+		"/index.php?x=1":                 true,
+		"/public/index.php":              true,
+		"/public/index.php5":             true,
+		"/private/public/index.php":      false,
+		"/nothing/matches/this/path.txt": true,
+	}
+	rules := parseRobots(strings.NewReader(content), "foobot")
+	for path, expected := range cases {
+		if actual := rules.allowed(path); actual != expected {
+			t.Fatalf("Inconsistent result for %q: expected: %v, actual: %v",
+				path, expected, actual)
+		}
+	}
+}
+
+func TestParseRobotsCrawlDelay(t *testing.T) {
+	content := `# comment
+User-agent: a
+user-agent: b # comment
+crawl-delay: 2.5
+Sitemap: http://example.com/sitemap.xml
+Disallow: /x
+
+User-agent: *
+Crawl-delay: not-a-number
+`
+	cases := map[string]time.Duration{
+		"a": 2500 * time.Millisecond,
+		"b": 2500 * time.Millisecond,
+		"c": 0,
+	}
+	for userAgent, expected := range cases {
+		rules := parseRobots(strings.NewReader(content), userAgent)
+		if rules.crawlDelay != expected {
+			t.Fatalf("Inconsistent crawl delay for %s: expected: %s, actual: %s",
+				userAgent, expected, rules.crawlDelay)
+		}
+	}
+	// 两个用户代理属于同一组记录。
+	if parseRobots(strings.NewReader(content), "b").allowed("/x") {
+		t.Fatal("The path /x is allowed for b, but should not be the case!")
+	}
+}
+
+func TestCompileRobotsPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/fish", "/fish", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish.asp", false},
+		{"/fish*", "/fishheads/yummy.html", true},
+		{"/fish/", "/fish", false},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php", "/windows.PHP", false},
+		{"/*.php$", "/filename.php", true},
+		{"/*.php$", "/filename.php?parameters", false},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+		{"/fish*.php", "/Fish.PHP", false},
+		{"/a.b+c", "/a.b+c", true},
+		{"/a.b+c", "/axb+c", false},
+	}
+	for _, c := range cases {
+		re := compileRobotsPattern(c.pattern)
+		if actual := re.MatchString(c.path); actual != c.matched {
+			t.Fatalf("Inconsistent match of %q against %q: expected: %v, actual: %v",
+				c.pattern, c.path, c.matched, actual)
+		}
+	}
+}
+
+func TestRobotsCacheLoad(t *testing.T) {
+	newResp := func(statusCode int, body string) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}
+	}
+	cases := []struct {
+		name      string
+		resp      *http.Response
+		err       error
+		allowed   bool
+		temporary bool
+		ttl       time.Duration
+	}{
+		{"disallowed", newResp(200, "User-agent: *\nDisallow: /a\n"), nil, false, false, time.Hour},
+		{"allowed", newResp(200, "User-agent: *\nDisallow: /b\n"), nil, true, false, time.Hour},
+		{"not found", newResp(404, ""), nil, true, false, time.Hour},
+		{"unavailable", newResp(503, ""), nil, false, true, robotsErrorTTL},
+		{"unreachable", nil, errors.New("connection refused"), false, true, robotsErrorTTL},
+	}
+	httpReq, _ := http.NewRequest(http.MethodGet, "http://example.com/a", nil)
+	for _, c := range cases {
+		rc := newRobotsCache("foobot", time.Hour,
+			func(ctx context.Context, robotsURL string) (*http.Response, error) {
+				if robotsURL != "http://example.com/robots.txt" {
+					t.Fatalf("Inconsistent robots.txt URL: %s", robotsURL)
+				}
+				return c.resp, c.err
+			})
+		allowed, temporary := rc.allowed(context.Background(), httpReq)
+		if allowed != c.allowed || temporary != c.temporary {
+			t.Fatalf("Inconsistent result for the %s case: expected: %v/%v, actual: %v/%v",
+				c.name, c.allowed, c.temporary, allowed, temporary)
+		}
+		var blocked, deferred uint64
+		if !c.allowed && c.temporary {
+			deferred = 1
+		} else if !c.allowed {
+			blocked = 1
+		}
+		if number := rc.blockedNumber(); number != blocked {
+			t.Fatalf("Inconsistent blocked number for the %s case: expected: %d, actual: %d",
+				c.name, blocked, number)
+		}
+		if number := rc.deferredNumber(); number != deferred {
+			t.Fatalf("Inconsistent deferred number for the %s case: expected: %d, actual: %d",
+				c.name, deferred, number)
+		}
+		entry := rc.entries["http://example.com"]
+		if ttl := time.Until(entry.expire); ttl > c.ttl || ttl < c.ttl-time.Minute/2 {
+			t.Fatalf("Inconsistent TTL for the %s case: expected: %s, actual: %s",
+				c.name, c.ttl, ttl)
+		}
+	}
+}
+
+func TestRobotsCacheSweep(t *testing.T) {
+	rc := newRobotsCache("foobot", time.Hour,
+		func(ctx context.Context, robotsURL string) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		})
+	for _, host := range []string{"a.com", "b.com", "c.com"} {
+		rc.rules(context.Background(), "http", host)
+	}
+	now := time.Now()
+	rc.lock.Lock()
+	rc.entries["http://a.com"].expire = now.Add(-time.Second)
+	rc.entries["http://b.com"].expire = now.Add(-time.Second)
+	// 正在获取规则的条目不应被清除。
+	rc.entries["http://d.com"] = &robotsEntry{ready: make(chan struct{})}
+	rc.lastSweep = now.Add(-robotsSweepInterval)
+	rc.lock.Unlock()
+	rc.rules(context.Background(), "http", "c.com")
+	var keys []string
+	for key := range rc.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	expectedKeys := []string{"http://c.com", "http://d.com"}
+	if !sameStrings(keys, expectedKeys) {
+		t.Fatalf("Inconsistent cached hosts: expected: %v, actual: %v",
+			expectedKeys, keys)
+	}
+}
+
+// nilDownloader 代表总是返回nil响应且不返回错误的下载器。
+type nilDownloader struct {
+	module.Downloader
+}
+
+func (d nilDownloader) Download(
+	ctx context.Context, req *module.Request) (*module.Response, error) {
+	return nil, nil
+}
+
+func TestFetchRobotsNilResponse(t *testing.T) {
+	registrar := module.NewRegistrar()
+	if _, err := registrar.Register(nilDownloader{newTestDownloader(t, 1)}); err != nil {
+		t.Fatalf("An error occurs when registering downloader: %s", err)
+	}
+	sched := &myScheduler{
+		registrar:   registrar,
+		requestArgs: RequestArgs{RobotsUserAgent: "foobot"},
+	}
+	httpResp, err := sched.fetchRobots(context.Background(), "http://a.com/robots.txt")
+	if err == nil {
+		t.Fatal("No error when the downloader returns a nil response!")
+	}
+	if httpResp != nil {
+		t.Fatalf("Inconsistent HTTP response: expected: %v, actual: %v", nil, httpResp)
+	}
+}
@@ -1,38 +1,110 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"module"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 	"toolkit/buffer"
+	"toolkit/canonicalizer"
 	"toolkit/cmap"
+	"toolkit/linkgraph"
 )
 
 type Scheduler interface {
 	Init(requestArgs RequestArgs, dataArgs DataArgs, moduleArgs ModuleArgs) (err error)
-	Start(firstHTTPReq *http.Request) (err error)
+	Start(firstHTTPReqs ...*http.Request) (err error)
 	Stop() (err error)
+	StopGracefully(ctx context.Context) (report StopReport, err error)
+	Pause() (err error)
+	Resume() (err error)
+	Enqueue(reqs ...*http.Request) (accepted int, err error)
+	// EnqueueRequests 会把给定的请求放入正在运行的调度器，
+	// 并保留它们的深度等元数据。其余的行为与Enqueue方法相同。
+	EnqueueRequests(reqs ...*module.Request) (accepted int, err error)
+	Checkpoint(w io.Writer) (err error)
+	ResumeFrom(r io.Reader) (err error)
 	Status() Status
 	ErrorChan() <-chan error
 	Idle() bool
+	Done() <-chan struct{}
+	Wait(ctx context.Context) error
+	// AddModule 会在调度器运行期间注册给定的组件。
+	AddModule(m module.Module) error
+	// RemoveModule 会在调度器运行期间注销给定ID的组件。
+	// 参数drain为true时会等待该组件正在进行的处理完成。
+	RemoveModule(mid module.MID, drain bool) error
 	Summary() SchedSummary
 }
 
 type myScheduler struct {
+	// requestArgs 代表请求相关的参数。
+	requestArgs       RequestArgs
 	maxDepth          uint32
 	acceptedDomainMap cmap.ConcurrentMap
 	registrar         module.Registrar
-	reqBufferPool     buffer.Pool
-	respBufferPool    buffer.Pool
-	itemBufferPool    buffer.Pool
-	errorBufferPool   buffer.Pool
-	// urlMap 代表已处理的URL的字典。
-	urlMap cmap.ConcurrentMap
+	// moduleLock 代表用于串行化组件增减的互斥锁。
+	moduleLock    sync.Mutex
+	reqBufferPool buffer.Pool
+	// reqFrontierArgs 代表请求前沿相关的参数。
+	reqFrontierArgs FrontierArgs
+	// reqSpillDir 代表请求缓冲池存放溢出请求的目录。
+	reqSpillDir     string
+	respBufferPool  buffer.Pool
+	itemBufferPool  buffer.Pool
+	errorBufferPool buffer.Pool
+	// canonicalizer 代表URL规范化器。
+	canonicalizer canonicalizer.Canonicalizer
+	// visitedURLs 代表已处理的URL的集合。其中的URL都已被规范化。
+	visitedURLs visitedSet
+	// pendingReqMap 代表已放入请求缓冲池但尚未下载完毕的请求的字典。
+	// 磁盘请求缓冲池中的请求只由缓冲池保存，在被取出之前不在此字典中。
+	pendingReqMap cmap.ConcurrentMap
+	// reqPoolLock 用于保证磁盘请求缓冲池被关闭时其中的请求都会被记录为待处理的请求。
+	reqPoolLock sync.RWMutex
+	// discardedReqMap 代表因预算耗尽而未被下载的请求的字典。
+	// 它们不再算作待完成的工作，但仍会被保存在检查点中。
+	discardedReqMap cmap.ConcurrentMap
+	// work 代表待完成的工作的跟踪器。
+	work *workTracker
+	// politeness 代表按主机限制请求频率的礼貌层。
+	politeness *politeness
+	// deadLetters 代表死信存储。为nil时代表不存储死信。
+	deadLetters *deadLetterStore
+	// linkGraph 代表链接图存储。为nil时代表不记录链接图。
+	linkGraph linkgraph.Store
+	// budget 代表爬取预算的记账器。
+	budget *budget
+	// scope 代表URL范围规则的评估器。
+	scope *scope
+	// retrier 代表下载失败时的重试器。
+	retrier *retrier
+	// robots 代表robots.txt规则的缓存。为nil时代表不遵守robots.txt。
+	robots *robotsCache
+	// downloadWorkers 代表下载阶段的工作协程的计量器。
+	downloadWorkers *workerGauge
+	// analyzeWorkers 代表分析阶段的工作协程的计量器。
+	analyzeWorkers *workerGauge
+	// pickWorkers 代表条目处理阶段的工作协程的计量器。
+	pickWorkers *workerGauge
+	// pauseGate 代表用于暂停工作协程的闸门。
+	pauseGate pauseGate
+	// draining 代表调度器是否正在优雅地停止。1代表是。
+	draining uint32
+	// drainRejected 代表优雅停止期间未被放入请求缓冲池的新请求的数量。
+	drainRejected uint64
+	// healthCheckInterval 代表主动健康检查的间隔时间。0代表不执行主动健康检查。
+	healthCheckInterval time.Duration
 	// ctx 代表上下文，用于感知调度器的停止。
 	ctx context.Context
 	// cancelFunc 代表取消函数，用于停止调度器。
@@ -44,7 +116,7 @@ type myScheduler struct {
 
 // NewScheduler 会创建一个调度器实例。
 func NewScheduler() Scheduler {
-	return &myScheduler{}
+	return &myScheduler{work: newWorkTracker()}
 }
 
 func (sched *myScheduler) Init(
@@ -85,24 +157,77 @@ func (sched *myScheduler) Init(
 	log.Println("Module arguments are valid.")
 	// 初始化内部字段。
 	log.Println("Initialize scheduler’s fields...")
-	if sched.registrar == nil {
-		sched.registrar = module.NewRegistrar()
-	} else {
-		sched.registrar.Clear()
+	registrarArgs := module.RegistrarArgs{
+		Balancers:   moduleArgs.Balancers,
+		Breaker:     moduleArgs.Breaker,
+		HealthCheck: moduleArgs.HealthCheck,
+	}
+	if sched.registrar, err = module.NewRegistrarWithArgs(registrarArgs); err != nil {
+		return genErrorByError(err)
 	}
+	log.Printf("-- Balancers: downloader: %s, analyzer: %s, pipeline: %s\n",
+		sched.registrar.Balancer(module.TYPE_DOWNLOADER).Name(),
+		sched.registrar.Balancer(module.TYPE_ANALYZER).Name(),
+		sched.registrar.Balancer(module.TYPE_PIPELINE).Name())
+	log.Printf("-- Breaker: consecutive failures: %d, failure ratio: %v, "+
+		"health check interval: %s\n",
+		moduleArgs.Breaker.ConsecutiveFailures, moduleArgs.Breaker.FailureRatio,
+		moduleArgs.HealthCheckInterval)
+	sched.healthCheckInterval = moduleArgs.HealthCheckInterval
+	sched.requestArgs = requestArgs
 	sched.maxDepth = requestArgs.MaxDepth
 	log.Printf("-- Max depth: %d\n", sched.maxDepth)
 	sched.acceptedDomainMap, _ =
 		cmap.NewConcurrentMap(1, nil)
 	for _, domain := range requestArgs.AcceptedDomains {
-		sched.acceptedDomainMap.Put(domain, struct{}{})
+		sched.acceptedDomainMap.Put(normalizeDomain(domain), struct{}{})
 	}
 	log.Printf("-- Accepted primary domains: %v\n",
 		requestArgs.AcceptedDomains)
-	sched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
-	log.Printf("-- URL map: length: %d, concurrency: %d\n",
-		sched.urlMap.Len(), sched.urlMap.Concurrency())
-	sched.initBufferPool(dataArgs)
+	sched.canonicalizer = requestArgs.Canonicalizer
+	if sched.canonicalizer == nil {
+		sched.canonicalizer = canonicalizer.New(canonicalizer.Options{})
+	}
+	if sched.visitedURLs, err = newVisitedSet(dataArgs.Visited); err != nil {
+		return genErrorByError(err)
+	}
+	log.Printf("-- Visited URLs: mode: %s\n", sched.visitedURLs.summary().Mode)
+	sched.pendingReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	sched.discardedReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	sched.politeness = newPoliteness(requestArgs, sched.putReq)
+	sched.retrier = newRetrier(requestArgs.Retry, sched.putReq)
+	sched.budget = newBudget(requestArgs.Budget)
+	if sched.scope, err = newScope(requestArgs.Scope); err != nil {
+		return err
+	}
+	log.Printf("-- Scope: rules: %d, default action: %s\n",
+		len(sched.scope.rules), sched.scope.defaultAction)
+	log.Printf("-- Politeness: max concurrency per host: %d, min delay per host: %s, "+
+		"delay jitter: %s, by primary domain: %v\n",
+		requestArgs.MaxConcurrencyPerHost, requestArgs.MinDelayPerHost,
+		requestArgs.DelayJitter, requestArgs.PolitenessByPrimaryDomain)
+	sched.robots = newRobotsCache(
+		requestArgs.RobotsUserAgent, requestArgs.RobotsTTL, sched.fetchRobots)
+	if sched.robots != nil {
+		sched.politeness.crawlDelay = func(req *module.Request) time.Duration {
+			return sched.robots.crawlDelay(req.HTTPReq())
+		}
+		log.Printf("-- Robots.txt: user agent: %q, TTL: %s\n",
+			sched.robots.userAgent, sched.robots.ttl)
+	} else {
+		log.Println("-- Robots.txt: ignored")
+	}
+	if err = sched.initBufferPool(dataArgs); err != nil {
+		return err
+	}
+	if err = sched.initDeadLetterStore(dataArgs.DeadLetterPath); err != nil {
+		return err
+	}
+	sched.linkGraph = dataArgs.LinkGraph
+	if sched.linkGraph != nil {
+		log.Println("-- Link graph: recorded")
+	}
+	sched.initWorkers(dataArgs.Workers, moduleArgs)
 	sched.resetContext()
 	sched.summary =
 		newSchedSummary(requestArgs, dataArgs, moduleArgs, sched)
@@ -115,10 +240,14 @@ func (sched *myScheduler) Init(
 	return nil
 }
 
-func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
+// Start 会启动调度器。
+// 参数firstHTTPReqs代表作为种子的首次请求。
+// 每个首次请求的主域名都会被添加到可接受的主域名的字典。
+// 没有首次请求时，需要在启动后通过Enqueue或EnqueueRequests方法放入请求。
+func (sched *myScheduler) Start(firstHTTPReqs ...*http.Request) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
-			errMsg := fmt.Sprintf("Fatal scheduler error: %sched", p)
+			errMsg := fmt.Sprintf("Fatal scheduler error: %s", p)
 			log.Fatal(errMsg)
 			err = genError(errMsg)
 		}
@@ -142,33 +271,141 @@ func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
 		return
 	}
 	// 检查参数。
-	log.Println("Check first HTTP request...")
-	if firstHTTPReq == nil {
-		err = genParameterError("nil first HTTP request")
-		return
+	log.Println("Check first HTTP requests...")
+	for _, firstHTTPReq := range firstHTTPReqs {
+		if firstHTTPReq == nil {
+			err = genParameterError("nil first HTTP request")
+			return
+		}
 	}
-	log.Println("The first HTTP request is valid.")
+	log.Println("The first HTTP requests are valid.")
 	// 获得首次请求的主域名，并将其添加到可接受的主域名的字典。
-	log.Println("Get the primary domain...")
-	log.Printf("-- Host: %s\n", firstHTTPReq.Host)
-	var primaryDomain string
-	primaryDomain, err = getPrimaryDomain(firstHTTPReq.Host)
+	log.Println("Get the primary domains...")
+	for _, firstHTTPReq := range firstHTTPReqs {
+		log.Printf("-- Host: %s\n", getReqHost(firstHTTPReq))
+		var primaryDomain string
+		primaryDomain, err = getPrimaryDomain(getReqHost(firstHTTPReq))
+		if err != nil {
+			return
+		}
+		log.Printf("-- Primary domain: %s\n", primaryDomain)
+		sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
+	}
+	// 开始调度数据和组件。
+	if err = sched.startScheduling(); err != nil {
+		return
+	}
+	log.Println("Scheduler has been started.")
+
+	accepted, _ := sched.enqueueReqs(seedReqs(firstHTTPReqs), false)
+	log.Printf("-- Accepted first requests: %d/%d\n", accepted, len(firstHTTPReqs))
+	sched.work.arm()
+	return nil
+}
+
+// ResumeFrom 会从给定的检查点恢复爬取状态并启动调度器。
+// 检查点中的已处理URL、可接受的主域名、待处理请求和爬取预算的计数都会被还原，
+// 而请求相关的参数仍以初始化时给定的为准。
+// 待处理请求会被重新扣除预算，预算不足时它们会被忽略。
+func (sched *myScheduler) ResumeFrom(r io.Reader) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			errMsg := fmt.Sprintf("Fatal scheduler error: %s", p)
+			log.Fatal(errMsg)
+			err = genError(errMsg)
+		}
+	}()
+	log.Println("Resume scheduler...")
+	// 检查状态。
+	log.Println("Check status for resumption...")
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_STARTING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_STARTED
+		}
+		sched.statusLock.Unlock()
+	}()
 	if err != nil {
 		return
 	}
-	log.Println("-- Primary domain: %s", primaryDomain)
-	sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
+	// 检查参数。
+	log.Println("Read checkpoint...")
+	if r == nil {
+		err = genParameterError("nil checkpoint reader")
+		return
+	}
+	var cp *checkpoint
+	cp, err = readCheckpoint(r)
+	if err != nil {
+		err = genErrorByError(err)
+		return
+	}
+	if !cp.RequestArgs.Same(&sched.requestArgs) {
+		log.Printf("The request arguments in checkpoint are different from current ones. "+
+			"Use the current ones. (checkpoint: %+v, current: %+v)\n",
+			cp.RequestArgs, sched.requestArgs)
+	}
+	// 还原爬取状态。
+	for _, domain := range cp.AcceptedDomains {
+		sched.acceptedDomainMap.Put(normalizeDomain(domain), struct{}{})
+	}
+	log.Printf("-- Accepted primary domains: %v\n", cp.AcceptedDomains)
+	if err = sched.visitedURLs.restore(cp); err != nil {
+		return
+	}
+	log.Printf("-- Visited URLs: %d\n", sched.visitedURLs.len())
+	var pendingReqs []*module.Request
+	for _, rs := range cp.PendingRequests {
+		req, err := rs.toRequest()
+		if err != nil {
+			log.Printf("Ignore the pending request in checkpoint! %s (URL: %s)\n",
+				err, rs.URL)
+			continue
+		}
+		pendingReqs = append(pendingReqs, req)
+	}
+	log.Printf("-- Pending requests: %d\n", len(pendingReqs))
 	// 开始调度数据和组件。
-	if err = sched.checkBufferPoolForStart(); err != nil {
+	if err = sched.startScheduling(); err != nil {
 		return
 	}
-	sched.download()
-	sched.analyze()
-	sched.pick()
-	log.Println("Scheduler has been started.")
+	sched.budget.restore(cp)
+	log.Println("Scheduler has been resumed.")
+	for _, req := range pendingReqs {
+		pd, _ := getPrimaryDomain(getReqHost(req.HTTPReq()))
+		if reason := sched.budget.take(req, pd); reason != "" {
+			log.Printf("Ignore the pending request in checkpoint! %s\n", reason)
+			continue
+		}
+		sched.putReq(req)
+	}
+	sched.work.arm()
+	return nil
+}
 
-	firstReq := module.NewRequest(firstHTTPReq, 0)
-	sched.sendReq(firstReq)
+// Checkpoint 会把调度器的爬取状态写入给定的写入器。
+// 调度器在运行中时也可以生成检查点，
+// 此时正在下载的请求会被视为待处理的请求。
+func (sched *myScheduler) Checkpoint(w io.Writer) (err error) {
+	if w == nil {
+		return genParameterError("nil checkpoint writer")
+	}
+	status := sched.Status()
+	if status == SCHED_STATUS_UNINITIALIZED ||
+		status == SCHED_STATUS_INITIALIZING {
+		return genError("the scheduler has not yet been initialized!")
+	}
+	cp := sched.genCheckpoint()
+	if err = writeCheckpoint(w, cp); err != nil {
+		return genErrorByError(err)
+	}
+	log.Printf("Checkpoint has been written. (visited URLs: %d, pending requests: %d)\n",
+		len(cp.VisitedURLs), len(cp.PendingRequests))
 	return nil
 }
 
@@ -191,47 +428,154 @@ func (sched *myScheduler) Stop() (err error) {
 	if err != nil {
 		return
 	}
+	sched.shutdown()
+	log.Println("Scheduler has been stopped.")
+	return nil
+}
+
+// shutdown 会取消调度器的上下文并关闭所有的缓冲池。
+// 缓冲池中尚未处理的数据都会被丢弃。
+func (sched *myScheduler) shutdown() {
 	sched.cancelFunc()
-	sched.reqBufferPool.Close()
+	sched.pauseGate.open()
+	sched.closeReqBufferPool()
 	sched.respBufferPool.Close()
 	sched.itemBufferPool.Close()
 	sched.errorBufferPool.Close()
-	log.Println("Scheduler has been stopped.")
+	if sched.deadLetters != nil {
+		sched.deadLetters.close()
+	}
+	sched.work.finish()
+}
+
+// Pause 会暂停调度器。
+// 暂停后，各阶段的工作协程不再从缓冲池中取出数据，
+// 但正在处理的数据会被处理完毕。
+// 缓冲池中的数据以及各种计数都会被保留，
+// 被推迟的请求也仍会在条件满足时被放回请求缓冲池。
+func (sched *myScheduler) Pause() (err error) {
+	log.Println("Pause scheduler...")
+	// 检查状态。
+	log.Println("Check status for pause...")
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_PAUSING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_PAUSED
+		}
+		sched.statusLock.Unlock()
+	}()
+	if err != nil {
+		return
+	}
+	sched.pauseGate.close()
+	log.Println("Scheduler has been paused.")
+	return nil
+}
+
+// Resume 会让已暂停的调度器继续运行。
+func (sched *myScheduler) Resume() (err error) {
+	log.Println("Resume scheduler...")
+	// 检查状态。
+	log.Println("Check status for resumption...")
+	sched.statusLock.Lock()
+	defer sched.statusLock.Unlock()
+	if err = checkStatus(sched.status, SCHED_STATUS_STARTED, nil); err != nil {
+		return
+	}
+	sched.pauseGate.open()
+	sched.status = SCHED_STATUS_STARTED
+	log.Println("Scheduler has been resumed.")
 	return nil
 }
 
 // download 会从请求缓冲池取出请求并下载，
 // 然后把得到的响应放入响应缓冲池。
 func (sched *myScheduler) download() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
-			datum, err := sched.reqBufferPool.Get()
-			if err != nil {
-				log.Println("The request buffer pool was closed. Break request reception.")
-				break
-			}
-			req, ok := datum.(*module.Request)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect request type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
+	for i := uint32(0); i < sched.downloadWorkers.workerNumber(); i++ {
+		go sched.downloadWorker()
+	}
+}
+
+// downloadWorker 代表下载阶段的一个工作协程。
+func (sched *myScheduler) downloadWorker() {
+	for {
+		sched.pauseGate.wait(sched.ctx)
+		if sched.canceled() {
+			break
+		}
+		datum, err := sched.reqBufferPool.Get()
+		if err == buffer.ErrClosedBufferPool {
+			log.Println("The request buffer pool was closed. Break request reception.")
+			break
+		}
+		// 其他的错误（例如读取溢出到磁盘上的请求失败）不影响后续请求的下载。
+		if err != nil {
+			// 被丢弃的请求不在待处理的请求的字典中，只需结清它们所代表的工作。
+			if dropped, ok := err.(*buffer.DroppedDataError); ok {
+				sched.work.add(workRequest, -int64(dropped.Number))
 			}
-			sched.downloadOne(req)
+			errMsg := fmt.Sprintf("couldn't get request from buffer pool: %s", err)
+			sendError(errors.New(errMsg), "", sched.errorBufferPool)
+			continue
 		}
-	}()
+		req, ok := datum.(*module.Request)
+		if !ok {
+			errMsg := fmt.Sprintf("incorrect request type: %T", datum)
+			sendError(errors.New(errMsg), "", sched.errorBufferPool)
+		}
+		if req == nil || !req.Valid() {
+			continue
+		}
+		// 从磁盘请求缓冲池中取出的请求需要重新被记录为待处理的请求。
+		if sched.diskReqPool() != nil {
+			sched.pendingReqMap.Put(req.HTTPReq().URL.String(), req)
+		}
+		// 在阻塞于Get期间被暂停时，持有取出的请求直到恢复。
+		// 调度器停止时，该请求仍属于待处理的请求。
+		sched.pauseGate.wait(sched.ctx)
+		if sched.canceled() {
+			break
+		}
+		// 优雅停止时不再下载新的请求，但它们仍属于待处理的请求。
+		if sched.isDraining() {
+			continue
+		}
+		// 预算耗尽时不再下载尚未开始下载的请求，但它们仍会被保存在检查点中。
+		if sched.budget.halted() != "" {
+			sched.budget.discard()
+			sched.discardPendingReq(req)
+			continue
+		}
+		// 对同一主机的请求过于频繁时推迟下载。
+		if !sched.politeness.admit(req) {
+			continue
+		}
+		retrying := sched.downloadOne(req)
+		sched.politeness.release(req)
+		// 等待重试的请求仍属于待处理的请求。
+		if !retrying {
+			sched.removePendingReq(req)
+		}
+	}
 }
 
 // downloadOne 会根据给定的请求执行下载并把响应放入响应缓冲池。
-func (sched *myScheduler) downloadOne(req *module.Request) {
+// 结果值为true代表下载失败且已按照重试策略安排重试。
+func (sched *myScheduler) downloadOne(req *module.Request) (retrying bool) {
 	if req == nil {
 		return
 	}
 	if sched.canceled() {
 		return
 	}
-	m, err := sched.registrar.Get(module.TYPE_DOWNLOADER)
+	sched.downloadWorkers.incrBusy()
+	defer sched.downloadWorkers.decrBusy()
+	m, err := sched.registrar.GetByKey(module.TYPE_DOWNLOADER, getReqHost(req.HTTPReq()))
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a downloader: %s", err)
 		sendError(errors.New(errMsg), "", sched.errorBufferPool)
@@ -242,40 +586,139 @@ func (sched *myScheduler) downloadOne(req *module.Request) {
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect downloader type: %T (MID: %s)",
 			m, m.ID())
+		sched.registrar.Report(m.ID(), errors.New(errMsg))
 		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
 		sched.sendReq(req)
 		return
 	}
-	resp, err := downloader.Download(req)
-	if resp != nil {
-		sendResp(resp, sched.respBufferPool)
+	req.SetAttempt(req.Attempt() + 1)
+	ctx, cancel := sched.downloadContext()
+	resp, err := downloader.Download(ctx, req)
+	// 在下载时限之内读出响应体，以免响应在等待分析期间超时。
+	if resp != nil && resp.HTTPResp() != nil && resp.HTTPResp().Body != nil {
+		if bodyErr := sched.bufferBody(resp.HTTPResp()); bodyErr != nil {
+			resp = nil
+			if err == nil {
+				err = bodyErr
+			}
+		}
 	}
+	cancel()
+	sched.registrar.Report(m.ID(), err)
 	if err != nil {
 		sendError(err, m.ID(), sched.errorBufferPool)
+		if resp == nil {
+			if isRetryableError(err) && sched.retryReq(req, 0) {
+				return true
+			}
+			sched.sendReqDeadLetter(req, err, m.ID())
+			return
+		}
+	}
+	if resp == nil {
+		return
+	}
+	if resp.Request() == nil {
+		resp.SetRequest(req)
+	}
+	httpResp := resp.HTTPResp()
+	if sched.retrier.enabled() && httpResp != nil &&
+		sched.retrier.retryableStatus(httpResp.StatusCode) {
+		retryAfter := parseRetryAfter(httpResp.Header.Get("Retry-After"), time.Now())
+		if httpResp.Body != nil {
+			httpResp.Body.Close()
+		}
+		if sched.retryReq(req, retryAfter) {
+			return true
+		}
+		errMsg := fmt.Sprintf("abandoned the request after %d attempt(s) (status code: %d, URL: %s)",
+			req.Attempt(), httpResp.StatusCode, req.HTTPReq().URL)
+		sendError(genError(errMsg), m.ID(), sched.errorBufferPool)
+		sched.sendReqDeadLetter(req, genError(errMsg), m.ID())
+		return
+	}
+	sched.work.add(workResponse, 1)
+	if !putDatum(resp, sched.respBufferPool) {
+		sched.work.add(workResponse, -1)
+	}
+	return
+}
+
+// downloadContext 用于生成下载单个请求时使用的上下文。
+// 它会在调度器停止或者超过下载时限时被取消。
+func (sched *myScheduler) downloadContext() (context.Context, context.CancelFunc) {
+	if sched.requestArgs.DownloadTimeout > 0 {
+		return context.WithTimeout(sched.ctx, sched.requestArgs.DownloadTimeout)
+	}
+	return context.WithCancel(sched.ctx)
+}
+
+// bufferBody 会读出并关闭给定HTTP响应的响应体，
+// 然后把它替换为内容相同的内存中的响应体。读出的字节数会被计入预算。
+func (sched *myScheduler) bufferBody(httpResp *http.Response) error {
+	body := &countingBody{ReadCloser: httpResp.Body, budget: sched.budget}
+	content, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return err
 	}
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(content))
+	return nil
+}
+
+// retryReq 用于为下载失败的请求安排重试。
+// 结果值为true代表已安排重试。
+// 调度器正在优雅停止时，可重试的请求只会被保留为待处理的请求。
+func (sched *myScheduler) retryReq(req *module.Request, retryAfter time.Duration) bool {
+	if sched.canceled() {
+		return false
+	}
+	if sched.isDraining() && sched.retrier.enabled() {
+		log.Printf("Keep the request for the next run. (URL: %s)\n",
+			req.HTTPReq().URL)
+		return true
+	}
+	if !sched.retrier.retry(req, retryAfter) {
+		if sched.retrier.enabled() {
+			log.Printf("Abandon the request after %d attempt(s). (URL: %s)\n",
+				req.Attempt(), req.HTTPReq().URL)
+		}
+		return false
+	}
+	log.Printf("Retry the request after %d attempt(s). (URL: %s)\n",
+		req.Attempt(), req.HTTPReq().URL)
+	return true
 }
 
 // analyze 会从响应缓冲池取出响应并解析，
 // 然后把得到的条目或请求放入相应的缓冲池。
 func (sched *myScheduler) analyze() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
-			datum, err := sched.respBufferPool.Get()
-			if err != nil {
-				log.Println("The response buffer pool was closed. Break response reception.")
-				break
-			}
-			resp, ok := datum.(*module.Response)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect response type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
-			}
-			sched.analyzeOne(resp)
+	for i := uint32(0); i < sched.analyzeWorkers.workerNumber(); i++ {
+		go sched.analyzeWorker()
+	}
+}
+
+// analyzeWorker 代表分析阶段的一个工作协程。
+func (sched *myScheduler) analyzeWorker() {
+	for {
+		sched.pauseGate.wait(sched.ctx)
+		if sched.canceled() {
+			break
 		}
-	}()
+		datum, err := sched.respBufferPool.Get()
+		if err != nil {
+			log.Println("The response buffer pool was closed. Break response reception.")
+			break
+		}
+		// 在阻塞于Get期间被暂停时，持有取出的数据直到恢复。
+		sched.pauseGate.wait(sched.ctx)
+		resp, ok := datum.(*module.Response)
+		if !ok {
+			errMsg := fmt.Sprintf("incorrect response type: %T", datum)
+			sendError(errors.New(errMsg), "", sched.errorBufferPool)
+		}
+		sched.analyzeOne(resp)
+	}
 }
 
 // analyzeOne 会根据给定的响应执行解析并把结果放入相应的缓冲池。
@@ -283,25 +726,39 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 	if resp == nil {
 		return
 	}
+	defer sched.work.add(workResponse, -1)
 	if sched.canceled() {
 		return
 	}
-	m, err := sched.registrar.Get(module.TYPE_ANALYZER)
+	sched.analyzeWorkers.incrBusy()
+	defer sched.analyzeWorkers.decrBusy()
+	var host string
+	if httpResp := resp.HTTPResp(); httpResp != nil && httpResp.Request != nil {
+		host = getReqHost(httpResp.Request)
+	}
+	m, err := sched.registrar.GetByKey(module.TYPE_ANALYZER, host)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get an analyzer: %s", err)
 		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sendResp(resp, sched.respBufferPool)
+		if sendResp(resp, sched.respBufferPool) {
+			sched.work.add(workResponse, 1)
+		}
 		return
 	}
 	analyzer, ok := m.(module.Analyzer)
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect analyzer type: %T (MID: %s)",
 			m, m.ID())
+		sched.registrar.Report(m.ID(), errors.New(errMsg))
 		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sendResp(resp, sched.respBufferPool)
+		if sendResp(resp, sched.respBufferPool) {
+			sched.work.add(workResponse, 1)
+		}
 		return
 	}
-	dataList, errs := analyzer.Analyze(resp)
+	dataList, errs := analyzer.Analyze(sched.ctx, resp)
+	errs = nonNilErrors(errs)
+	sched.reportErrors(m.ID(), errs)
 	if dataList != nil {
 		for _, data := range dataList {
 			if data == nil {
@@ -309,9 +766,16 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 			}
 			switch d := data.(type) {
 			case *module.Request:
-				sched.sendReq(d)
+				reason := sched.sendReq(d)
+				sched.recordLink(resp, d, reason)
 			case module.Item:
-				sendItem(d, sched.itemBufferPool)
+				if d == nil {
+					continue
+				}
+				sched.work.add(workItem, 1)
+				if !putDatum(d, sched.itemBufferPool) {
+					sched.work.add(workItem, -1)
+				}
 			default:
 				errMsg := fmt.Sprintf("Unsupported data type %T! (data: %#v)", d, d)
 				sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
@@ -323,56 +787,83 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 			sendError(err, m.ID(), sched.errorBufferPool)
 		}
 	}
+	if len(errs) > 0 {
+		sched.sendRespDeadLetter(resp, errs, m.ID())
+	}
 }
 
 // pick 会从条目缓冲池取出条目并处理。
 func (sched *myScheduler) pick() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
-			datum, err := sched.itemBufferPool.Get()
-			if err != nil {
-				log.Println("The item buffer pool was closed. Break item reception.")
-				break
-			}
-			item, ok := datum.(module.Item)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect item type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
-			}
-			sched.pickOne(item)
+	for i := uint32(0); i < sched.pickWorkers.workerNumber(); i++ {
+		go sched.pickWorker()
+	}
+}
+
+// pickWorker 代表条目处理阶段的一个工作协程。
+func (sched *myScheduler) pickWorker() {
+	for {
+		sched.pauseGate.wait(sched.ctx)
+		if sched.canceled() {
+			break
 		}
-	}()
+		datum, err := sched.itemBufferPool.Get()
+		if err != nil {
+			log.Println("The item buffer pool was closed. Break item reception.")
+			break
+		}
+		// 在阻塞于Get期间被暂停时，持有取出的数据直到恢复。
+		sched.pauseGate.wait(sched.ctx)
+		item, ok := datum.(module.Item)
+		if !ok {
+			errMsg := fmt.Sprintf("incorrect item type: %T", datum)
+			sendError(errors.New(errMsg), "", sched.errorBufferPool)
+		}
+		sched.pickOne(item)
+	}
 }
 
 // pickOne 会处理给定的条目。
 func (sched *myScheduler) pickOne(item module.Item) {
+	// 缓冲池被关闭时可能会取出nil，它并不是待完成的工作。
+	if item == nil {
+		return
+	}
+	defer sched.work.add(workItem, -1)
 	if sched.canceled() {
 		return
 	}
+	sched.pickWorkers.incrBusy()
+	defer sched.pickWorkers.decrBusy()
 	m, err := sched.registrar.Get(module.TYPE_PIPELINE)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a pipeline pipline: %s", err)
 		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sendItem(item, sched.itemBufferPool)
+		if sendItem(item, sched.itemBufferPool) {
+			sched.work.add(workItem, 1)
+		}
 		return
 	}
 	pipeline, ok := m.(module.Pipeline)
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect pipeline type: %T (MID: %s)",
 			m, m.ID())
+		sched.registrar.Report(m.ID(), errors.New(errMsg))
 		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sendItem(item, sched.itemBufferPool)
+		if sendItem(item, sched.itemBufferPool) {
+			sched.work.add(workItem, 1)
+		}
 		return
 	}
-	errs := pipeline.Send(item)
+	errs := nonNilErrors(pipeline.Send(sched.ctx, item))
+	sched.reportErrors(m.ID(), errs)
 	if errs != nil {
 		for _, err := range errs {
 			sendError(err, m.ID(), sched.errorBufferPool)
 		}
 	}
+	if len(errs) > 0 && pipeline.FailFast() {
+		sched.sendItemDeadLetter(item, errs, m.ID())
+	}
 }
 
 // checkAndSetStatus 用于状态的检查，并在条件满足时设置状态。
@@ -390,54 +881,196 @@ func (sched *myScheduler) checkAndSetStatus(
 
 // sendReq 会向请求缓冲池发送请求。
 // 不符合要求的请求会被过滤掉。
-func (sched *myScheduler) sendReq(req *module.Request) bool {
+// 结果值为空字符串代表已发送，否则代表请求被过滤掉的原因。
+func (sched *myScheduler) sendReq(req *module.Request) (reason string) {
+	if reason = sched.filterReq(req); reason != "" {
+		log.Printf("Ignore the request! %s\n", reason)
+		return
+	}
+	// 优雅停止时不再放入新的请求，但它们仍属于待处理的请求，会被保存在检查点中。
+	if sched.isDraining() {
+		atomic.AddUint64(&sched.drainRejected, 1)
+		sched.addPendingReq(req)
+		return "The scheduler is being stopped!"
+	}
+	sched.putReq(req)
+	return ""
+}
+
+// recordLink 用于把从给定响应中发现的请求记录为链接图中的一条边。
+// 参数reason代表请求被过滤掉的原因，为空代表已被接受。
+func (sched *myScheduler) recordLink(
+	resp *module.Response, req *module.Request, reason string) {
+	if sched.linkGraph == nil || !req.Valid() {
+		return
+	}
+	source := req.ParentURL()
+	if source == "" && resp.HTTPResp() != nil && resp.HTTPResp().Request != nil {
+		source = resp.HTTPResp().Request.URL.String()
+	}
+	edge := linkgraph.Edge{
+		Source:     source,
+		Target:     req.HTTPReq().URL.String(),
+		AnchorText: req.AnchorText(),
+		Depth:      req.Depth(),
+		Accepted:   reason == "",
+		Reason:     reason,
+		Time:       time.Now(),
+	}
+	if err := sched.linkGraph.Add(edge); err != nil {
+		log.Printf("Couldn't record the link: %s (source: %s, target: %s)\n",
+			err, edge.Source, edge.Target)
+	}
+}
+
+// filterReq 用于检查给定的请求是否符合要求。
+// 结果值为空字符串代表符合要求，否则代表请求被过滤掉的原因。
+// 被成功获取的robots.txt禁止的请求的URL会被直接记为已处理。
+// 因暂时无法获取robots.txt而被过滤掉的请求的URL不会被记为已处理，
+// 以便之后再次发现它时可以重新检查。
+// 符合要求的请求的URL会在本方法中被原子地记为已处理，
+// 因此对于同一个URL的并发调用，最多只有一个调用会返回空字符串。
+func (sched *myScheduler) filterReq(req *module.Request) (reason string) {
 	if req == nil {
-		return false
+		return "The request is nil!"
 	}
 	if sched.canceled() {
-		return false
+		return "The scheduler has been stopped!"
 	}
 	httpReq := req.HTTPReq()
 	if httpReq == nil {
-		log.Printf("Ignore the request! Its HTTP request is invalid!")
-		return false
+		return "Its HTTP request is invalid!"
 	}
 	reqURL := httpReq.URL
 	if reqURL == nil {
-		log.Printf("Ignore the request! Its URL is invalid!")
-		return false
+		return "Its URL is invalid!"
 	}
 	scheme := strings.ToLower(reqURL.Scheme)
 	if scheme != "http" && scheme != "https" {
-		log.Printf("Ignore the request! Its URL scheme is %q, but should be %q or %q. (URL: %s)\n",
+		return fmt.Sprintf("Its URL scheme is %q, but should be %q or %q. (URL: %s)",
 			scheme, "http", "https", reqURL)
-		return false
 	}
-	if v := sched.urlMap.Get(reqURL.String()); v != nil {
-		log.Printf("Ignore the request! Its URL is repeated. (URL: %s)\n", reqURL)
-		return false
+	if sched.visitedURLs.contains(sched.urlKey(reqURL)) {
+		return fmt.Sprintf("Its URL is repeated. (URL: %s)", reqURL)
 	}
-	pd, _ := getPrimaryDomain(httpReq.Host)
+	host := getReqHost(httpReq)
+	pd, _ := getPrimaryDomain(host)
 	if sched.acceptedDomainMap.Get(pd) == nil {
 		if pd == "bing.net" {
 			panic(httpReq.URL)
 		}
-		log.Printf("Ignore the request! Its host %q is not in accepted primary domain map. (URL: %s)\n",
-			httpReq.Host, reqURL)
-		return false
+		return fmt.Sprintf("Its host %q is not in accepted primary domain map. (URL: %s)",
+			host, reqURL)
 	}
 	if req.Depth() > sched.maxDepth {
-		log.Printf("Ignore the request! Its depth %d is greater than %d. (URL: %s)\n",
+		return fmt.Sprintf("Its depth %d is greater than %d. (URL: %s)",
 			req.Depth(), sched.maxDepth, reqURL)
-		return false
 	}
+	if reason := sched.scope.check(req); reason != "" {
+		return reason
+	}
+	if sched.robots != nil {
+		if allowed, temporary := sched.robots.allowed(sched.ctx, httpReq); !allowed {
+			if temporary {
+				return fmt.Sprintf("Its robots.txt is temporarily unavailable. (URL: %s)", reqURL)
+			}
+			sched.visitedURLs.add(sched.urlKey(reqURL))
+			return fmt.Sprintf("It is disallowed by robots.txt. (URL: %s)", reqURL)
+		}
+	}
+	// 以记为已处理的结果作为去重的依据，以免并发发现的同一个URL被重复下载。
+	if !sched.visitedURLs.add(sched.urlKey(reqURL)) {
+		return fmt.Sprintf("Its URL is repeated. (URL: %s)", reqURL)
+	}
+	// 扣除预算必须是最后一项检查，以免为被过滤掉的请求扣除预算。
+	// 因预算不足而被过滤掉的请求的URL仍会被记为已处理。
+	if reason := sched.budget.take(req, pd); reason != "" {
+		return reason
+	}
+	return ""
+}
+
+// urlKey 用于获取给定URL在已处理URL的字典中的键，即其规范形式。
+func (sched *myScheduler) urlKey(u *url.URL) string {
+	return sched.canonicalizer.Canonicalize(u)
+}
+
+// putReq 会把请求放入请求缓冲池并记录为待处理的请求。
+// 本方法不会对请求进行过滤。
+func (sched *myScheduler) putReq(req *module.Request) {
+	if pool := sched.diskReqPool(); pool != nil {
+		sched.putDiskReq(pool, req)
+		return
+	}
+	sched.addPendingReq(req)
 	go func(req *module.Request) {
 		if err := sched.reqBufferPool.Put(req); err != nil {
 			log.Printf("The request buffer pool was closed. Ignore request sending.")
 		}
 	}(req)
-	sched.urlMap.Put(reqURL.String(), struct{}{})
-	return true
+}
+
+// putDiskReq 会把请求放入磁盘请求缓冲池。
+// 为了使内存占用不随请求的数量增长，缓冲池中的请求不在待处理的请求的字典中，
+// 但仍算作待完成的工作。
+// 磁盘缓冲池的Put方法不会阻塞，因此无需为每个请求启用goroutine。
+func (sched *myScheduler) putDiskReq(pool buffer.DiskPool, req *module.Request) {
+	sched.reqPoolLock.RLock()
+	defer sched.reqPoolLock.RUnlock()
+	key := req.HTTPReq().URL.String()
+	// 重新放入的请求已算作待完成的工作。
+	if !sched.pendingReqMap.Delete(key) {
+		sched.work.add(workRequest, 1)
+	}
+	if err := pool.Put(req); err != nil {
+		log.Printf("Couldn't put the request into the request buffer pool: %s", err)
+		sched.pendingReqMap.Put(key, req)
+	}
+}
+
+// diskReqPool 用于获取磁盘请求缓冲池。请求缓冲池不是磁盘缓冲池时返回nil。
+func (sched *myScheduler) diskReqPool() buffer.DiskPool {
+	pool, _ := sched.reqBufferPool.(buffer.DiskPool)
+	return pool
+}
+
+// closeReqBufferPool 用于关闭请求缓冲池。
+// 磁盘请求缓冲池中的请求会先被记录为待处理的请求，以便它们仍会被保存在检查点中。
+func (sched *myScheduler) closeReqBufferPool() {
+	if pool := sched.diskReqPool(); pool != nil {
+		sched.reqPoolLock.Lock()
+		defer sched.reqPoolLock.Unlock()
+		err := pool.Range(func(datum interface{}) bool {
+			if req, ok := datum.(*module.Request); ok && req.Valid() {
+				sched.pendingReqMap.Put(req.HTTPReq().URL.String(), req)
+			}
+			return true
+		})
+		if err != nil && err != buffer.ErrClosedBufferPool {
+			log.Printf("Couldn't read the requests in the request buffer pool: %s\n", err)
+		}
+	}
+	sched.reqBufferPool.Close()
+}
+
+// addPendingReq 会把给定的请求记录为待处理的请求。
+func (sched *myScheduler) addPendingReq(req *module.Request) {
+	if added, _ := sched.pendingReqMap.Put(req.HTTPReq().URL.String(), req); added {
+		sched.work.add(workRequest, 1)
+	}
+}
+
+// removePendingReq 会删除给定的待处理的请求。
+func (sched *myScheduler) removePendingReq(req *module.Request) {
+	if sched.pendingReqMap.Delete(req.HTTPReq().URL.String()) {
+		sched.work.add(workRequest, -1)
+	}
+}
+
+// discardPendingReq 会把给定的待处理的请求移入因预算耗尽而未被下载的请求的字典。
+func (sched *myScheduler) discardPendingReq(req *module.Request) {
+	sched.discardedReqMap.Put(req.HTTPReq().URL.String(), req)
+	sched.removePendingReq(req)
 }
 
 // sendResp 会向响应缓冲池发送响应。
@@ -506,12 +1139,26 @@ func (sched *myScheduler) ErrorChan() <-chan error {
 }
 
 func (sched *myScheduler) Idle() bool {
+	if sched.work.totalNumber() > 0 {
+		return false
+	}
 	moduleMap := sched.registrar.GetAll()
 	for _, module := range moduleMap {
 		if module.HandlingNumber() > 0 {
 			return false
 		}
 	}
+	if sched.politeness.deferredNumber() > 0 {
+		return false
+	}
+	if sched.retrier.waitingNumber() > 0 {
+		return false
+	}
+	if sched.downloadWorkers.busyNumber() > 0 ||
+		sched.analyzeWorkers.busyNumber() > 0 ||
+		sched.pickWorkers.busyNumber() > 0 {
+		return false
+	}
 	if sched.reqBufferPool.Total() > 0 ||
 		sched.respBufferPool.Total() > 0 ||
 		sched.itemBufferPool.Total() > 0 {
@@ -536,15 +1183,24 @@ func (sched *myScheduler) canceled() bool {
 
 // initBufferPool 用于按照给定的参数初始化缓冲池。
 // 如果某个缓冲池可用且未关闭，就先关闭该缓冲池。
-func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
+func (sched *myScheduler) initBufferPool(dataArgs DataArgs) error {
 	// 初始化请求缓冲池。
 	if sched.reqBufferPool != nil && !sched.reqBufferPool.Closed() {
 		sched.reqBufferPool.Close()
 	}
-	sched.reqBufferPool, _ = buffer.NewPool(
+	sched.reqFrontierArgs = dataArgs.ReqFrontier
+	sched.reqSpillDir = dataArgs.ReqSpillDir
+	var err error
+	sched.reqBufferPool, err = sched.newReqBufferPool(
 		dataArgs.ReqBufferCap, dataArgs.ReqMaxBufferNumber)
-	log.Printf("-- Request buffer pool: bufferCap: %d, maxBufferNumber: %d",
-		sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
+	if err != nil {
+		return genErrorByError(err)
+	}
+	log.Printf("-- Request buffer pool: bufferCap: %d, maxBufferNumber: %d, "+
+		"priority: %v, hostFairness: %v, spillDir: %q",
+		sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber(),
+		sched.reqFrontierArgs.Priority != nil, sched.reqFrontierArgs.HostFairness,
+		sched.reqSpillDir)
 	// 初始化响应缓冲池。
 	if sched.respBufferPool != nil && !sched.respBufferPool.Closed() {
 		sched.respBufferPool.Close()
@@ -569,6 +1225,37 @@ func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
 		dataArgs.ErrorBufferCap, dataArgs.ErrorMaxBufferNumber)
 	log.Printf("-- Error buffer pool: bufferCap: %d, maxBufferNumber: %d",
 		sched.errorBufferPool.BufferCap(), sched.errorBufferPool.MaxBufferNumber())
+	return nil
+}
+
+// initWorkers 用于按照给定的参数初始化各阶段的工作协程计量器。
+func (sched *myScheduler) initWorkers(workerArgs WorkerArgs, moduleArgs ModuleArgs) {
+	moduleArgsSummary := moduleArgs.Summary()
+	sched.downloadWorkers = newWorkerGauge(
+		workerArgs.DownloaderNumber, moduleArgsSummary.DownloaderListSize)
+	sched.analyzeWorkers = newWorkerGauge(
+		workerArgs.AnalyzerNumber, moduleArgsSummary.AnalyzerListSize)
+	sched.pickWorkers = newWorkerGauge(
+		workerArgs.PipelineNumber, moduleArgsSummary.PipelineListSize)
+	log.Printf("-- Workers: download: %d, analyze: %d, pick: %d",
+		sched.downloadWorkers.workerNumber(), sched.analyzeWorkers.workerNumber(),
+		sched.pickWorkers.workerNumber())
+}
+
+// newReqBufferPool 用于创建请求缓冲池。
+// 若参数中指定了请求优先级的计算函数，就创建优先级前沿；
+// 若参数中指定了溢出目录，就创建磁盘缓冲池。
+func (sched *myScheduler) newReqBufferPool(
+	bufferCap uint32, maxBufferNumber uint32) (buffer.Pool, error) {
+	if sched.reqFrontierArgs.Priority != nil {
+		return NewPriorityFrontier(bufferCap, maxBufferNumber,
+			sched.reqFrontierArgs.Priority, sched.reqFrontierArgs.HostFairness)
+	}
+	if sched.reqSpillDir != "" {
+		return buffer.NewDiskPool(bufferCap, maxBufferNumber,
+			sched.reqSpillDir, requestCodec{})
+	}
+	return buffer.NewPool(bufferCap, maxBufferNumber)
 }
 
 // checkBufferPoolForStart 会检查缓冲池是否已为调度器的启动准备就绪。
@@ -580,8 +1267,12 @@ func (sched *myScheduler) checkBufferPoolForStart() error {
 		return genError("nil request buffer pool")
 	}
 	if sched.reqBufferPool != nil && sched.reqBufferPool.Closed() {
-		sched.reqBufferPool, _ = buffer.NewPool(
+		reqBufferPool, err := sched.newReqBufferPool(
 			sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
+		if err != nil {
+			return genErrorByError(err)
+		}
+		sched.reqBufferPool = reqBufferPool
 	}
 	// 检查响应缓冲池。
 	if sched.respBufferPool == nil {
@@ -610,6 +1301,26 @@ func (sched *myScheduler) checkBufferPoolForStart() error {
 	return nil
 }
 
+// startScheduling 会检查缓冲池并开始调度数据和组件。
+func (sched *myScheduler) startScheduling() error {
+	if err := sched.checkBufferPoolForStart(); err != nil {
+		return err
+	}
+	sched.pauseGate.open()
+	// 上次运行遗留的待处理请求已无法被下载。
+	sched.pendingReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	sched.discardedReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	sched.work.reset()
+	atomic.StoreUint32(&sched.draining, 0)
+	atomic.StoreUint64(&sched.drainRejected, 0)
+	sched.budget.start()
+	sched.checkHealth(sched.healthCheckInterval)
+	sched.download()
+	sched.analyze()
+	sched.pick()
+	return nil
+}
+
 // resetContext 用于重置调度器的上下文。
 func (sched *myScheduler) resetContext() {
 	sched.ctx, sched.cancelFunc = context.WithCancel(context.Background())
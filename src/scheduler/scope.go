@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"fmt"
+	"module"
+	"regexp"
+	"strings"
+)
+
+// ScopeAction 代表范围规则的动作。
+type ScopeAction string
+
+const (
+	// SCOPE_ACTION_ALLOW 代表允许匹配的请求。
+	SCOPE_ACTION_ALLOW ScopeAction = "allow"
+	// SCOPE_ACTION_DENY 代表拒绝匹配的请求。
+	SCOPE_ACTION_DENY ScopeAction = "deny"
+)
+
+// ScopeRule 代表URL范围规则。
+// 主机名、路径和查询字符串的模式都为空的规则会匹配任何请求。
+// 通配符模式中的"*"匹配任意多个字符（包括"/"），"?"匹配单个字符，
+// 例如，"/docs/*"可以表示路径前缀"/docs/"。
+type ScopeRule struct {
+	// Name 代表规则的名称，仅用于记录。
+	Name   string      `json:"name,omitempty"`
+	Action ScopeAction `json:"action"`
+	// Host 代表主机名的模式。为空代表匹配任何主机名。
+	Host string `json:"host,omitempty"`
+	// Path 代表路径的模式。为空代表匹配任何路径。
+	Path string `json:"path,omitempty"`
+	// Query 代表查询字符串（不含"?"）的模式。为空代表匹配任何查询字符串。
+	Query string `json:"query,omitempty"`
+	// Regexp 代表模式是否为正则表达式。否则为通配符模式。
+	// 正则表达式需要完整匹配，而非部分匹配。
+	Regexp bool `json:"regexp,omitempty"`
+	// ExactHost 代表主机名的模式是否只匹配主机名本身。
+	// 否则，主机名的模式与主机名或其在主域名范围内的任何上级域名匹配即可，
+	// 例如，"example.com"会匹配"www.example.com"。
+	ExactHost bool `json:"exact_host,omitempty"`
+	// MaxDepth 代表允许规则所允许的最大深度。0代表不额外限制。
+	MaxDepth uint32 `json:"max_depth,omitempty"`
+}
+
+// ScopeArgs 代表URL范围相关的参数。
+// 规则会被依次评估，由第一条匹配的规则决定是否允许请求。
+type ScopeArgs struct {
+	Rules []ScopeRule `json:"rules,omitempty"`
+	// DefaultAction 代表没有规则匹配时的动作。为空代表允许。
+	DefaultAction ScopeAction `json:"default_action,omitempty"`
+}
+
+// Check 用于检查范围参数的有效性。
+func (args *ScopeArgs) Check() error {
+	_, err := newScope(*args)
+	return err
+}
+
+// Same 用于判断当前的范围参数与另一份是否相同。
+func (args *ScopeArgs) Same(another *ScopeArgs) bool {
+	if another == nil {
+		return false
+	}
+	if another.DefaultAction != args.DefaultAction {
+		return false
+	}
+	if len(another.Rules) != len(args.Rules) {
+		return false
+	}
+	for i, rule := range another.Rules {
+		if rule != args.Rules[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scopeRule 代表已编译的范围规则。
+type scopeRule struct {
+	ScopeRule
+	// index 代表规则在参数中的索引。
+	index int
+	host  *regexp.Regexp
+	path  *regexp.Regexp
+	query *regexp.Regexp
+}
+
+// String 会返回用于记录的规则描述。
+func (rule *scopeRule) String() string {
+	if rule.Name != "" {
+		return fmt.Sprintf("scope rule #%d %q (%s)", rule.index, rule.Name, rule.Action)
+	}
+	return fmt.Sprintf("scope rule #%d (%s)", rule.index, rule.Action)
+}
+
+// scope 代表URL范围规则的评估器。
+type scope struct {
+	rules         []*scopeRule
+	defaultAction ScopeAction
+}
+
+// newScope 会根据给定的参数创建一个范围规则评估器。
+func newScope(args ScopeArgs) (*scope, error) {
+	s := &scope{defaultAction: args.DefaultAction}
+	switch s.defaultAction {
+	case "":
+		s.defaultAction = SCOPE_ACTION_ALLOW
+	case SCOPE_ACTION_ALLOW, SCOPE_ACTION_DENY:
+	default:
+		return nil, genError(fmt.Sprintf("illegal default scope action: %q", args.DefaultAction))
+	}
+	for i, rule := range args.Rules {
+		if rule.Action != SCOPE_ACTION_ALLOW && rule.Action != SCOPE_ACTION_DENY {
+			return nil, genError(fmt.Sprintf("illegal action of scope rule #%d: %q", i, rule.Action))
+		}
+		compiled := &scopeRule{ScopeRule: rule, index: i}
+		var err error
+		if compiled.host, err = compileScopePattern(rule.Host, rule.Regexp); err == nil {
+			if compiled.path, err = compileScopePattern(rule.Path, rule.Regexp); err == nil {
+				compiled.query, err = compileScopePattern(rule.Query, rule.Regexp)
+			}
+		}
+		if err != nil {
+			return nil, genError(fmt.Sprintf("illegal pattern of scope rule #%d: %s", i, err))
+		}
+		s.rules = append(s.rules, compiled)
+	}
+	return s, nil
+}
+
+// compileScopePattern 用于把给定的模式编译为完整匹配的正则表达式。
+// 空模式的结果为nil，代表匹配任何值。
+func compileScopePattern(pattern string, isRegexp bool) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if isRegexp {
+		return regexp.Compile("^(?:" + pattern + ")$")
+	}
+	var buffer strings.Builder
+	buffer.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			buffer.WriteString(".*")
+		case '?':
+			buffer.WriteString(".")
+		default:
+			buffer.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buffer.WriteString("$")
+	return regexp.Compile(buffer.String())
+}
+
+// matchHost 用于判断给定的主机名是否与规则的主机名模式匹配。
+func (rule *scopeRule) matchHost(host string) bool {
+	if rule.host == nil {
+		return true
+	}
+	hostname := strings.ToLower(splitHostname(host))
+	if rule.host.MatchString(hostname) {
+		return true
+	}
+	if rule.ExactHost {
+		return false
+	}
+	pd, err := getPrimaryDomain(hostname)
+	if err != nil {
+		return false
+	}
+	// 依次尝试各级上级域名，直到主域名为止。
+	for hostname != pd {
+		index := strings.Index(hostname, ".")
+		if index < 0 {
+			break
+		}
+		hostname = hostname[index+1:]
+		if rule.host.MatchString(hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// match 用于判断给定的请求是否与规则匹配。
+func (rule *scopeRule) match(req *module.Request) bool {
+	httpReq := req.HTTPReq()
+	if !rule.matchHost(getReqHost(httpReq)) {
+		return false
+	}
+	if rule.path != nil {
+		path := httpReq.URL.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		if !rule.path.MatchString(path) {
+			return false
+		}
+	}
+	if rule.query != nil && !rule.query.MatchString(httpReq.URL.RawQuery) {
+		return false
+	}
+	return true
+}
+
+// check 用于检查给定的请求是否在范围之内。
+// 结果值为空字符串代表在范围之内，否则代表被拒绝的原因，其中包含匹配的规则。
+func (s *scope) check(req *module.Request) (reason string) {
+	if s == nil {
+		return ""
+	}
+	reqURL := req.HTTPReq().URL
+	for _, rule := range s.rules {
+		if !rule.match(req) {
+			continue
+		}
+		if rule.Action == SCOPE_ACTION_DENY {
+			return fmt.Sprintf("It is denied by %s. (URL: %s)", rule, reqURL)
+		}
+		if rule.MaxDepth > 0 && req.Depth() > rule.MaxDepth {
+			return fmt.Sprintf("Its depth %d is greater than %d of %s. (URL: %s)",
+				req.Depth(), rule.MaxDepth, rule, reqURL)
+		}
+		return ""
+	}
+	if s.defaultAction == SCOPE_ACTION_DENY {
+		return fmt.Sprintf("It matches no scope rule and is denied by default. (URL: %s)", reqURL)
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeCheck(t *testing.T) {
+	s, err := newScope(ScopeArgs{
+		Rules: []ScopeRule{
+			{Name: "private", Action: SCOPE_ACTION_DENY, Path: "/private/*"},
+			{Action: SCOPE_ACTION_ALLOW, Host: "example.com", MaxDepth: 2},
+			{Action: SCOPE_ACTION_ALLOW, Host: "cdn.other.org", ExactHost: true},
+			{Action: SCOPE_ACTION_ALLOW, Path: "/a/[0-9]+", Query: "id=.*", Regexp: true},
+			{Action: SCOPE_ACTION_ALLOW, Host: "w?.net", Path: "/docs/*"},
+		},
+		DefaultAction: SCOPE_ACTION_DENY,
+	})
+	if err != nil {
+		t.Fatalf("An error occurs when creating scope: %s", err)
+	}
+	cases := []struct {
+		url   string
+		depth uint32
+		// reason 代表被拒绝的原因中应包含的内容。为空代表应被允许。
+		reason string
+	}{
+		{"http://example.com/", 0, ""},
+		{"http://www.example.com/page", 2, ""},
+		{"http://WWW.Example.COM:8080/page", 1, ""},
+		// 规则按顺序评估，第一条匹配的规则生效。
+		{"http://www.example.com/private/x", 0, `scope rule #0 "private" (deny)`},
+		{"http://www.example.com/page", 3, "greater than 2 of scope rule #1 (allow)"},
+		{"http://cdn.other.org/x", 5, ""},
+		{"http://img.cdn.other.org/x", 0, "denied by default"},
+		{"http://foo.net/a/12?id=3", 0, ""},
+		{"http://foo.net/a/12x?id=3", 0, "denied by default"},
+		{"http://foo.net/b/a/12?id=3", 0, "denied by default"},
+		{"http://foo.net/a/12", 0, "denied by default"},
+		{"http://w1.net/docs/x/y", 0, ""},
+		{"http://w12.net/docs/x", 0, "denied by default"},
+		{"http://w1.net/doc", 0, "denied by default"},
+	}
+	for _, c := range cases {
+		reason := s.check(newTestReq(t, c.url, c.depth))
+		if c.reason == "" && reason != "" {
+			t.Fatalf("The request for %s (depth: %d) was rejected: %s", c.url, c.depth, reason)
+		}
+		if c.reason != "" && !strings.Contains(reason, c.reason) {
+			t.Fatalf("Inconsistent reason for %s (depth: %d): expected: %q, actual: %q",
+				c.url, c.depth, c.reason, reason)
+		}
+	}
+}
+
+func TestScopeRuleOrder(t *testing.T) {
+	rules := []ScopeRule{
+		{Action: SCOPE_ACTION_ALLOW, Path: "/docs/*"},
+		{Action: SCOPE_ACTION_DENY, Host: "example.com"},
+	}
+	req := newTestReq(t, "http://example.com/docs/x", 0)
+	s, _ := newScope(ScopeArgs{Rules: rules})
+	if reason := s.check(req); reason != "" {
+		t.Fatalf("The request was rejected: %s", reason)
+	}
+	rules[0], rules[1] = rules[1], rules[0]
+	s, _ = newScope(ScopeArgs{Rules: rules})
+	if reason := s.check(req); !strings.Contains(reason, "scope rule #0 (deny)") {
+		t.Fatalf("Inconsistent reason: %q", reason)
+	}
+	// 没有规则匹配时默认允许。
+	if reason := s.check(newTestReq(t, "http://other.com/", 0)); reason != "" {
+		t.Fatalf("The request was rejected: %s", reason)
+	}
+	var nilScope *scope
+	if reason := nilScope.check(req); reason != "" {
+		t.Fatalf("The request was rejected by nil scope: %s", reason)
+	}
+}
+
+func TestNewScopeError(t *testing.T) {
+	cases := []ScopeArgs{
+		{DefaultAction: "block"},
+		{Rules: []ScopeRule{{Action: ""}}},
+		{Rules: []ScopeRule{{Action: SCOPE_ACTION_ALLOW, Path: "(", Regexp: true}}},
+	}
+	for _, args := range cases {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when checking scope args %+v!", args)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"bytes"
+	"errs"
+	"fmt"
+	"log"
+	"module"
+	"net/http"
+)
+
+// SeedRejection 代表种子请求被拒绝的记录。
+type SeedRejection struct {
+	// Index 代表种子请求在参数中的索引。
+	Index int `json:"index"`
+	// URL 代表种子请求的URL。可能为空。
+	URL string `json:"url"`
+	// Reason 代表被拒绝的原因。
+	Reason string `json:"reason"`
+}
+
+// SeedError 代表有种子请求被拒绝时的错误类型。
+type SeedError struct {
+	// Rejections 代表所有被拒绝的种子请求的记录。
+	Rejections []SeedRejection
+}
+
+func (se *SeedError) Type() errs.ErrorType {
+	return errs.ERROR_TYPE_SCHEDULER
+}
+
+func (se *SeedError) Error() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("crawler error: ")
+	buffer.WriteString(string(errs.ERROR_TYPE_SCHEDULER))
+	buffer.WriteString(": ")
+	buffer.WriteString(fmt.Sprintf("%d seed request(s) rejected", len(se.Rejections)))
+	for _, rejection := range se.Rejections {
+		buffer.WriteString(fmt.Sprintf("; [%d] %s", rejection.Index, rejection.Reason))
+	}
+	return buffer.String()
+}
+
+// Enqueue 会把给定的HTTP请求作为种子请求放入正在运行的调度器。
+// 这些请求会经过与分析得到的请求相同的过滤，
+// 结果值accepted代表被接受的请求的数量。
+// 若有请求被拒绝，则err的类型为*SeedError，其中包含每个请求被拒绝的原因。
+func (sched *myScheduler) Enqueue(httpReqs ...*http.Request) (accepted int, err error) {
+	if len(httpReqs) == 0 {
+		return 0, genParameterError("no HTTP request to enqueue")
+	}
+	return sched.EnqueueRequests(seedReqs(httpReqs)...)
+}
+
+// EnqueueRequests 会把给定的请求放入正在运行的调度器。
+// 与Enqueue方法不同，请求的深度、父页面的URL等元数据都会被保留，
+// 因此可以用它重新放入之前未能完成的请求，例如死信中的请求。
+func (sched *myScheduler) EnqueueRequests(reqs ...*module.Request) (accepted int, err error) {
+	if len(reqs) == 0 {
+		return 0, genParameterError("no request to enqueue")
+	}
+	status := sched.Status()
+	if status != SCHED_STATUS_STARTED && status != SCHED_STATUS_PAUSED {
+		return 0, genError("the scheduler has not been started!")
+	}
+	accepted, rejections :=
+		sched.enqueueReqs(reqs, sched.requestArgs.AcceptEnqueuedDomains)
+	if len(rejections) > 0 {
+		return accepted, &SeedError{Rejections: rejections}
+	}
+	return accepted, nil
+}
+
+// seedReqs 用于把给定的HTTP请求转换为深度为0的种子请求。
+// 为nil的HTTP请求会被转换为nil。
+func seedReqs(httpReqs []*http.Request) []*module.Request {
+	reqs := make([]*module.Request, len(httpReqs))
+	for i, httpReq := range httpReqs {
+		if httpReq != nil {
+			reqs[i] = module.NewRequest(httpReq, 0)
+		}
+	}
+	return reqs
+}
+
+// enqueueReqs 用于把给定的请求放入请求缓冲池。
+// 参数acceptDomains代表是否先把请求的主域名添加到可接受的主域名的字典。
+func (sched *myScheduler) enqueueReqs(
+	reqs []*module.Request,
+	acceptDomains bool) (accepted int, rejections []SeedRejection) {
+	for i, req := range reqs {
+		rejection := SeedRejection{Index: i}
+		if req == nil || req.HTTPReq() == nil {
+			rejection.Reason = "nil HTTP request"
+			rejections = append(rejections, rejection)
+			continue
+		}
+		httpReq := req.HTTPReq()
+		if httpReq.URL != nil {
+			rejection.URL = httpReq.URL.String()
+		}
+		if acceptDomains {
+			primaryDomain, err := getPrimaryDomain(getReqHost(httpReq))
+			if err != nil {
+				rejection.Reason = err.Error()
+				rejections = append(rejections, rejection)
+				continue
+			}
+			sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
+		}
+		if reason := sched.filterReq(req); reason != "" {
+			rejection.Reason = reason
+			rejections = append(rejections, rejection)
+			continue
+		}
+		sched.putReq(req)
+		accepted++
+	}
+	for _, rejection := range rejections {
+		log.Printf("Ignore the seed request! %s\n", rejection.Reason)
+	}
+	return
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"module"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnqueueRequestsKeepsDepth(t *testing.T) {
+	srv := newTestServer(0)
+	defer srv.Close()
+	var itemNumber uint32
+	modules := newTestModules(t, srv.URL, func(item module.Item) {
+		atomic.AddUint32(&itemNumber, 1)
+	})
+	sched := newTestScheduler(t, RequestArgs{MaxDepth: 2}, modules)
+	defer sched.Stop()
+	if err := sched.Start(); err != nil {
+		t.Fatalf("An error occurs when starting scheduler without first requests: %s", err)
+	}
+	httpReq, _ := http.NewRequest(http.MethodGet, srv.URL+"/p1", nil)
+	accepted, err := sched.EnqueueRequests(module.NewRequest(httpReq, 1), nil)
+	if accepted != 1 {
+		t.Fatalf("Inconsistent accepted number: expected: %d, actual: %d", 1, accepted)
+	}
+	seedErr, ok := err.(*SeedError)
+	if !ok || len(seedErr.Rejections) != 1 || seedErr.Rejections[0].Index != 1 {
+		t.Fatalf("Inconsistent error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	// 深度为1的请求只会再产生深度为2的3个请求。
+	if actual := atomic.LoadUint32(&itemNumber); actual != 4 {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", 4, actual)
+	}
+	if _, err := sched.EnqueueRequests(); err == nil {
+		t.Fatal("No error when enqueuing no request!")
+	}
+}
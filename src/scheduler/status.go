@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -15,17 +16,22 @@ const (
 	SCHED_STATUS_STARTED
 	SCHED_STATUS_STOPPING
 	SCHED_STATUS_STOPPED
+	SCHED_STATUS_PAUSING
+	SCHED_STATUS_PAUSED
 )
 
 // checkStatus 用于状态的检查。
 // 参数currentStatus代表当前的状态。
 // 参数wantedStatus代表想要的状态。
 // 检查规则：
-//     1. 处于正在初始化、正在启动或正在停止状态时，不能从外部改变状态。
-//     2. 想要的状态只能是正在初始化、正在启动或正在停止状态中的一个。
+//     1. 处于正在初始化、正在启动、正在停止或正在暂停状态时，不能从外部改变状态。
+//     2. 想要的状态只能是正在初始化、正在启动、正在停止或正在暂停状态中的一个，
+//        或者是用于恢复的已启动状态。
 //     3. 处于未初始化状态时，不能变为正在启动或正在停止状态。
-//     4. 处于已启动状态时，不能变为正在初始化或正在启动状态。
-//     5. 只要未处于已启动状态就不能变为正在停止状态。
+//     4. 处于已启动或已暂停状态时，不能变为正在初始化或正在启动状态。
+//     5. 只要未处于已启动或已暂停状态就不能变为正在停止状态。
+//     6. 只要未处于已启动状态就不能变为正在暂停状态。
+//     7. 只要未处于已暂停状态就不能（通过恢复）变为已启动状态。
 func checkStatus(
 	currentStatus Status,
 	wantedStatus Status,
@@ -41,6 +47,8 @@ func checkStatus(
 		err = genError("the scheduler is being started!")
 	case SCHED_STATUS_STOPPING:
 		err = genError("the scheduler is being stopped!")
+	case SCHED_STATUS_PAUSING:
+		err = genError("the scheduler is being paused!")
 	}
 	if err != nil {
 		return
@@ -56,6 +64,8 @@ func checkStatus(
 		switch currentStatus {
 		case SCHED_STATUS_STARTED:
 			err = genError("the scheduler has been started!")
+		case SCHED_STATUS_PAUSED:
+			err = genError("the scheduler has been paused!")
 		}
 	case SCHED_STATUS_STARTING:
 		switch currentStatus {
@@ -63,11 +73,26 @@ func checkStatus(
 			err = genError("the scheduler has not been initialized!")
 		case SCHED_STATUS_STARTED:
 			err = genError("the scheduler has been started!")
+		case SCHED_STATUS_PAUSED:
+			err = genError("the scheduler has been paused!")
 		}
 	case SCHED_STATUS_STOPPING:
-		if currentStatus != SCHED_STATUS_STARTED {
+		if currentStatus != SCHED_STATUS_STARTED &&
+			currentStatus != SCHED_STATUS_PAUSED {
 			err = genError("the scheduler has not been started!")
 		}
+	case SCHED_STATUS_PAUSING:
+		switch currentStatus {
+		case SCHED_STATUS_STARTED:
+		case SCHED_STATUS_PAUSED:
+			err = genError("the scheduler has been paused!")
+		default:
+			err = genError("the scheduler has not been started!")
+		}
+	case SCHED_STATUS_STARTED:
+		if currentStatus != SCHED_STATUS_PAUSED {
+			err = genError("the scheduler has not been paused!")
+		}
 	default:
 		errMsg :=
 			fmt.Sprintf("unsupported wanted status for check! (wantedStatus: %d)",
@@ -94,7 +119,56 @@ func GetStatusDescription(status Status) string {
 		return "stopping"
 	case SCHED_STATUS_STOPPED:
 		return "stopped"
+	case SCHED_STATUS_PAUSING:
+		return "pausing"
+	case SCHED_STATUS_PAUSED:
+		return "paused"
 	default:
 		return "unkown"
 	}
 }
+
+// pauseGate 代表用于暂停工作协程的闸门。
+type pauseGate struct {
+	paused bool
+	// resumed 会在闸门打开时被关闭。
+	resumed chan struct{}
+	lock    sync.Mutex
+}
+
+// close 用于关闭闸门，使工作协程在下次检查时等待。
+func (gate *pauseGate) close() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if gate.paused {
+		return
+	}
+	gate.paused = true
+	gate.resumed = make(chan struct{})
+}
+
+// open 用于打开闸门，唤醒所有等待的工作协程。
+func (gate *pauseGate) open() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if !gate.paused {
+		return
+	}
+	gate.paused = false
+	close(gate.resumed)
+}
+
+// wait 用于在闸门关闭时等待，直到闸门打开或给定的上下文被取消。
+func (gate *pauseGate) wait(ctx context.Context) {
+	gate.lock.Lock()
+	if !gate.paused {
+		gate.lock.Unlock()
+		return
+	}
+	resumed := gate.resumed
+	gate.lock.Unlock()
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+	}
+}
@@ -49,18 +49,33 @@ type mySchedSummary struct {
 
 // SummaryStruct 代表调度器摘要的结构。
 type SummaryStruct struct {
-	RequestArgs     RequestArgs             `json:"request_args"`
-	DataArgs        DataArgs                `json:"data_args"`
-	ModuleArgs      ModuleArgsSummary       `json:"module_args"`
-	Status          string                  `json:"status"`
-	Downloaders     []module.SummaryStruct  `json:"downloaders"`
-	Analyzers       []module.SummaryStruct  `json:"analyzers"`
-	Pipelines       []module.SummaryStruct  `json:"pipelines"`
-	ReqBufferPool   BufferPoolSummaryStruct `json:"request_buffer_pool"`
-	RespBufferPool  BufferPoolSummaryStruct `json:"response_buffer_pool"`
-	ItemBufferPool  BufferPoolSummaryStruct `json:"item_buffer_pool"`
-	ErrorBufferPool BufferPoolSummaryStruct `json:"error_buffer_pool"`
-	NumURL          uint64                  `json:"url_number"`
+	RequestArgs       RequestArgs                   `json:"request_args"`
+	DataArgs          DataArgs                      `json:"data_args"`
+	ModuleArgs        ModuleArgsSummary             `json:"module_args"`
+	Status            string                        `json:"status"`
+	Downloaders       []module.SummaryStruct        `json:"downloaders"`
+	Analyzers         []module.SummaryStruct        `json:"analyzers"`
+	Pipelines         []module.SummaryStruct        `json:"pipelines"`
+	ReqBufferPool     BufferPoolSummaryStruct       `json:"request_buffer_pool"`
+	RespBufferPool    BufferPoolSummaryStruct       `json:"response_buffer_pool"`
+	ItemBufferPool    BufferPoolSummaryStruct       `json:"item_buffer_pool"`
+	ErrorBufferPool   BufferPoolSummaryStruct       `json:"error_buffer_pool"`
+	NumURL            uint64                        `json:"url_number"`
+	Visited           VisitedSummaryStruct          `json:"visited"`
+	Hosts             []HostSummaryStruct           `json:"hosts"`
+	NumRobotsBlocked  uint64                        `json:"robots_blocked_url_number"`
+	NumRobotsDeferred uint64                        `json:"robots_deferred_url_number"`
+	NumRetried        uint64                        `json:"retried_request_number"`
+	NumAbandoned      uint64                        `json:"abandoned_request_number"`
+	NumDeadLetters    uint64                        `json:"dead_letter_number"`
+	Budget            BudgetSummaryStruct           `json:"budget"`
+	Outstanding       OutstandingSummaryStruct      `json:"outstanding"`
+	NumLinkEdges      uint64                        `json:"link_edge_number"`
+	Balancers         BalancerSummaryStruct         `json:"balancers"`
+	Breakers          []module.BreakerSummaryStruct `json:"breakers"`
+	DownloadWorkers   WorkerSummaryStruct           `json:"download_workers"`
+	AnalyzeWorkers    WorkerSummaryStruct           `json:"analyze_workers"`
+	PickWorkers       WorkerSummaryStruct           `json:"pick_workers"`
 }
 
 // Same 用于判断当前的调度器摘要与另一份是否相同。
@@ -68,7 +83,7 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 	if !another.RequestArgs.Same(&one.RequestArgs) {
 		return false
 	}
-	if another.DataArgs != one.DataArgs {
+	if !another.DataArgs.Same(&one.DataArgs) {
 		return false
 	}
 	if another.ModuleArgs != one.ModuleArgs {
@@ -116,24 +131,78 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 	if another.NumURL != one.NumURL {
 		return false
 	}
+	if another.Visited != one.Visited {
+		return false
+	}
+	if another.NumRobotsBlocked != one.NumRobotsBlocked ||
+		another.NumRobotsDeferred != one.NumRobotsDeferred {
+		return false
+	}
+	if another.NumRetried != one.NumRetried ||
+		another.NumAbandoned != one.NumAbandoned ||
+		another.NumDeadLetters != one.NumDeadLetters {
+		return false
+	}
+	if another.Budget != one.Budget ||
+		another.Outstanding != one.Outstanding ||
+		another.NumLinkEdges != one.NumLinkEdges ||
+		another.Balancers != one.Balancers {
+		return false
+	}
+	if another.DownloadWorkers != one.DownloadWorkers ||
+		another.AnalyzeWorkers != one.AnalyzeWorkers ||
+		another.PickWorkers != one.PickWorkers {
+		return false
+	}
+	if len(another.Breakers) != len(one.Breakers) {
+		return false
+	}
+	for i, bs := range another.Breakers {
+		if !bs.Same(one.Breakers[i]) {
+			return false
+		}
+	}
+	if len(another.Hosts) != len(one.Hosts) {
+		return false
+	}
+	for i, hs := range another.Hosts {
+		if hs != one.Hosts[i] {
+			return false
+		}
+	}
 	return true
 }
 
 func (ss *mySchedSummary) Struct() SummaryStruct {
 	registrar := ss.sched.registrar
 	return SummaryStruct{
-		RequestArgs:     ss.requestArgs,
-		DataArgs:        ss.dataArgs,
-		ModuleArgs:      ss.moduleArgs.Summary(),
-		Status:          GetStatusDescription(ss.sched.Status()),
-		Downloaders:     getModuleSummaries(registrar, module.TYPE_DOWNLOADER),
-		Analyzers:       getModuleSummaries(registrar, module.TYPE_ANALYZER),
-		Pipelines:       getModuleSummaries(registrar, module.TYPE_PIPELINE),
-		ReqBufferPool:   getBufferPoolSummary(ss.sched.reqBufferPool),
-		RespBufferPool:  getBufferPoolSummary(ss.sched.respBufferPool),
-		ItemBufferPool:  getBufferPoolSummary(ss.sched.itemBufferPool),
-		ErrorBufferPool: getBufferPoolSummary(ss.sched.errorBufferPool),
-		NumURL:          ss.sched.urlMap.Len(),
+		RequestArgs:       ss.requestArgs,
+		DataArgs:          ss.dataArgs,
+		ModuleArgs:        ss.moduleArgs.Summary(),
+		Status:            GetStatusDescription(ss.sched.Status()),
+		Downloaders:       getModuleSummaries(registrar, module.TYPE_DOWNLOADER),
+		Analyzers:         getModuleSummaries(registrar, module.TYPE_ANALYZER),
+		Pipelines:         getModuleSummaries(registrar, module.TYPE_PIPELINE),
+		ReqBufferPool:     getBufferPoolSummary(ss.sched.reqBufferPool),
+		RespBufferPool:    getBufferPoolSummary(ss.sched.respBufferPool),
+		ItemBufferPool:    getBufferPoolSummary(ss.sched.itemBufferPool),
+		ErrorBufferPool:   getBufferPoolSummary(ss.sched.errorBufferPool),
+		NumURL:            ss.sched.visitedURLs.len(),
+		Visited:           ss.sched.visitedURLs.summary(),
+		Hosts:             ss.sched.politeness.summary(),
+		NumRobotsBlocked:  getRobotsBlockedNumber(ss.sched.robots),
+		NumRobotsDeferred: getRobotsDeferredNumber(ss.sched.robots),
+		NumRetried:        ss.sched.retrier.retriedNumber(),
+		NumAbandoned:      ss.sched.retrier.abandonedNumber(),
+		NumDeadLetters:    ss.sched.deadLetters.number(),
+		Budget:            ss.sched.budget.summary(),
+		Outstanding:       ss.sched.work.summary(),
+		NumLinkEdges:      ss.sched.linkEdgeNumber(),
+		Balancers:         getBalancerSummary(ss.sched.registrar),
+		Breakers:          ss.sched.registrar.BreakerSummaries(),
+		DownloadWorkers:   ss.sched.downloadWorkers.summary(),
+		AnalyzeWorkers:    ss.sched.analyzeWorkers.summary(),
+		PickWorkers:       ss.sched.pickWorkers.summary(),
 	}
 }
 
@@ -152,16 +221,37 @@ type BufferPoolSummaryStruct struct {
 	MaxBufferNumber uint32 `json:"max_buffer_number"`
 	BufferNumber    uint32 `json:"buffer_number"`
 	Total           uint64 `json:"total"`
+	Spilled         uint64 `json:"spilled,omitempty"`
 }
 
 // getBufferPoolSummary 用于生成和返回某个数据缓冲池的摘要信息。
 func getBufferPoolSummary(bufferPool buffer.Pool) BufferPoolSummaryStruct {
-	return BufferPoolSummaryStruct{
+	summary := BufferPoolSummaryStruct{
 		BufferCap:       bufferPool.BufferCap(),
 		MaxBufferNumber: bufferPool.MaxBufferNumber(),
 		BufferNumber:    bufferPool.BufferNumber(),
 		Total:           bufferPool.Total(),
 	}
+	if diskPool, ok := bufferPool.(buffer.DiskPool); ok {
+		summary.Spilled = diskPool.SpilledTotal()
+	}
+	return summary
+}
+
+// BalancerSummaryStruct 代表各类型的组件所用的负载均衡策略的摘要类型。
+type BalancerSummaryStruct struct {
+	Downloader string `json:"downloader"`
+	Analyzer   string `json:"analyzer"`
+	Pipeline   string `json:"pipeline"`
+}
+
+// getBalancerSummary 用于获取各类型的组件所用的负载均衡策略的摘要。
+func getBalancerSummary(registrar module.Registrar) BalancerSummaryStruct {
+	return BalancerSummaryStruct{
+		Downloader: registrar.Balancer(module.TYPE_DOWNLOADER).Name(),
+		Analyzer:   registrar.Balancer(module.TYPE_ANALYZER).Name(),
+		Pipeline:   registrar.Balancer(module.TYPE_PIPELINE).Name(),
+	}
 }
 
 // getModuleSummaries 用于获取已注册的某类组件的摘要。
@@ -181,3 +271,27 @@ func getModuleSummaries(registrar module.Registrar, mType module.Type) []module.
 	}
 	return summaries
 }
+
+// getRobotsBlockedNumber 用于获取被robots.txt禁止访问的URL的数量。
+func getRobotsBlockedNumber(robots *robotsCache) uint64 {
+	if robots == nil {
+		return 0
+	}
+	return robots.blockedNumber()
+}
+
+// getRobotsDeferredNumber 用于获取因暂时无法获取robots.txt而未被允许访问的URL的数量。
+func getRobotsDeferredNumber(robots *robotsCache) uint64 {
+	if robots == nil {
+		return 0
+	}
+	return robots.deferredNumber()
+}
+
+// linkEdgeNumber 用于获取链接图中已记录的边的数量。
+func (sched *myScheduler) linkEdgeNumber() uint64 {
+	if sched.linkGraph == nil {
+		return 0
+	}
+	return sched.linkGraph.EdgeNumber()
+}
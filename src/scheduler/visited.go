@@ -0,0 +1,200 @@
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"sync/atomic"
+	"toolkit/bloom"
+	"toolkit/cmap"
+)
+
+// VisitedMode 代表已处理URL集合的实现方式。
+type VisitedMode string
+
+const (
+	// VISITED_MODE_EXACT 代表用并发安全字典精确地记录已处理的URL。
+	VISITED_MODE_EXACT VisitedMode = "exact"
+	// VISITED_MODE_BLOOM 代表用可扩展布隆过滤器记录已处理的URL。
+	// 它占用的内存少得多，但会以一定的误判率把未处理的URL当作已处理的。
+	VISITED_MODE_BLOOM VisitedMode = "bloom"
+)
+
+const (
+	// DEFAULT_BLOOM_CAPACITY 代表布隆过滤器的默认初始容量。
+	DEFAULT_BLOOM_CAPACITY uint64 = 1 << 20
+	// DEFAULT_BLOOM_FP_RATE 代表布隆过滤器的默认目标误判率。
+	DEFAULT_BLOOM_FP_RATE = 0.0001
+)
+
+// VisitedArgs 代表已处理URL集合相关的参数。
+type VisitedArgs struct {
+	// Mode 代表实现方式。为空代表VISITED_MODE_EXACT。
+	Mode VisitedMode `json:"mode"`
+	// BloomCapacity 代表布隆过滤器的初始容量。0代表使用默认值。
+	BloomCapacity uint64 `json:"bloom_capacity,omitempty"`
+	// BloomFPRate 代表布隆过滤器的目标误判率。0代表使用默认值。
+	BloomFPRate float64 `json:"bloom_fp_rate,omitempty"`
+}
+
+// Check 用于检查已处理URL集合相关参数的有效性。
+func (args *VisitedArgs) Check() error {
+	switch args.Mode {
+	case "", VISITED_MODE_EXACT, VISITED_MODE_BLOOM:
+	default:
+		return genError(fmt.Sprintf("illegal visited mode: %q", args.Mode))
+	}
+	if args.BloomFPRate < 0 || args.BloomFPRate >= 1 {
+		return genError(fmt.Sprintf("illegal bloom false positive rate: %v", args.BloomFPRate))
+	}
+	return nil
+}
+
+// VisitedSummaryStruct 代表已处理URL集合的摘要类型。
+type VisitedSummaryStruct struct {
+	Mode VisitedMode `json:"mode"`
+	// EstimatedFPRate 代表估计的误判率。精确模式下总为0。
+	EstimatedFPRate float64 `json:"estimated_fp_rate"`
+	// MemoryBytes 代表估计占用的内存字节数。
+	// 精确模式下只包括URL本身，不包括字典结构的开销。
+	MemoryBytes uint64 `json:"memory_bytes"`
+}
+
+// visitedSet 代表已处理URL集合的接口类型。
+type visitedSet interface {
+	// contains 用于判断给定的URL是否已被处理。
+	contains(key string) bool
+	// add 用于把给定的URL记为已处理。
+	// 结果值代表该URL此前是否未被记录。
+	// 对于同一个URL的并发调用，只有一个调用的结果值为true。
+	add(key string) bool
+	// len 用于获取已处理的URL的数量。
+	len() uint64
+	// snapshot 用于把已处理的URL写入给定的检查点。
+	snapshot(cp *checkpoint)
+	// restore 用于从给定的检查点还原已处理的URL。
+	restore(cp *checkpoint) error
+	summary() VisitedSummaryStruct
+}
+
+// newVisitedSet 会根据给定的参数创建已处理URL集合。
+func newVisitedSet(args VisitedArgs) (visitedSet, error) {
+	if args.Mode != VISITED_MODE_BLOOM {
+		m, err := cmap.NewConcurrentMap(16, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &exactVisitedSet{m: m}, nil
+	}
+	capacity := args.BloomCapacity
+	if capacity == 0 {
+		capacity = DEFAULT_BLOOM_CAPACITY
+	}
+	fpRate := args.BloomFPRate
+	if fpRate == 0 {
+		fpRate = DEFAULT_BLOOM_FP_RATE
+	}
+	filter, err := bloom.New(capacity, fpRate)
+	if err != nil {
+		return nil, err
+	}
+	return &bloomVisitedSet{filter: filter}, nil
+}
+
+// exactVisitedSet 代表基于并发安全字典的已处理URL集合。
+type exactVisitedSet struct {
+	m cmap.ConcurrentMap
+	// bytes 代表已处理的URL的总字节数。
+	bytes uint64
+}
+
+func (set *exactVisitedSet) contains(key string) bool {
+	return set.m.Get(key) != nil
+}
+
+func (set *exactVisitedSet) add(key string) bool {
+	ok, _ := set.m.Put(key, struct{}{})
+	if ok {
+		atomic.AddUint64(&set.bytes, uint64(len(key)))
+	}
+	return ok
+}
+
+func (set *exactVisitedSet) len() uint64 {
+	return set.m.Len()
+}
+
+func (set *exactVisitedSet) snapshot(cp *checkpoint) {
+	set.m.Range(func(key string, element interface{}) bool {
+		cp.VisitedURLs = append(cp.VisitedURLs, key)
+		return true
+	})
+	sort.Strings(cp.VisitedURLs)
+}
+
+func (set *exactVisitedSet) restore(cp *checkpoint) error {
+	if len(cp.VisitedFilter) > 0 {
+		return genError("the checkpoint contains a bloom filter, " +
+			"but the visited mode is not " + string(VISITED_MODE_BLOOM))
+	}
+	for _, u := range cp.VisitedURLs {
+		set.add(u)
+	}
+	return nil
+}
+
+func (set *exactVisitedSet) summary() VisitedSummaryStruct {
+	return VisitedSummaryStruct{
+		Mode:        VISITED_MODE_EXACT,
+		MemoryBytes: atomic.LoadUint64(&set.bytes),
+	}
+}
+
+// bloomVisitedSet 代表基于可扩展布隆过滤器的已处理URL集合。
+type bloomVisitedSet struct {
+	filter bloom.Filter
+}
+
+func (set *bloomVisitedSet) contains(key string) bool {
+	return set.filter.TestString(key)
+}
+
+func (set *bloomVisitedSet) add(key string) bool {
+	return !set.filter.AddString(key)
+}
+
+func (set *bloomVisitedSet) len() uint64 {
+	return set.filter.Count()
+}
+
+func (set *bloomVisitedSet) snapshot(cp *checkpoint) {
+	var buffer bytes.Buffer
+	if _, err := set.filter.WriteTo(&buffer); err != nil {
+		log.Printf("Couldn't serialize the bloom filter: %s\n", err)
+		return
+	}
+	cp.VisitedFilter = buffer.Bytes()
+}
+
+func (set *bloomVisitedSet) restore(cp *checkpoint) error {
+	if len(cp.VisitedFilter) > 0 {
+		filter, err := bloom.ReadFrom(bytes.NewReader(cp.VisitedFilter))
+		if err != nil {
+			return genError(fmt.Sprintf("couldn't load the bloom filter: %s", err))
+		}
+		set.filter = filter
+	}
+	for _, u := range cp.VisitedURLs {
+		set.add(u)
+	}
+	return nil
+}
+
+func (set *bloomVisitedSet) summary() VisitedSummaryStruct {
+	return VisitedSummaryStruct{
+		Mode:            VISITED_MODE_BLOOM,
+		EstimatedFPRate: set.filter.EstimatedFPRate(),
+		MemoryBytes:     set.filter.MemoryBytes(),
+	}
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVisitedSetAddOnce(t *testing.T) {
+	for _, mode := range []VisitedMode{VISITED_MODE_EXACT, VISITED_MODE_BLOOM} {
+		set, err := newVisitedSet(VisitedArgs{Mode: mode, BloomCapacity: 1000})
+		if err != nil {
+			t.Fatalf("An error occurs when creating visited set (mode: %s): %s", mode, err)
+		}
+		var added [100]int32
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range added {
+					if set.add("http://example.com/" + strconv.Itoa(i)) {
+						atomic.AddInt32(&added[i], 1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		for i, n := range added {
+			if n != 1 {
+				t.Fatalf("Inconsistent number of successful adds for key %d (mode: %s): expected: 1, actual: %d",
+					i, mode, n)
+			}
+		}
+		if set.add("http://example.com/0") {
+			t.Fatalf("A repeated key was added again! (mode: %s)", mode)
+		}
+	}
+}
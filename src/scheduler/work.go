@@ -0,0 +1,216 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// workKind 代表待完成的工作的种类。
+type workKind int
+
+const (
+	// workRequest 代表尚未下载完毕的请求。
+	workRequest workKind = iota
+	// workResponse 代表尚未解析完毕的响应。
+	workResponse
+	// workItem 代表尚未处理完毕的条目。
+	workItem
+)
+
+func (kind workKind) String() string {
+	switch kind {
+	case workRequest:
+		return "request"
+	case workResponse:
+		return "response"
+	case workItem:
+		return "item"
+	default:
+		return fmt.Sprintf("work kind %d", int(kind))
+	}
+}
+
+// negativeWorkHandler 会在某种待完成的工作的数量变为负数时被调用。
+// 数量变为负数意味着同一份工作被重复地标记为已完成。
+// 测试中会把它替换为引发恐慌的函数，以便暴露这类问题。
+var negativeWorkHandler = func(kind workKind, count int64) {
+	log.Printf("The outstanding %s number is negative: %d\n", kind, count)
+}
+
+// OutstandingSummaryStruct 代表待完成的工作的摘要类型。
+type OutstandingSummaryStruct struct {
+	Requests  uint64 `json:"requests"`
+	Responses uint64 `json:"responses"`
+	Items     uint64 `json:"items"`
+}
+
+// workTracker 代表待完成的工作的跟踪器。
+// 每个请求、响应和条目从被接受开始到被处理完毕为止都算作一份待完成的工作，
+// 无论它处于缓冲池中、等待放入缓冲池的goroutine中还是正在被某个工作协程处理。
+// 处理某份工作时产生的新工作总是在该工作完成之前被计入，
+// 因此待完成的工作的数量为0就意味着爬取已经完成。
+type workTracker struct {
+	// counts 代表各种待完成的工作的数量。
+	counts [3]int64
+	// armed 代表是否已在启动后放入了所有的种子。
+	// 在此之前，待完成的工作的数量为0并不代表爬取已完成。
+	armed bool
+	// done 会在没有待完成的工作或调度器停止时被关闭。
+	done chan struct{}
+	// closed 代表done是否已被关闭。
+	closed bool
+	lock   sync.Mutex
+}
+
+// newWorkTracker 会创建一个待完成的工作的跟踪器。
+func newWorkTracker() *workTracker {
+	return &workTracker{done: make(chan struct{})}
+}
+
+// reset 用于在调度器启动时清零所有的计数。
+// 尚未被关闭的done会被沿用，以便在启动之前获取它的一方也能收到通知。
+func (tracker *workTracker) reset() {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	tracker.counts = [3]int64{}
+	tracker.armed = false
+	if tracker.closed {
+		tracker.done = make(chan struct{})
+		tracker.closed = false
+	}
+}
+
+// arm 用于表明所有的种子都已放入。
+// 此后待完成的工作的数量一旦为0，done就会被关闭。
+func (tracker *workTracker) arm() {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	tracker.armed = true
+	tracker.check()
+}
+
+// add 用于增减给定种类的待完成的工作的数量。
+// 在done已被关闭后有新的工作加入时，会生成新的done。
+// 数量变为负数时不会被修正，而是会被报告给negativeWorkHandler。
+func (tracker *workTracker) add(kind workKind, delta int64) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	tracker.counts[kind] += delta
+	if tracker.counts[kind] < 0 {
+		negativeWorkHandler(kind, tracker.counts[kind])
+	}
+	if tracker.total() > 0 && tracker.closed && tracker.armed {
+		tracker.done = make(chan struct{})
+		tracker.closed = false
+	}
+	tracker.check()
+}
+
+// finish 用于在调度器停止时关闭done。
+// 此后不会再生成新的done，直到调度器被重新启动。
+func (tracker *workTracker) finish() {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	tracker.armed = false
+	if !tracker.closed {
+		close(tracker.done)
+		tracker.closed = true
+	}
+}
+
+// check 用于在没有待完成的工作时关闭done。
+// 只有各种工作的数量都为0时才算没有待完成的工作，
+// 以免某种工作的数量为负数时抵消其他种类的工作。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (tracker *workTracker) check() {
+	if !tracker.armed || tracker.closed {
+		return
+	}
+	for _, count := range tracker.counts {
+		if count != 0 {
+			return
+		}
+	}
+	close(tracker.done)
+	tracker.closed = true
+}
+
+// total 用于获取待完成的工作的总数。负数的数量会被视为0。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (tracker *workTracker) total() int64 {
+	var total int64
+	for _, count := range tracker.counts {
+		if count > 0 {
+			total += count
+		}
+	}
+	return total
+}
+
+// number 用于获取给定种类的待完成的工作的数量。负数的数量会被视为0。
+func (tracker *workTracker) number(kind workKind) uint64 {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	return nonNegative(tracker.counts[kind])
+}
+
+// nonNegative 用于把给定的数量转换为无符号整数。负数会被视为0。
+func nonNegative(count int64) uint64 {
+	if count < 0 {
+		return 0
+	}
+	return uint64(count)
+}
+
+// totalNumber 用于获取待完成的工作的总数。
+func (tracker *workTracker) totalNumber() uint64 {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	return uint64(tracker.total())
+}
+
+// doneChan 用于获取当前的done。
+func (tracker *workTracker) doneChan() <-chan struct{} {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	return tracker.done
+}
+
+func (tracker *workTracker) summary() OutstandingSummaryStruct {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	return OutstandingSummaryStruct{
+		Requests:  nonNegative(tracker.counts[workRequest]),
+		Responses: nonNegative(tracker.counts[workResponse]),
+		Items:     nonNegative(tracker.counts[workItem]),
+	}
+}
+
+// Done 会返回一个通道，它会在爬取完成（即没有任何待完成的工作）
+// 或调度器停止时被关闭。
+// 爬取完成后若又通过Enqueue方法放入了新的请求，
+// 再次调用本方法会得到一个新的通道。
+func (sched *myScheduler) Done() <-chan struct{} {
+	return sched.work.doneChan()
+}
+
+// Wait 会一直等待，直到爬取完成或参数ctx被取消。
+// 若调度器在爬取完成之前就被停止了，就返回非nil的错误值。
+func (sched *myScheduler) Wait(ctx context.Context) error {
+	if ctx == nil {
+		return genParameterError("nil context")
+	}
+	select {
+	case <-sched.Done():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if remaining := sched.work.totalNumber(); remaining > 0 {
+		errMsg := fmt.Sprintf("the scheduler has been stopped with %d outstanding work unit(s)",
+			remaining)
+		return genError(errMsg)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+// isClosed 用于判断给定的通道是否已被关闭。
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestWorkTrackerDone(t *testing.T) {
+	tracker := newWorkTracker()
+	done := tracker.doneChan()
+	// 在放入所有种子之前，待完成的工作的数量为0并不代表爬取已完成。
+	tracker.add(workRequest, 1)
+	tracker.add(workRequest, -1)
+	if isClosed(done) {
+		t.Fatal("The done channel was closed before arming!")
+	}
+	tracker.add(workRequest, 2)
+	tracker.arm()
+	if isClosed(done) {
+		t.Fatal("The done channel was closed with outstanding work!")
+	}
+	tracker.add(workResponse, 1)
+	tracker.add(workRequest, -2)
+	tracker.add(workItem, 3)
+	tracker.add(workResponse, -1)
+	summary := tracker.summary()
+	if summary.Requests != 0 || summary.Responses != 0 || summary.Items != 3 ||
+		tracker.totalNumber() != 3 || tracker.number(workItem) != 3 {
+		t.Fatalf("Inconsistent outstanding work: %+v", summary)
+	}
+	tracker.add(workItem, -3)
+	if !isClosed(done) {
+		t.Fatal("The done channel was not closed without outstanding work!")
+	}
+	// 完成后又有新的工作加入时会生成新的done。
+	tracker.add(workRequest, 1)
+	newDone := tracker.doneChan()
+	if newDone == done || isClosed(newDone) {
+		t.Fatal("No new done channel after adding new work!")
+	}
+	// 调度器停止时done会被关闭，即使仍有待完成的工作。
+	tracker.finish()
+	if !isClosed(newDone) {
+		t.Fatal("The done channel was not closed after finishing!")
+	}
+	tracker.add(workRequest, 1)
+	if tracker.doneChan() != newDone {
+		t.Fatal("A new done channel was created after finishing!")
+	}
+	// 重新启动时计数会被清零，并生成新的done。
+	tracker.reset()
+	if tracker.totalNumber() != 0 || isClosed(tracker.doneChan()) {
+		t.Fatal("The tracker was not reset!")
+	}
+}
+
+func TestWorkTrackerNegative(t *testing.T) {
+	var reported []int64
+	handler := negativeWorkHandler
+	negativeWorkHandler = func(kind workKind, count int64) {
+		if kind != workResponse {
+			t.Fatalf("Inconsistent work kind: expected: %s, actual: %s", workResponse, kind)
+		}
+		reported = append(reported, count)
+	}
+	defer func() {
+		negativeWorkHandler = handler
+	}()
+	tracker := newWorkTracker()
+	done := tracker.doneChan()
+	tracker.add(workRequest, 1)
+	tracker.arm()
+	// 重复完成的工作不能抵消其他待完成的工作。
+	tracker.add(workResponse, -1)
+	if len(reported) != 1 || reported[0] != -1 {
+		t.Fatalf("Inconsistent reported counts: %v", reported)
+	}
+	if isClosed(done) {
+		t.Fatal("The done channel was closed with outstanding work!")
+	}
+	if tracker.number(workResponse) != 0 || tracker.totalNumber() != 1 {
+		t.Fatalf("Inconsistent outstanding work: %+v", tracker.summary())
+	}
+	// 负数的数量会被保留，而不会被修正为0。
+	tracker.add(workResponse, 1)
+	tracker.add(workRequest, -1)
+	if !isClosed(done) {
+		t.Fatal("The done channel was not closed without outstanding work!")
+	}
+	if len(reported) != 1 {
+		t.Fatalf("Inconsistent reported counts: %v", reported)
+	}
+}
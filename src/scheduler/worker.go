@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"sync/atomic"
+	"toolkit/buffer"
+)
+
+// workerGauge 代表某个处理阶段的工作协程的计量器。
+type workerGauge struct {
+	// number 代表工作协程的数量。
+	number uint32
+	// busy 代表正在处理数据的工作协程的数量。
+	busy uint32
+	// auto 代表工作协程的数量是否随组件的数量而定。
+	auto bool
+}
+
+// newWorkerGauge 会创建一个工作协程计量器。
+// 参数configured代表参数中指定的工作协程数量，为0时使用默认值。
+// 参数moduleNumber代表该阶段可用的组件的数量。
+// 默认值为组件的数量，但至少为1。
+func newWorkerGauge(configured uint32, moduleNumber int) *workerGauge {
+	number := configured
+	if number == 0 {
+		number = uint32(moduleNumber)
+	}
+	if number == 0 {
+		number = 1
+	}
+	return &workerGauge{number: number, auto: configured == 0}
+}
+
+// workerNumber 用于获取工作协程的数量。
+func (gauge *workerGauge) workerNumber() uint32 {
+	return atomic.LoadUint32(&gauge.number)
+}
+
+// grow 用于在组件的数量增加后相应地增加工作协程的数量。
+// 参数moduleNumber代表该阶段现有的组件的数量。
+// 结果值代表是否增加了一个工作协程。
+// 工作协程的数量由参数指定时不会增加。
+func (gauge *workerGauge) grow(moduleNumber int) bool {
+	if !gauge.auto {
+		return false
+	}
+	for {
+		number := atomic.LoadUint32(&gauge.number)
+		if uint32(moduleNumber) <= number || number >= MAX_WORKER_NUMBER {
+			return false
+		}
+		if atomic.CompareAndSwapUint32(&gauge.number, number, number+1) {
+			return true
+		}
+	}
+}
+
+func (gauge *workerGauge) incrBusy() {
+	atomic.AddUint32(&gauge.busy, 1)
+}
+
+func (gauge *workerGauge) decrBusy() {
+	atomic.AddUint32(&gauge.busy, ^uint32(0))
+}
+
+func (gauge *workerGauge) busyNumber() uint32 {
+	return atomic.LoadUint32(&gauge.busy)
+}
+
+// WorkerSummaryStruct 代表某个处理阶段的工作协程的摘要类型。
+type WorkerSummaryStruct struct {
+	Number uint32 `json:"number"`
+	Busy   uint32 `json:"busy"`
+}
+
+func (gauge *workerGauge) summary() WorkerSummaryStruct {
+	return WorkerSummaryStruct{
+		Number: gauge.workerNumber(),
+		Busy:   gauge.busyNumber(),
+	}
+}
+
+// putDatum 会把数据放入给定的缓冲池。
+// 与sendResp和sendItem不同，本函数会在缓冲池已满时阻塞，
+// 从而对上游阶段的工作协程形成反压。
+func putDatum(datum interface{}, bufferPool buffer.Pool) bool {
+	if datum == nil || bufferPool == nil || bufferPool.Closed() {
+		return false
+	}
+	if err := bufferPool.Put(datum); err != nil {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,323 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errs"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sync"
+)
+
+const (
+	// growthFactor 代表每个新的子过滤器相对于上一个的容量倍数。
+	growthFactor = 2
+	// tighteningRatio 代表每个新的子过滤器相对于上一个的误判率倍数。
+	tighteningRatio = 0.8
+	// magic 代表序列化数据的开头。
+	magic = "BLM1"
+)
+
+// Filter 代表可扩展布隆过滤器的接口类型。
+// 它会在元素数量超过当前容量时自动添加更大的子过滤器，
+// 从而使总的误判率始终不超过创建时给定的目标误判率。
+// 它的所有方法都是并发安全的。
+type Filter interface {
+	// Add 会添加给定的元素。
+	// 若该元素（可能）已存在，就返回true，且不会重复添加。
+	Add(data []byte) bool
+	// AddString 会添加给定的字符串。
+	AddString(s string) bool
+	// Test 用于判断给定的元素是否（可能）已存在。
+	// 结果值为false时该元素一定不存在。
+	Test(data []byte) bool
+	// TestString 用于判断给定的字符串是否（可能）已存在。
+	TestString(s string) bool
+	// Count 会返回已添加的元素的数量。
+	Count() uint64
+	// FPRate 会返回目标误判率。
+	FPRate() float64
+	// EstimatedFPRate 会根据当前的填充程度返回估计的误判率。
+	EstimatedFPRate() float64
+	// MemoryBytes 会返回位数组所占用的字节数。
+	MemoryBytes() uint64
+	// StageNumber 会返回子过滤器的数量。
+	StageNumber() int
+	// WriteTo 会把过滤器序列化后写入给定的写入器。
+	WriteTo(w io.Writer) (n int64, err error)
+}
+
+// stage 代表可扩展布隆过滤器中的子过滤器。
+type stage struct {
+	// capacity 代表子过滤器的容量。
+	capacity uint64
+	// k 代表哈希函数的数量。
+	k uint32
+	// m 代表位数组的位数。
+	m uint64
+	// count 代表已添加到该子过滤器的元素的数量。
+	count uint64
+	bits  []uint64
+}
+
+// newStage 会创建一个容量和误判率都符合要求的子过滤器。
+func newStage(capacity uint64, fpRate float64) *stage {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Ceil(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &stage{
+		capacity: capacity,
+		k:        k,
+		m:        m,
+		bits:     make([]uint64, (m+63)/64),
+	}
+}
+
+// location 用于获取第i个哈希函数对应的位的索引。
+func (s *stage) location(h1, h2 uint64, i uint32) uint64 {
+	return (h1 + uint64(i)*h2) % s.m
+}
+
+func (s *stage) test(h1, h2 uint64) bool {
+	for i := uint32(0); i < s.k; i++ {
+		loc := s.location(h1, h2, i)
+		if s.bits[loc/64]&(1<<(loc%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *stage) add(h1, h2 uint64) {
+	for i := uint32(0); i < s.k; i++ {
+		loc := s.location(h1, h2, i)
+		s.bits[loc/64] |= 1 << (loc % 64)
+	}
+	s.count++
+}
+
+// estimatedFPRate 用于根据元素数量估计子过滤器的误判率。
+func (s *stage) estimatedFPRate() float64 {
+	return math.Pow(1-math.Exp(-float64(s.k)*float64(s.count)/float64(s.m)), float64(s.k))
+}
+
+// myFilter 代表可扩展布隆过滤器的实现类型。
+type myFilter struct {
+	// initialCapacity 代表第一个子过滤器的容量。
+	initialCapacity uint64
+	// fpRate 代表目标误判率。
+	fpRate float64
+	stages []*stage
+	count  uint64
+	lock   sync.RWMutex
+}
+
+// New 会创建一个可扩展布隆过滤器。
+// 参数initialCapacity代表第一个子过滤器的容量。
+// 参数fpRate代表目标误判率，必须在0和1之间。
+func New(initialCapacity uint64, fpRate float64) (Filter, error) {
+	if initialCapacity == 0 {
+		return nil, errs.NewIllegalParameterError("zero initial capacity for bloom filter")
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		errMsg := fmt.Sprintf("illegal false positive rate for bloom filter: %v", fpRate)
+		return nil, errs.NewIllegalParameterError(errMsg)
+	}
+	filter := &myFilter{
+		initialCapacity: initialCapacity,
+		fpRate:          fpRate,
+	}
+	filter.grow()
+	return filter, nil
+}
+
+// grow 用于添加一个新的子过滤器。
+// 第i个子过滤器的误判率为fpRate*(1-r)*r^i，因此总的误判率不超过fpRate。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (filter *myFilter) grow() {
+	i := len(filter.stages)
+	capacity := filter.initialCapacity
+	for j := 0; j < i; j++ {
+		capacity *= growthFactor
+	}
+	fpRate := filter.fpRate * (1 - tighteningRatio) * math.Pow(tighteningRatio, float64(i))
+	filter.stages = append(filter.stages, newStage(capacity, fpRate))
+}
+
+// hash 用于计算给定元素的两个哈希值，以便通过双重哈希模拟k个哈希函数。
+func hash(data []byte) (h1, h2 uint64) {
+	hasher := fnv.New64a()
+	hasher.Write(data)
+	h1 = hasher.Sum64()
+	// 使用splitmix64的混合函数从h1得到h2，并保证其为奇数。
+	h2 = h1 + 0x9e3779b97f4a7c15
+	h2 = (h2 ^ (h2 >> 30)) * 0xbf58476d1ce4e5b9
+	h2 = (h2 ^ (h2 >> 27)) * 0x94d049bb133111eb
+	h2 = (h2 ^ (h2 >> 31)) | 1
+	return
+}
+
+// test 用于判断给定哈希值的元素是否在任何子过滤器中。
+// 注意！必须在读锁或互斥锁的保护下调用本方法！
+func (filter *myFilter) test(h1, h2 uint64) bool {
+	for _, s := range filter.stages {
+		if s.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+func (filter *myFilter) Add(data []byte) bool {
+	h1, h2 := hash(data)
+	filter.lock.Lock()
+	defer filter.lock.Unlock()
+	if filter.test(h1, h2) {
+		return true
+	}
+	last := filter.stages[len(filter.stages)-1]
+	if last.count >= last.capacity {
+		filter.grow()
+		last = filter.stages[len(filter.stages)-1]
+	}
+	last.add(h1, h2)
+	filter.count++
+	return false
+}
+
+func (filter *myFilter) AddString(s string) bool {
+	return filter.Add([]byte(s))
+}
+
+func (filter *myFilter) Test(data []byte) bool {
+	h1, h2 := hash(data)
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	return filter.test(h1, h2)
+}
+
+func (filter *myFilter) TestString(s string) bool {
+	return filter.Test([]byte(s))
+}
+
+func (filter *myFilter) Count() uint64 {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	return filter.count
+}
+
+func (filter *myFilter) FPRate() float64 {
+	return filter.fpRate
+}
+
+func (filter *myFilter) EstimatedFPRate() float64 {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	// 任一子过滤器误判即为误判。
+	notFP := 1.0
+	for _, s := range filter.stages {
+		notFP *= 1 - s.estimatedFPRate()
+	}
+	return 1 - notFP
+}
+
+func (filter *myFilter) MemoryBytes() uint64 {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	var total uint64
+	for _, s := range filter.stages {
+		total += uint64(len(s.bits)) * 8
+	}
+	return total
+}
+
+func (filter *myFilter) StageNumber() int {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	return len(filter.stages)
+}
+
+// countingWriter 代表会记录已写入字节数的写入器。
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// write 用于以大端字节序写入给定的数据。发生错误后不再写入。
+func (cw *countingWriter) write(data interface{}) {
+	if cw.err != nil {
+		return
+	}
+	if cw.err = binary.Write(cw.w, binary.BigEndian, data); cw.err == nil {
+		cw.n += int64(binary.Size(data))
+	}
+}
+
+// WriteTo 会把过滤器序列化后写入给定的写入器。
+// 数据格式为：魔数、初始容量、目标误判率、元素数量、子过滤器数量，
+// 然后是每个子过滤器的容量、哈希函数数量、位数、元素数量和位数组。
+func (filter *myFilter) WriteTo(w io.Writer) (n int64, err error) {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+	cw := &countingWriter{w: w}
+	cw.write([]byte(magic))
+	cw.write(filter.initialCapacity)
+	cw.write(filter.fpRate)
+	cw.write(filter.count)
+	cw.write(uint32(len(filter.stages)))
+	for _, s := range filter.stages {
+		cw.write(s.capacity)
+		cw.write(s.k)
+		cw.write(s.m)
+		cw.write(s.count)
+		cw.write(s.bits)
+	}
+	return cw.n, cw.err
+}
+
+// ReadFrom 会从给定的读取器中读出由WriteTo方法序列化的过滤器。
+func ReadFrom(r io.Reader) (Filter, error) {
+	var header [len(magic)]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if string(header[:]) != magic {
+		return nil, ErrInvalidData
+	}
+	filter := &myFilter{}
+	var stageNumber uint32
+	for _, v := range []interface{}{
+		&filter.initialCapacity, &filter.fpRate, &filter.count, &stageNumber} {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			return nil, err
+		}
+	}
+	if filter.initialCapacity == 0 || !(filter.fpRate > 0 && filter.fpRate < 1) ||
+		stageNumber == 0 || stageNumber > 64 {
+		return nil, ErrInvalidData
+	}
+	for i := uint32(0); i < stageNumber; i++ {
+		s := &stage{}
+		for _, v := range []interface{}{&s.capacity, &s.k, &s.m, &s.count} {
+			if err := binary.Read(r, binary.BigEndian, v); err != nil {
+				return nil, err
+			}
+		}
+		if s.k == 0 || s.m == 0 || s.m > 1<<40 {
+			return nil, ErrInvalidData
+		}
+		s.bits = make([]uint64, (s.m+63)/64)
+		if err := binary.Read(r, binary.BigEndian, s.bits); err != nil {
+			return nil, err
+		}
+		filter.stages = append(filter.stages, s)
+	}
+	return filter, nil
+}
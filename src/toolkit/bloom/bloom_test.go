@@ -0,0 +1,114 @@
+package bloom
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestBloomNew(t *testing.T) {
+	if _, err := New(0, 0.01); err == nil {
+		t.Fatal("No error when new a bloom filter with zero capacity, but should not be the case!")
+	}
+	for _, fpRate := range []float64{0, 1, -0.1, 1.5} {
+		if _, err := New(100, fpRate); err == nil {
+			t.Fatalf("No error when new a bloom filter with FP rate %v, but should not be the case!",
+				fpRate)
+		}
+	}
+}
+
+func TestBloomAddAndTest(t *testing.T) {
+	fpRate := 0.01
+	filter, err := New(100, fpRate)
+	if err != nil {
+		t.Fatalf("An error occurs when new a bloom filter: %s", err)
+	}
+	number := 10000
+	for i := 0; i < number; i++ {
+		filter.AddString("http://example.com/" + strconv.Itoa(i))
+	}
+	if filter.StageNumber() < 2 {
+		t.Fatalf("The bloom filter did not grow: stage number: %d", filter.StageNumber())
+	}
+	for i := 0; i < number; i++ {
+		if !filter.TestString("http://example.com/" + strconv.Itoa(i)) {
+			t.Fatalf("False negative for element %d!", i)
+		}
+	}
+	// 添加时遇到误判的元素不会被计数。
+	if filter.Count() > uint64(number) || filter.Count() < uint64(float64(number)*(1-fpRate)) {
+		t.Fatalf("Inconsistent count: %d", filter.Count())
+	}
+	var fp int
+	for i := 0; i < number; i++ {
+		if filter.TestString("http://example.org/" + strconv.Itoa(i)) {
+			fp++
+		}
+	}
+	if actual := float64(fp) / float64(number); actual > fpRate*2 {
+		t.Fatalf("Too high false positive rate: expected: <=%v, actual: %v", fpRate, actual)
+	}
+	if estimated := filter.EstimatedFPRate(); estimated <= 0 || estimated > fpRate {
+		t.Fatalf("Inconsistent estimated false positive rate: %v", estimated)
+	}
+	if filter.MemoryBytes() == 0 {
+		t.Fatal("Zero memory bytes!")
+	}
+}
+
+func TestBloomConcurrentAdd(t *testing.T) {
+	filter, _ := New(1000, 0.001)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				filter.AddString(strconv.Itoa(g) + "-" + strconv.Itoa(i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 1000; i++ {
+			if !filter.TestString(strconv.Itoa(g) + "-" + strconv.Itoa(i)) {
+				t.Fatalf("False negative for element %d-%d!", g, i)
+			}
+		}
+	}
+}
+
+func TestBloomSerialization(t *testing.T) {
+	filter, _ := New(50, 0.01)
+	for i := 0; i < 500; i++ {
+		filter.AddString(strconv.Itoa(i))
+	}
+	var buffer bytes.Buffer
+	n, err := filter.WriteTo(&buffer)
+	if err != nil {
+		t.Fatalf("An error occurs when writing the bloom filter: %s", err)
+	}
+	if n != int64(buffer.Len()) {
+		t.Fatalf("Inconsistent written bytes: expected: %d, actual: %d", buffer.Len(), n)
+	}
+	loaded, err := ReadFrom(&buffer)
+	if err != nil {
+		t.Fatalf("An error occurs when reading the bloom filter: %s", err)
+	}
+	if loaded.Count() != filter.Count() ||
+		loaded.StageNumber() != filter.StageNumber() ||
+		loaded.MemoryBytes() != filter.MemoryBytes() ||
+		loaded.FPRate() != filter.FPRate() {
+		t.Fatal("The loaded bloom filter is different from the original one!")
+	}
+	for i := 0; i < 500; i++ {
+		if !loaded.TestString(strconv.Itoa(i)) {
+			t.Fatalf("False negative for element %d in the loaded bloom filter!", i)
+		}
+	}
+	if _, err := ReadFrom(bytes.NewReader([]byte("XXXX"))); err != ErrInvalidData {
+		t.Fatalf("Inconsistent error for invalid data: %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+package bloom
+
+import "errors"
+
+// ErrInvalidData 代表无法被反序列化的数据的错误。
+var ErrInvalidData = errors.New("invalid bloom filter data")
@@ -0,0 +1,444 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errs"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// DEFAULT_SEGMENT_SIZE 代表段文件的默认最大字节数。
+const DEFAULT_SEGMENT_SIZE int64 = 16 << 20
+
+// Codec 代表数据编解码器的接口类型。
+// 磁盘缓冲池用它把数据写入段文件以及从段文件中读出数据。
+type Codec interface {
+	Encode(datum interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// DiskPool 代表可以把溢出的数据存放到磁盘上的缓冲池的接口类型。
+// 内存中最多存放BufferCap()*MaxBufferNumber()个数据，
+// 超出的数据会被追加到磁盘上的段文件中。
+// 因此它的Put方法不会因缓冲池已满而阻塞。
+// 它的Get方法会在没有数据时阻塞，直到缓冲池被关闭。
+// 读取段文件失败时，Get方法会丢弃该段文件中尚未读出的数据，
+// 无法解码的数据也会被丢弃。此时Get方法会返回*DroppedDataError类型的错误值，
+// 但缓冲池仍然可用。只有在缓冲池已关闭时才会返回ErrClosedBufferPool。
+type DiskPool interface {
+	Pool
+	// SpilledTotal 会返回存放在磁盘上的数据的数量。
+	SpilledTotal() uint64
+	// SegmentNumber 会返回磁盘上的段文件的数量。
+	SegmentNumber() uint32
+	// Range 会按照先进先出的顺序对尚未取出的每个数据调用给定的函数，
+	// 直到该函数返回false。存放在磁盘上的数据会被重新读出，无法解码的数据会被跳过。
+	// 遍历期间缓冲池的其他操作会被阻塞。缓冲池已关闭时会返回ErrClosedBufferPool。
+	Range(f func(datum interface{}) bool) error
+}
+
+// segment 代表磁盘上的段文件。
+type segment struct {
+	path string
+	// written 代表已写入的记录数。
+	written uint64
+	// read 代表已读出的记录数。
+	read uint64
+	// size 代表已写入的字节数。
+	size int64
+	// offset 代表已读出的字节数。
+	offset int64
+}
+
+// myDiskPool 代表磁盘缓冲池的实现类型。
+type myDiskPool struct {
+	bufferCap       uint32
+	maxBufferNumber uint32
+	codec           Codec
+	// dirPath 代表存放段文件的目录。
+	dirPath     string
+	segmentSize int64
+	// memory 代表内存中的数据队列。
+	memory []interface{}
+	// segments 代表尚未读完的段文件的列表。
+	// 最后一个是当前的写入段，第一个是当前的读取段。
+	segments []*segment
+	// nextSegmentID 代表下一个段文件的序号。
+	nextSegmentID uint64
+	writeFile     *os.File
+	writer        *bufio.Writer
+	readFile      *os.File
+	reader        *bufio.Reader
+	// spilled 代表存放在磁盘上的数据的数量。
+	spilled  uint64
+	total    uint64
+	closed   uint32
+	lock     sync.Mutex
+	notEmpty *sync.Cond
+}
+
+// NewDiskPool 会创建一个磁盘缓冲池。
+// 参数bufferCap和maxBufferNumber的乘积代表内存中最多存放的数据的数量。
+// 参数dirPath代表存放段文件的目录，缓冲池会在其中创建专属的子目录。
+// 参数codec代表数据编解码器。
+func NewDiskPool(
+	bufferCap uint32,
+	maxBufferNumber uint32,
+	dirPath string,
+	codec Codec) (DiskPool, error) {
+	if bufferCap == 0 {
+		errMsg := fmt.Sprintf("illegal buffer cap for disk pool: %d", bufferCap)
+		return nil, errs.NewIllegalParameterError(errMsg)
+	}
+	if maxBufferNumber == 0 {
+		errMsg := fmt.Sprintf("illegal max buffer number for disk pool: %d", maxBufferNumber)
+		return nil, errs.NewIllegalParameterError(errMsg)
+	}
+	if dirPath == "" {
+		return nil, errs.NewIllegalParameterError("empty dir path for disk pool")
+	}
+	if codec == nil {
+		return nil, errs.NewIllegalParameterError("nil codec for disk pool")
+	}
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return nil, err
+	}
+	poolDirPath, err := ioutil.TempDir(dirPath, "pool-")
+	if err != nil {
+		return nil, err
+	}
+	pool := &myDiskPool{
+		bufferCap:       bufferCap,
+		maxBufferNumber: maxBufferNumber,
+		codec:           codec,
+		dirPath:         poolDirPath,
+		segmentSize:     DEFAULT_SEGMENT_SIZE,
+	}
+	pool.notEmpty = sync.NewCond(&pool.lock)
+	return pool, nil
+}
+
+func (pool *myDiskPool) BufferCap() uint32 {
+	return pool.bufferCap
+}
+
+func (pool *myDiskPool) MaxBufferNumber() uint32 {
+	return pool.maxBufferNumber
+}
+
+// BufferNumber 会返回内存中的数据按缓冲器容量计算所占的缓冲器数量。
+func (pool *myDiskPool) BufferNumber() uint32 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	length := uint32(len(pool.memory))
+	return (length + pool.bufferCap - 1) / pool.bufferCap
+}
+
+func (pool *myDiskPool) Total() uint64 {
+	return atomic.LoadUint64(&pool.total)
+}
+
+func (pool *myDiskPool) SpilledTotal() uint64 {
+	return atomic.LoadUint64(&pool.spilled)
+}
+
+func (pool *myDiskPool) SegmentNumber() uint32 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	return uint32(len(pool.segments))
+}
+
+// memoryCap 代表内存中最多存放的数据的数量。
+func (pool *myDiskPool) memoryCap() int {
+	return int(pool.bufferCap) * int(pool.maxBufferNumber)
+}
+
+func (pool *myDiskPool) Put(datum interface{}) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.Closed() {
+		return ErrClosedBufferPool
+	}
+	// 只要磁盘上还有数据，新数据就必须追加到磁盘上，以保证先进先出。
+	if pool.spilled == 0 && len(pool.memory) < pool.memoryCap() {
+		pool.memory = append(pool.memory, datum)
+	} else if err := pool.spill(datum); err != nil {
+		return err
+	}
+	atomic.AddUint64(&pool.total, 1)
+	pool.notEmpty.Signal()
+	return nil
+}
+
+// spill 用于把数据追加到当前的写入段。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (pool *myDiskPool) spill(datum interface{}) error {
+	data, err := pool.codec.Encode(datum)
+	if err != nil {
+		return err
+	}
+	if pool.writer == nil || pool.segments[len(pool.segments)-1].size >= pool.segmentSize {
+		if err = pool.rotate(); err != nil {
+			return err
+		}
+	}
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(data)))
+	if _, err = pool.writer.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err = pool.writer.Write(data); err != nil {
+		return err
+	}
+	current := pool.segments[len(pool.segments)-1]
+	current.written++
+	current.size += int64(len(header) + len(data))
+	atomic.AddUint64(&pool.spilled, 1)
+	return nil
+}
+
+// rotate 用于关闭当前的写入段并创建新的写入段。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (pool *myDiskPool) rotate() error {
+	if pool.writer != nil {
+		if err := pool.writer.Flush(); err != nil {
+			return err
+		}
+		if err := pool.writeFile.Close(); err != nil {
+			return err
+		}
+		pool.writer = nil
+		pool.writeFile = nil
+	}
+	path := filepath.Join(pool.dirPath,
+		fmt.Sprintf("%020d.seg", pool.nextSegmentID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	pool.nextSegmentID++
+	pool.writeFile = file
+	pool.writer = bufio.NewWriter(file)
+	pool.segments = append(pool.segments, &segment{path: path})
+	return nil
+}
+
+func (pool *myDiskPool) Get() (datum interface{}, err error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	for {
+		if pool.Closed() {
+			return nil, ErrClosedBufferPool
+		}
+		if len(pool.memory) > 0 {
+			break
+		}
+		if pool.spilled > 0 {
+			if err = pool.load(); err != nil {
+				if dropped, ok := err.(*DroppedDataError); ok {
+					return nil, dropped
+				}
+				lost := pool.dropFirstSegment()
+				return nil, &DroppedDataError{Number: lost, Err: err}
+			}
+			continue
+		}
+		pool.notEmpty.Wait()
+	}
+	datum = pool.memory[0]
+	pool.memory[0] = nil
+	pool.memory = pool.memory[1:]
+	atomic.AddUint64(&pool.total, ^uint64(0))
+	return datum, nil
+}
+
+// load 用于从磁盘上读出一批数据放入内存，最多读出BufferCap()个。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (pool *myDiskPool) load() error {
+	for len(pool.memory) < int(pool.bufferCap) && pool.spilled > 0 {
+		first := pool.segments[0]
+		if first.read == first.written {
+			if len(pool.segments) == 1 {
+				break
+			}
+			pool.removeFirstSegment()
+			continue
+		}
+		if pool.readFile == nil {
+			file, err := os.Open(first.path)
+			if err != nil {
+				return err
+			}
+			pool.readFile = file
+			pool.reader = bufio.NewReader(file)
+		}
+		if len(pool.segments) == 1 {
+			// 读取段也是写入段，需要先把已缓冲的数据写入文件。
+			if err := pool.writer.Flush(); err != nil {
+				return err
+			}
+		}
+		data, err := readRecord(pool.reader, first.size-first.offset)
+		if err != nil {
+			return err
+		}
+		datum, err := pool.codec.Decode(data)
+		first.read++
+		first.offset += int64(4 + len(data))
+		atomic.AddUint64(&pool.spilled, ^uint64(0))
+		if err != nil {
+			// 无法解码的数据会被丢弃，已读出的数据仍会留在内存中。
+			atomic.AddUint64(&pool.total, ^uint64(0))
+			return &DroppedDataError{Number: 1, Err: err}
+		}
+		pool.memory = append(pool.memory, datum)
+	}
+	return nil
+}
+
+// readRecord 用于从给定的读取器读出一条记录。
+// 参数remaining代表段文件中尚未读出的字节数。
+// 长度超出它的记录必定已损坏，因此不会为其分配内存。
+func readRecord(reader io.Reader, remaining int64) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if int64(length) > remaining-int64(len(header)) {
+		return nil, fmt.Errorf("illegal record length: %d (remaining: %d)",
+			length, remaining-int64(len(header)))
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (pool *myDiskPool) Range(f func(datum interface{}) bool) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.Closed() {
+		return ErrClosedBufferPool
+	}
+	for _, datum := range pool.memory {
+		if !f(datum) {
+			return nil
+		}
+	}
+	if pool.spilled == 0 {
+		return nil
+	}
+	if pool.writer != nil {
+		if err := pool.writer.Flush(); err != nil {
+			return err
+		}
+	}
+	for _, seg := range pool.segments {
+		next, err := pool.rangeSegment(seg, f)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+// rangeSegment 用于对给定段文件中尚未读出的每个数据调用给定的函数。
+// 结果值next为false代表给定的函数要求停止遍历。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (pool *myDiskPool) rangeSegment(
+	seg *segment, f func(datum interface{}) bool) (next bool, err error) {
+	if seg.read == seg.written {
+		return true, nil
+	}
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	if _, err = file.Seek(seg.offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	reader := bufio.NewReader(file)
+	offset := seg.offset
+	for i := seg.read; i < seg.written; i++ {
+		data, err := readRecord(reader, seg.size-offset)
+		if err != nil {
+			return false, err
+		}
+		offset += int64(4 + len(data))
+		datum, err := pool.codec.Decode(data)
+		if err != nil {
+			continue
+		}
+		if !f(datum) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// dropFirstSegment 用于丢弃读取段中尚未读出的数据并删除该段，
+// 返回被丢弃的数据的数量。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (pool *myDiskPool) dropFirstSegment() uint64 {
+	first := pool.segments[0]
+	lost := first.written - first.read
+	if lost > 0 {
+		atomic.AddUint64(&pool.spilled, ^(lost - 1))
+		atomic.AddUint64(&pool.total, ^(lost - 1))
+	}
+	if len(pool.segments) == 1 && pool.writer != nil {
+		// 读取段也是写入段，之后的数据需要写入新的段文件。
+		pool.writeFile.Close()
+		pool.writer = nil
+		pool.writeFile = nil
+	}
+	pool.removeFirstSegment()
+	return lost
+}
+
+// removeFirstSegment 用于关闭并删除已读完的读取段。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (pool *myDiskPool) removeFirstSegment() {
+	if pool.readFile != nil {
+		pool.readFile.Close()
+		pool.readFile = nil
+		pool.reader = nil
+	}
+	os.Remove(pool.segments[0].path)
+	pool.segments[0] = nil
+	pool.segments = pool.segments[1:]
+}
+
+// Close 会关闭缓冲池并删除所有段文件。
+// 尚未取出的数据会被丢弃。
+func (pool *myDiskPool) Close() bool {
+	if !atomic.CompareAndSwapUint32(&pool.closed, 0, 1) {
+		return false
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.writeFile != nil {
+		pool.writeFile.Close()
+	}
+	if pool.readFile != nil {
+		pool.readFile.Close()
+	}
+	os.RemoveAll(pool.dirPath)
+	pool.memory = nil
+	pool.segments = nil
+	pool.notEmpty.Broadcast()
+	return true
+}
+
+func (pool *myDiskPool) Closed() bool {
+	return atomic.LoadUint32(&pool.closed) == 1
+}
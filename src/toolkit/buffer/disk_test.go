@@ -0,0 +1,257 @@
+package buffer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// intCodec 代表用于测试的整数编解码器。
+type intCodec struct{}
+
+func (intCodec) Encode(datum interface{}) ([]byte, error) {
+	i, ok := datum.(int)
+	if !ok {
+		return nil, errors.New("not an int")
+	}
+	return []byte(strconv.Itoa(i)), nil
+}
+
+func (intCodec) Decode(data []byte) (interface{}, error) {
+	return strconv.Atoi(string(data))
+}
+
+func TestDiskPoolNew(t *testing.T) {
+	dirPath, _ := ioutil.TempDir("", "disk_pool_test")
+	defer os.RemoveAll(dirPath)
+	if _, err := NewDiskPool(0, 1, dirPath, intCodec{}); err == nil {
+		t.Fatal("No error when new a disk pool with zero buffer cap, but should not be the case!")
+	}
+	if _, err := NewDiskPool(1, 0, dirPath, intCodec{}); err == nil {
+		t.Fatal("No error when new a disk pool with zero max buffer number, but should not be the case!")
+	}
+	if _, err := NewDiskPool(1, 1, "", intCodec{}); err == nil {
+		t.Fatal("No error when new a disk pool with empty dir path, but should not be the case!")
+	}
+	if _, err := NewDiskPool(1, 1, dirPath, nil); err == nil {
+		t.Fatal("No error when new a disk pool with nil codec, but should not be the case!")
+	}
+}
+
+func TestDiskPoolPutAndGet(t *testing.T) {
+	dirPath, _ := ioutil.TempDir("", "disk_pool_test")
+	defer os.RemoveAll(dirPath)
+	pool, err := NewDiskPool(2, 2, dirPath, intCodec{})
+	if err != nil {
+		t.Fatalf("An error occurs when new a disk pool: %s", err)
+	}
+	// 使每个段文件只能存放少量数据，以便测试段文件的轮转。
+	pool.(*myDiskPool).segmentSize = 16
+	number := 100
+	for i := 0; i < number; i++ {
+		if err := pool.Put(i); err != nil {
+			t.Fatalf("An error occurs when putting datum %d: %s", i, err)
+		}
+	}
+	if pool.Total() != uint64(number) {
+		t.Fatalf("Inconsistent total: expected: %d, actual: %d",
+			number, pool.Total())
+	}
+	expectedSpilled := uint64(number - 4)
+	if pool.SpilledTotal() != expectedSpilled {
+		t.Fatalf("Inconsistent spilled total: expected: %d, actual: %d",
+			expectedSpilled, pool.SpilledTotal())
+	}
+	if pool.SegmentNumber() <= 1 {
+		t.Fatalf("Too few segments: %d", pool.SegmentNumber())
+	}
+	for i := 0; i < number; i++ {
+		datum, err := pool.Get()
+		if err != nil {
+			t.Fatalf("An error occurs when getting datum: %s", err)
+		}
+		if datum != i {
+			t.Fatalf("Inconsistent datum: expected: %d, actual: %v", i, datum)
+		}
+		// 在读取期间继续放入数据，以检验读写同一个段文件的情况。
+		if i == number/2 {
+			pool.Put(number)
+			number++
+		}
+	}
+	if pool.Total() != 0 {
+		t.Fatalf("Inconsistent total: expected: %d, actual: %d", 0, pool.Total())
+	}
+	if !pool.Close() {
+		t.Fatal("Couldn't close the disk pool!")
+	}
+	if _, err := pool.Get(); err != ErrClosedBufferPool {
+		t.Fatalf("Inconsistent error: expected: %s, actual: %v", ErrClosedBufferPool, err)
+	}
+	if _, err := os.Stat(pool.(*myDiskPool).dirPath); !os.IsNotExist(err) {
+		t.Fatal("The segment files haven't been removed after closing!")
+	}
+}
+
+func TestDiskPoolRange(t *testing.T) {
+	dirPath, _ := ioutil.TempDir("", "disk_pool_test")
+	defer os.RemoveAll(dirPath)
+	pool, err := NewDiskPool(2, 2, dirPath, intCodec{})
+	if err != nil {
+		t.Fatalf("An error occurs when new a disk pool: %s", err)
+	}
+	pool.(*myDiskPool).segmentSize = 16
+	number := 20
+	for i := 0; i < number; i++ {
+		if err := pool.Put(i); err != nil {
+			t.Fatalf("An error occurs when putting datum %d: %s", i, err)
+		}
+	}
+	// 取出一部分数据，使读取段中既有已读出的数据，也有尚未读出的数据。
+	taken := 7
+	for i := 0; i < taken; i++ {
+		if _, err := pool.Get(); err != nil {
+			t.Fatalf("An error occurs when getting datum: %s", err)
+		}
+	}
+	var data []int
+	if err := pool.Range(func(datum interface{}) bool {
+		data = append(data, datum.(int))
+		return true
+	}); err != nil {
+		t.Fatalf("An error occurs when ranging over the disk pool: %s", err)
+	}
+	if len(data) != number-taken {
+		t.Fatalf("Inconsistent datum number: expected: %d, actual: %d",
+			number-taken, len(data))
+	}
+	for i, datum := range data {
+		if datum != taken+i {
+			t.Fatalf("Inconsistent datum: expected: %d, actual: %d", taken+i, datum)
+		}
+	}
+	// 遍历不会取出数据。
+	if pool.Total() != uint64(number-taken) {
+		t.Fatalf("Inconsistent total: expected: %d, actual: %d",
+			number-taken, pool.Total())
+	}
+	if datum, err := pool.Get(); err != nil || datum != taken {
+		t.Fatalf("Inconsistent result: expected: %d, actual: %v (error: %v)", taken, datum, err)
+	}
+	var count int
+	pool.Range(func(datum interface{}) bool {
+		count++
+		return count < 3
+	})
+	if count != 3 {
+		t.Fatalf("Inconsistent visited datum number: expected: %d, actual: %d", 3, count)
+	}
+	pool.Close()
+	if err := pool.Range(func(datum interface{}) bool { return true }); err != ErrClosedBufferPool {
+		t.Fatalf("Inconsistent error: expected: %s, actual: %v", ErrClosedBufferPool, err)
+	}
+}
+
+func TestDiskPoolBrokenSegment(t *testing.T) {
+	dirPath, _ := ioutil.TempDir("", "disk_pool_test")
+	defer os.RemoveAll(dirPath)
+	pool, err := NewDiskPool(1, 1, dirPath, intCodec{})
+	if err != nil {
+		t.Fatalf("An error occurs when new a disk pool: %s", err)
+	}
+	defer pool.Close()
+	for i := 0; i < 3; i++ {
+		if err := pool.Put(i); err != nil {
+			t.Fatalf("An error occurs when putting datum %d: %s", i, err)
+		}
+	}
+	if datum, err := pool.Get(); err != nil || datum != 0 {
+		t.Fatalf("Inconsistent result: expected: %d, actual: %v (error: %v)", 0, datum, err)
+	}
+	// 删除尚未被读取的段文件，以模拟读取段文件失败的情况。
+	paths, _ := filepath.Glob(filepath.Join(pool.(*myDiskPool).dirPath, "*.seg"))
+	for _, path := range paths {
+		os.Remove(path)
+	}
+	_, err = pool.Get()
+	if dropped, ok := err.(*DroppedDataError); !ok || dropped.Number != 2 {
+		t.Fatalf("Inconsistent error: expected: 2 dropped data, actual: %v", err)
+	}
+	if pool.Total() != 0 || pool.SpilledTotal() != 0 {
+		t.Fatalf("Inconsistent total: expected: 0/0, actual: %d/%d",
+			pool.Total(), pool.SpilledTotal())
+	}
+	// 丢弃损坏的段文件之后缓冲池应该仍然可用。
+	for i := 3; i < 5; i++ {
+		if err := pool.Put(i); err != nil {
+			t.Fatalf("An error occurs when putting datum %d: %s", i, err)
+		}
+	}
+	for i := 3; i < 5; i++ {
+		if datum, err := pool.Get(); err != nil || datum != i {
+			t.Fatalf("Inconsistent result: expected: %d, actual: %v (error: %v)", i, datum, err)
+		}
+	}
+}
+
+func TestDiskPoolCorruptRecord(t *testing.T) {
+	dirPath, _ := ioutil.TempDir("", "disk_pool_test")
+	defer os.RemoveAll(dirPath)
+	cases := []struct {
+		name string
+		// offset 代表被改写的字节在段文件中的位置。
+		offset  int64
+		content []byte
+		// dropped 代表被丢弃的数据的数量。
+		dropped uint64
+		// rest 代表丢弃之后还能被取出的数据。
+		rest []int
+	}{
+		// 第一条记录的长度被改写为一个极大的值。
+		{"length", 0, []byte{0xff, 0xff, 0xff, 0xff}, 2, nil},
+		// 第一条记录的内容无法被解码。
+		{"content", 4, []byte("x"), 1, []int{2}},
+	}
+	for _, c := range cases {
+		pool, err := NewDiskPool(1, 1, dirPath, intCodec{})
+		if err != nil {
+			t.Fatalf("An error occurs when new a disk pool: %s", err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := pool.Put(i); err != nil {
+				t.Fatalf("An error occurs when putting datum %d: %s", i, err)
+			}
+		}
+		if datum, err := pool.Get(); err != nil || datum != 0 {
+			t.Fatalf("Inconsistent result: expected: %d, actual: %v (error: %v)", 0, datum, err)
+		}
+		// 遍历会把已缓冲的数据写入段文件。
+		pool.Range(func(datum interface{}) bool { return true })
+		segment := pool.(*myDiskPool).segments[0]
+		file, err := os.OpenFile(segment.path, os.O_WRONLY, 0600)
+		if err != nil {
+			t.Fatalf("An error occurs when opening segment file: %s", err)
+		}
+		file.WriteAt(c.content, c.offset)
+		file.Close()
+		_, err = pool.Get()
+		if dropped, ok := err.(*DroppedDataError); !ok || dropped.Number != c.dropped {
+			t.Fatalf("Inconsistent error for the %s case: expected: %d dropped data, actual: %v",
+				c.name, c.dropped, err)
+		}
+		for _, i := range c.rest {
+			if datum, err := pool.Get(); err != nil || datum != i {
+				t.Fatalf("Inconsistent result for the %s case: expected: %d, actual: %v (error: %v)",
+					c.name, i, datum, err)
+			}
+		}
+		if pool.Total() != 0 || pool.SpilledTotal() != 0 {
+			t.Fatalf("Inconsistent total for the %s case: expected: 0/0, actual: %d/%d",
+				c.name, pool.Total(), pool.SpilledTotal())
+		}
+		pool.Close()
+	}
+}
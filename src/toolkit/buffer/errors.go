@@ -1,6 +1,21 @@
 package buffer
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // ErrClosedBufferPool 是表示缓冲池已关闭的错误的变量。
 var ErrClosedBufferPool = errors.New("closed buffer pool")
+
+// DroppedDataError 代表磁盘缓冲池因读取段文件失败而丢弃了数据的错误类型。
+type DroppedDataError struct {
+	// Number 代表被丢弃的数据的数量。
+	Number uint64
+	// Err 代表导致数据被丢弃的错误值。
+	Err error
+}
+
+func (err *DroppedDataError) Error() string {
+	return fmt.Sprintf("dropped %d spilled data: %s", err.Number, err.Err)
+}
@@ -0,0 +1,209 @@
+package canonicalizer
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams 代表常见的跟踪参数的名称。
+// 以"*"结尾的名称代表前缀。
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"gclid",
+	"dclid",
+	"fbclid",
+	"msclkid",
+	"yclid",
+	"mc_cid",
+	"mc_eid",
+	"_ga",
+	"_hsenc",
+	"_hsmi",
+}
+
+// defaultPorts 代表各协议的默认端口号。
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalizer 代表URL规范化器的接口类型。
+// 同一URL的不同写法经规范化后应得到相同的结果，
+// 并且对规范化的结果再次规范化应得到相同的结果。
+type Canonicalizer interface {
+	// Canonicalize 会返回给定URL的规范形式。
+	Canonicalize(u *url.URL) string
+}
+
+// Options 代表URL规范化的选项。
+type Options struct {
+	// TrackingParams 代表需要被移除的查询参数的名称。
+	// 以"*"结尾的名称代表前缀。名称不区分大小写。
+	TrackingParams []string
+	// KeepFragment 代表是否保留片段标识。
+	KeepFragment bool
+	// KeepQueryOrder 代表是否保持查询参数的原有顺序。
+	KeepQueryOrder bool
+}
+
+// myCanonicalizer 代表URL规范化器的实现类型。
+type myCanonicalizer struct {
+	// trackingParams 代表需要被移除的查询参数的名称的集合。
+	trackingParams map[string]bool
+	// trackingPrefixes 代表需要被移除的查询参数的名称前缀。
+	trackingPrefixes []string
+	keepFragment     bool
+	keepQueryOrder   bool
+}
+
+// New 会创建一个URL规范化器。
+// 它会依次进行以下处理：
+//  1. 把协议和主机名转换为小写，并去掉主机名末尾的点。
+//  2. 去掉协议的默认端口号。
+//  3. 规范化百分号编码：解码非保留字符，其余编码中的十六进制数字转换为大写，
+//     必须编码的字符会被编码。
+//  4. 解析路径中的"."和".."，空路径会变为"/"。
+//  5. 移除跟踪参数和空的查询参数，并按名称和值对查询参数排序。
+//  6. 去掉片段标识。
+func New(options Options) Canonicalizer {
+	c := &myCanonicalizer{
+		trackingParams: map[string]bool{},
+		keepFragment:   options.KeepFragment,
+		keepQueryOrder: options.KeepQueryOrder,
+	}
+	for _, name := range options.TrackingParams {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if strings.HasSuffix(name, "*") {
+			c.trackingPrefixes = append(c.trackingPrefixes, strings.TrimSuffix(name, "*"))
+		} else {
+			c.trackingParams[name] = true
+		}
+	}
+	return c
+}
+
+// Canonicalize 会返回给定URL的规范形式。
+// 对于不透明的URL（如"mailto:"），只把协议转换为小写。
+func (c *myCanonicalizer) Canonicalize(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if u.Opaque != "" {
+		return scheme + ":" + u.Opaque
+	}
+	var buffer strings.Builder
+	if scheme != "" {
+		buffer.WriteString(scheme)
+		buffer.WriteString(":")
+	}
+	if scheme != "" || u.Host != "" {
+		buffer.WriteString("//")
+		if u.User != nil {
+			buffer.WriteString(u.User.String())
+			buffer.WriteString("@")
+		}
+		buffer.WriteString(canonicalHost(scheme, u.Host))
+	}
+	path := removeDotSegments(normalizeEscapes(u.EscapedPath(), false))
+	if path == "" && u.Host != "" {
+		path = "/"
+	}
+	buffer.WriteString(path)
+	if query := c.canonicalQuery(u.RawQuery); query != "" {
+		buffer.WriteString("?")
+		buffer.WriteString(query)
+	}
+	if c.keepFragment && u.Fragment != "" {
+		buffer.WriteString("#")
+		buffer.WriteString(normalizeEscapes(u.EscapedFragment(), true))
+	}
+	return buffer.String()
+}
+
+// canonicalHost 用于规范化给定的主机名（可以带有端口号）。
+func canonicalHost(scheme string, host string) string {
+	host = strings.ToLower(host)
+	hostname, port := host, ""
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		hostname, port = h, p
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		hostname = host[1 : len(host)-1]
+	}
+	hostname = strings.TrimSuffix(hostname, ".")
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+	if strings.Contains(hostname, ":") {
+		hostname = "[" + hostname + "]"
+	}
+	if port != "" {
+		return hostname + ":" + port
+	}
+	return hostname
+}
+
+// canonicalQuery 用于规范化给定的查询字符串。
+func (c *myCanonicalizer) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	kept := make([]string, 0, len(params))
+	for _, param := range params {
+		if param == "" {
+			continue
+		}
+		param = normalizeEscapes(param, true)
+		if c.tracking(param) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if !c.keepQueryOrder {
+		// 先按名称排序，名称相同的再按值排序。
+		sort.SliceStable(kept, func(i, j int) bool {
+			iName, iValue := splitParam(kept[i])
+			jName, jValue := splitParam(kept[j])
+			if iName != jName {
+				return iName < jName
+			}
+			return iValue < jValue
+		})
+	}
+	return strings.Join(kept, "&")
+}
+
+// tracking 用于判断给定的查询参数是否为跟踪参数。
+func (c *myCanonicalizer) tracking(param string) bool {
+	if len(c.trackingParams) == 0 && len(c.trackingPrefixes) == 0 {
+		return false
+	}
+	name, _ := splitParam(param)
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		name = unescaped
+	}
+	name = strings.ToLower(name)
+	if c.trackingParams[name] {
+		return true
+	}
+	for _, prefix := range c.trackingPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitParam 用于把查询参数拆分为名称和值。
+func splitParam(param string) (name string, value string) {
+	if i := strings.Index(param, "="); i >= 0 {
+		return param[:i], param[i+1:]
+	}
+	return param, ""
+}
@@ -0,0 +1,82 @@
+package canonicalizer
+
+import (
+	"net/url"
+	"testing"
+)
+
+func canonicalize(t *testing.T, c Canonicalizer, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("An error occurs when parsing URL %q: %s", rawURL, err)
+	}
+	return c.Canonicalize(u)
+}
+
+func TestCanonicalize(t *testing.T) {
+	c := New(Options{})
+	cases := map[string]string{
+		"HTTP://Example.com:80/a/../b?b=2&a=1#frag": "http://example.com/b?a=1&b=2",
+		"http://example.com/b?a=1&b=2":              "http://example.com/b?a=1&b=2",
+		"https://example.com:443":                   "https://example.com/",
+		"https://example.com:8443/":                 "https://example.com:8443/",
+		"http://example.com./a/./b/../c/":           "http://example.com/a/c/",
+		"http://example.com/../../a":                "http://example.com/a",
+		"http://example.com/a/..":                   "http://example.com/",
+		"http://example.com/%7euser/%2e/x":          "http://example.com/~user/x",
+		"http://example.com/a%2fb%3f":               "http://example.com/a%2Fb%3F",
+		"http://example.com/a b/c":                  "http://example.com/a%20b/c",
+		"http://example.com/?&&b=1&&a":              "http://example.com/?a&b=1",
+		"http://example.com/?a=2&a=1":               "http://example.com/?a=1&a=2",
+		"http://[::1]:80/x":                         "http://[::1]/x",
+		"http://user@Example.com/":                  "http://user@example.com/",
+		"mailto:Someone@Example.com":                "mailto:Someone@Example.com",
+	}
+	for rawURL, expected := range cases {
+		actual := canonicalize(t, c, rawURL)
+		if actual != expected {
+			t.Fatalf("Inconsistent canonical URL for %q: expected: %s, actual: %s",
+				rawURL, expected, actual)
+		}
+		// 规范化应是幂等的。
+		if again := canonicalize(t, c, actual); again != actual {
+			t.Fatalf("Canonicalization is not idempotent for %q: %s, %s",
+				rawURL, actual, again)
+		}
+	}
+}
+
+func TestCanonicalizeWithOptions(t *testing.T) {
+	c := New(Options{
+		TrackingParams: DefaultTrackingParams,
+		KeepFragment:   true,
+		KeepQueryOrder: true,
+	})
+	cases := map[string]string{
+		"http://example.com/?utm_source=x&b=2&UTM_Medium=y&a=1&gclid=z": "http://example.com/?b=2&a=1",
+		"http://example.com/?utm_source=x":                              "http://example.com/",
+		"http://example.com/a#Frag%7e":                                  "http://example.com/a#Frag~",
+	}
+	for rawURL, expected := range cases {
+		actual := canonicalize(t, c, rawURL)
+		if actual != expected {
+			t.Fatalf("Inconsistent canonical URL for %q: expected: %s, actual: %s",
+				rawURL, expected, actual)
+		}
+	}
+}
+
+func TestNormalizeEscapes(t *testing.T) {
+	cases := map[string]string{
+		"%zz%4a%4A": "%25zzJJ",
+		"%2f%e4%B8": "%2F%E4%B8",
+		"a?b#c":     "a%3Fb%23c",
+		"é":         "%C3%A9",
+	}
+	for s, expected := range cases {
+		if actual := normalizeEscapes(s, false); actual != expected {
+			t.Fatalf("Inconsistent normalized escapes for %q: expected: %s, actual: %s",
+				s, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package canonicalizer
+
+import (
+	"strings"
+)
+
+const upperHex = "0123456789ABCDEF"
+
+// isUnreserved 用于判断给定的字节是否为非保留字符，参见RFC 3986。
+func isUnreserved(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	switch b {
+	case '-', '.', '_', '~':
+		return true
+	}
+	return false
+}
+
+// isAllowed 用于判断给定的字节是否可以不经编码出现在路径或查询字符串中。
+func isAllowed(b byte, inQuery bool) bool {
+	if isUnreserved(b) {
+		return true
+	}
+	switch b {
+	case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '@', '/':
+		return true
+	case '?':
+		return inQuery
+	}
+	return false
+}
+
+// isHex 用于判断给定的字节是否为十六进制数字。
+func isHex(b byte) bool {
+	return '0' <= b && b <= '9' || 'a' <= b && b <= 'f' || 'A' <= b && b <= 'F'
+}
+
+// unhex 用于获取给定十六进制数字的值。
+func unhex(b byte) byte {
+	switch {
+	case '0' <= b && b <= '9':
+		return b - '0'
+	case 'a' <= b && b <= 'f':
+		return b - 'a' + 10
+	default:
+		return b - 'A' + 10
+	}
+}
+
+// normalizeEscapes 用于规范化给定字符串中的百分号编码。
+// 被编码的非保留字符会被解码，其余编码中的十六进制数字会被转换为大写，
+// 不能直接出现的字符（包括不完整的编码中的"%"）会被编码。
+// 参数inQuery代表给定的字符串是否属于查询字符串或片段标识。
+func normalizeEscapes(s string, inQuery bool) string {
+	var buffer strings.Builder
+	buffer.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if b == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(decoded) {
+				buffer.WriteByte(decoded)
+			} else {
+				writeEscape(&buffer, decoded)
+			}
+			i += 2
+			continue
+		}
+		if isAllowed(b, inQuery) {
+			buffer.WriteByte(b)
+			continue
+		}
+		writeEscape(&buffer, b)
+	}
+	return buffer.String()
+}
+
+// writeEscape 用于写入给定字节的百分号编码。
+func writeEscape(buffer *strings.Builder, b byte) {
+	buffer.WriteByte('%')
+	buffer.WriteByte(upperHex[b>>4])
+	buffer.WriteByte(upperHex[b&0x0f])
+}
+
+// removeDotSegments 用于解析路径中的"."和".."，参见RFC 3986第5.2.4节。
+func removeDotSegments(path string) string {
+	if path == "" {
+		return ""
+	}
+	segments := strings.Split(path, "/")
+	output := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				output = append(output, "")
+			}
+		case "..":
+			// 不能越过根路径。
+			if len(output) > 1 {
+				output = output[:len(output)-1]
+			}
+			if last {
+				output = append(output, "")
+			}
+		default:
+			output = append(output, segment)
+		}
+	}
+	result := strings.Join(output, "/")
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(result, "/") {
+		result = "/" + result
+	}
+	return result
+}
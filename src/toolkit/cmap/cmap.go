@@ -22,6 +22,10 @@ type ConcurrentMap interface {
 	Delete(key string) bool
 	// Len 会返回当前字典中键-元素对的数量。
 	Len() uint64
+	// Range 会依次把字典中的每个键-元素对传给参数f。
+	// 若f返回false则停止遍历。
+	// 遍历期间的并发修改不一定会被反映出来。
+	Range(f func(key string, element interface{}) bool)
 }
 
 // myConcurrentMap 代表ConcurrentMap接口的实现类型。
@@ -92,6 +96,17 @@ func (cmap *myConcurrentMap) Len() uint64 {
 	return atomic.LoadUint64(&cmap.total)
 }
 
+func (cmap *myConcurrentMap) Range(f func(key string, element interface{}) bool) {
+	if f == nil {
+		return
+	}
+	for _, s := range cmap.segments {
+		if !s.Range(f) {
+			return
+		}
+	}
+}
+
 // findSegment 会根据给定参数寻找并返回对应散列段。
 func (cmap *myConcurrentMap) findSegment(keyHash uint64) Segment {
 	if cmap.concurrency == 1 {
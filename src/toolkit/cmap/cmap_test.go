@@ -203,6 +203,40 @@ func TestCmapDelete(t *testing.T) {
 	}
 }
 
+func TestCmapRange(t *testing.T) {
+	number := 30
+	testCases := genNoRepetitiveTestingPairs(number)
+	concurrency := number / 3
+	cm, _ := NewConcurrentMap(concurrency, nil)
+	for _, p := range testCases {
+		cm.Put(p.Key(), p.Element())
+	}
+	visited := map[string]interface{}{}
+	cm.Range(func(key string, element interface{}) bool {
+		visited[key] = element
+		return true
+	})
+	if len(visited) != number {
+		t.Fatalf("Inconsistent visited number: expected: %d, actual: %d",
+			number, len(visited))
+	}
+	for _, p := range testCases {
+		if visited[p.Key()] != p.Element() {
+			t.Fatalf("Inconsistent element: expected: %#v, actual: %#v",
+				p.Element(), visited[p.Key()])
+		}
+	}
+	var count int
+	cm.Range(func(key string, element interface{}) bool {
+		count++
+		return count < 5
+	})
+	if count != 5 {
+		t.Fatalf("Inconsistent visited number after breaking: expected: %d, actual: %d",
+			5, count)
+	}
+}
+
 func TestCmapDeleteInParallel(t *testing.T) {
 	number := 30
 	testCases := genNoRepetitiveTestingPairs(number)
@@ -22,6 +22,9 @@ type Segment interface {
 	Delete(key string) bool
 	// Size 用于获取当前段的尺寸（其中包含的散列桶的数量）。
 	Size() uint64
+	// Range 会依次把当前段中的每个键-元素对传给参数f。
+	// 若f返回false则停止遍历，此时结果值为false。
+	Range(f func(key string, element interface{}) bool) bool
 }
 
 // segment 代表并发安全的散列段的类型。
@@ -98,6 +101,20 @@ func (s *segment) Size() uint64 {
 	return atomic.LoadUint64(&s.pairTotal)
 }
 
+func (s *segment) Range(f func(key string, element interface{}) bool) bool {
+	s.lock.Lock()
+	buckets := s.buckets
+	s.lock.Unlock()
+	for _, b := range buckets {
+		for p := b.GetFirstPair(); p != nil; p = p.Next() {
+			if !f(p.Key(), p.Element()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // redistribute 会检查给定参数并设置相应的阈值和计数，
 // 并在必要时重新分配所有散列桶中的所有键-元素对。
 // 注意！必须在互斥锁的保护下调用本方法！
@@ -0,0 +1,6 @@
+package linkgraph
+
+import "errors"
+
+// ErrClosedStore 代表链接图存储已关闭的错误。
+var ErrClosedStore = errors.New("closed link graph store")
@@ -0,0 +1,202 @@
+package linkgraph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format 代表链接图的导出格式。
+type Format string
+
+const (
+	// FORMAT_DOT 代表Graphviz的DOT格式。
+	FORMAT_DOT Format = "dot"
+	// FORMAT_GRAPHML 代表GraphML格式。
+	FORMAT_GRAPHML Format = "graphml"
+	// FORMAT_CSV 代表CSV格式的边列表。
+	FORMAT_CSV Format = "csv"
+)
+
+// Write 会按照给定的格式把给定的边写入给定的写入器。
+func Write(w io.Writer, format Format, edges []Edge) error {
+	switch format {
+	case FORMAT_DOT:
+		return WriteDOT(w, edges)
+	case FORMAT_GRAPHML:
+		return WriteGraphML(w, edges)
+	case FORMAT_CSV:
+		return WriteCSV(w, edges)
+	default:
+		return fmt.Errorf("unsupported link graph format: %q", format)
+	}
+}
+
+// nodes 用于获取给定的边所涉及的所有URL，按字典序排列。
+func nodes(edges []Edge) []string {
+	nodeSet := map[string]struct{}{}
+	for _, edge := range edges {
+		nodeSet[edge.Source] = struct{}{}
+		nodeSet[edge.Target] = struct{}{}
+	}
+	nodeList := make([]string, 0, len(nodeSet))
+	for node := range nodeSet {
+		nodeList = append(nodeList, node)
+	}
+	sort.Strings(nodeList)
+	return nodeList
+}
+
+// WriteDOT 会把给定的边以Graphviz的DOT格式写入给定的写入器。
+// 被拒绝的边会以虚线表示。
+func WriteDOT(w io.Writer, edges []Edge) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph linkgraph {")
+	for _, node := range nodes(edges) {
+		fmt.Fprintf(bw, "  %s;\n", strconv.Quote(node))
+	}
+	for _, edge := range edges {
+		var attrs []string
+		if edge.AnchorText != "" {
+			attrs = append(attrs, "label="+strconv.Quote(edge.AnchorText))
+		}
+		if !edge.Accepted {
+			attrs = append(attrs, "style=dashed")
+			if edge.Reason != "" {
+				attrs = append(attrs, "tooltip="+strconv.Quote(edge.Reason))
+			}
+		}
+		fmt.Fprintf(bw, "  %s -> %s", strconv.Quote(edge.Source), strconv.Quote(edge.Target))
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(bw, ";")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// graphML 及以下类型代表GraphML文档的结构。
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML 会把给定的边以GraphML格式写入给定的写入器。
+// 节点的ID为其序号，URL被记录在节点的url属性中。
+func WriteGraphML(w io.Writer, edges []Edge) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "url", For: "node", AttrName: "url", AttrType: "string"},
+			{ID: "anchor", For: "edge", AttrName: "anchor_text", AttrType: "string"},
+			{ID: "accepted", For: "edge", AttrName: "accepted", AttrType: "boolean"},
+			{ID: "reason", For: "edge", AttrName: "reason", AttrType: "string"},
+			{ID: "depth", For: "edge", AttrName: "depth", AttrType: "int"},
+		},
+		Graph: graphMLGraph{ID: "linkgraph", EdgeDefault: "directed"},
+	}
+	nodeIDs := map[string]string{}
+	for i, node := range nodes(edges) {
+		id := "n" + strconv.Itoa(i)
+		nodeIDs[node] = id
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:   id,
+			Data: []graphMLData{{Key: "url", Value: node}},
+		})
+	}
+	for _, edge := range edges {
+		data := []graphMLData{
+			{Key: "accepted", Value: strconv.FormatBool(edge.Accepted)},
+			{Key: "depth", Value: strconv.FormatUint(uint64(edge.Depth), 10)},
+		}
+		if edge.AnchorText != "" {
+			data = append(data, graphMLData{Key: "anchor", Value: edge.AnchorText})
+		}
+		if edge.Reason != "" {
+			data = append(data, graphMLData{Key: "reason", Value: edge.Reason})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: nodeIDs[edge.Source],
+			Target: nodeIDs[edge.Target],
+			Data:   data,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteCSV 会把给定的边以CSV格式的边列表写入给定的写入器。
+// 第一行为表头。
+func WriteCSV(w io.Writer, edges []Edge) error {
+	writer := csv.NewWriter(w)
+	header := []string{"source", "target", "anchor_text", "depth", "accepted", "reason", "time"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, edge := range edges {
+		var timeStr string
+		if !edge.Time.IsZero() {
+			timeStr = edge.Time.Format("2006-01-02T15:04:05Z07:00")
+		}
+		record := []string{
+			edge.Source,
+			edge.Target,
+			edge.AnchorText,
+			strconv.FormatUint(uint64(edge.Depth), 10),
+			strconv.FormatBool(edge.Accepted),
+			edge.Reason,
+			timeStr,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
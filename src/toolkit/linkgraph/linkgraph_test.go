@@ -0,0 +1,154 @@
+package linkgraph
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var testEdges = []Edge{
+	{Source: "http://a.com/", Target: "http://a.com/x", AnchorText: "X \"quoted\"", Depth: 1, Accepted: true},
+	{Source: "http://a.com/", Target: "http://b.com/", Depth: 1, Reason: "not in accepted primary domain map"},
+	{Source: "http://a.com/x", Target: "http://a.com/", AnchorText: "home", Depth: 2, Reason: "repeated"},
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.Add(Edge{Source: "s", Target: "t"})
+		}()
+	}
+	wg.Wait()
+	if store.EdgeNumber() != 100 {
+		t.Fatalf("Inconsistent edge number: expected: %d, actual: %d", 100, store.EdgeNumber())
+	}
+	edges, err := store.Edges()
+	if err != nil || len(edges) != 100 {
+		t.Fatalf("Couldn't get all edges: %d, %v", len(edges), err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	if _, err := NewFileStore(""); err == nil {
+		t.Fatal("No error when new a file store with empty path, but should not be the case!")
+	}
+	path := filepath.Join(t.TempDir(), "links.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("An error occurs when new a file store: %s", err)
+	}
+	for _, edge := range testEdges[:2] {
+		if err := store.Add(edge); err != nil {
+			t.Fatalf("An error occurs when adding edge: %s", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("An error occurs when closing file store: %s", err)
+	}
+	if err := store.Add(testEdges[2]); err != ErrClosedStore {
+		t.Fatalf("Unexpected error when adding edge to closed store: %v", err)
+	}
+	// 重新打开的存储会在原有的边之后追加。
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("An error occurs when reopening file store: %s", err)
+	}
+	defer store.Close()
+	store.Add(testEdges[2])
+	if store.EdgeNumber() != 1 {
+		t.Fatalf("Inconsistent edge number: expected: %d, actual: %d", 1, store.EdgeNumber())
+	}
+	edges, err := store.Edges()
+	if err != nil {
+		t.Fatalf("An error occurs when reading edges: %s", err)
+	}
+	if len(edges) != len(testEdges) {
+		t.Fatalf("Inconsistent edge count: expected: %d, actual: %d", len(testEdges), len(edges))
+	}
+	for i, edge := range edges {
+		if edge != testEdges[i] {
+			t.Fatalf("Inconsistent edge %d: expected: %+v, actual: %+v", i, testEdges[i], edge)
+		}
+	}
+}
+
+func TestReadEdgesInvalid(t *testing.T) {
+	_, err := ReadEdges(strings.NewReader("{\"source\":\"a\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Unexpected error for invalid line: %v", err)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FORMAT_DOT, testEdges); err != nil {
+		t.Fatalf("An error occurs when writing DOT: %s", err)
+	}
+	out := buf.String()
+	for _, expected := range []string{
+		"digraph linkgraph {",
+		`"http://a.com/" -> "http://a.com/x" [label="X \"quoted\""];`,
+		`"http://a.com/" -> "http://b.com/" [style=dashed, tooltip="not in accepted primary domain map"];`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Missing %q in DOT output:\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, ";\n") != 3+len(testEdges) {
+		t.Fatalf("Unexpected statement number in DOT output:\n%s", out)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FORMAT_GRAPHML, testEdges); err != nil {
+		t.Fatalf("An error occurs when writing GraphML: %s", err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid GraphML output: %s", err)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != len(testEdges) {
+		t.Fatalf("Unexpected GraphML graph: nodes: %d, edges: %d",
+			len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	nodeURLs := map[string]string{}
+	for _, node := range doc.Graph.Nodes {
+		nodeURLs[node.ID] = node.Data[0].Value
+	}
+	first := doc.Graph.Edges[0]
+	if nodeURLs[first.Source] != testEdges[0].Source || nodeURLs[first.Target] != testEdges[0].Target {
+		t.Fatalf("Unexpected first edge: %+v", first)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FORMAT_CSV, testEdges); err != nil {
+		t.Fatalf("An error occurs when writing CSV: %s", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV output: %s", err)
+	}
+	if len(records) != len(testEdges)+1 {
+		t.Fatalf("Unexpected record number: %d", len(records))
+	}
+	if records[1][2] != testEdges[0].AnchorText || records[2][4] != "false" {
+		t.Fatalf("Unexpected records: %v", records)
+	}
+}
+
+func TestWriteUnsupported(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, Format("svg"), testEdges); err == nil {
+		t.Fatal("No error when writing unsupported format, but should not be the case!")
+	}
+}
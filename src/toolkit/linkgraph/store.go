@@ -0,0 +1,183 @@
+package linkgraph
+
+import (
+	"bufio"
+	"encoding/json"
+	"errs"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Edge 代表链接图中的一条边，即某个页面中指向另一个URL的链接。
+type Edge struct {
+	// Source 代表包含该链接的页面的URL。
+	Source string `json:"source"`
+	// Target 代表该链接指向的URL。
+	Target string `json:"target"`
+	// AnchorText 代表该链接的文本。
+	AnchorText string `json:"anchor_text,omitempty"`
+	// Depth 代表目标URL的爬取深度。
+	Depth uint32 `json:"depth"`
+	// Accepted 代表目标URL是否被调度器接受。
+	Accepted bool `json:"accepted"`
+	// Reason 代表目标URL被拒绝的原因。
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Store 代表链接图存储的接口类型。
+// 它的所有方法都应该是并发安全的。
+type Store interface {
+	// Add 会存储给定的边。
+	Add(edge Edge) error
+	// Edges 会返回已存储的所有的边。
+	Edges() ([]Edge, error)
+	// EdgeNumber 会返回已存储的边的数量。
+	EdgeNumber() uint64
+	// Close 会关闭存储。
+	Close() error
+}
+
+// myMemoryStore 代表把边存放在内存中的链接图存储的实现类型。
+type myMemoryStore struct {
+	edges []Edge
+	lock  sync.RWMutex
+}
+
+// NewMemoryStore 会创建一个把边存放在内存中的链接图存储。
+func NewMemoryStore() Store {
+	return &myMemoryStore{}
+}
+
+func (store *myMemoryStore) Add(edge Edge) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	store.edges = append(store.edges, edge)
+	return nil
+}
+
+func (store *myMemoryStore) Edges() ([]Edge, error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+	edges := make([]Edge, len(store.edges))
+	copy(edges, store.edges)
+	return edges, nil
+}
+
+func (store *myMemoryStore) EdgeNumber() uint64 {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+	return uint64(len(store.edges))
+}
+
+func (store *myMemoryStore) Close() error {
+	return nil
+}
+
+// myFileStore 代表把边追加到JSONL文件中的链接图存储的实现类型。
+type myFileStore struct {
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	// total 代表本次打开后已存储的边的数量。
+	total uint64
+	lock  sync.Mutex
+}
+
+// NewFileStore 会创建一个把边追加到给定路径的JSONL文件中的链接图存储。
+// 若该文件已存在，新的边会被追加到其末尾。
+func NewFileStore(path string) (Store, error) {
+	if path == "" {
+		return nil, errs.NewIllegalParameterError("empty path for link graph store")
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	return &myFileStore{
+		path:    path,
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}, nil
+}
+
+func (store *myFileStore) Add(edge Edge) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.file == nil {
+		return ErrClosedStore
+	}
+	if err := store.encoder.Encode(edge); err != nil {
+		return err
+	}
+	atomic.AddUint64(&store.total, 1)
+	return nil
+}
+
+// Edges 会从文件中读出所有的边，包括本次打开之前存储的边。
+func (store *myFileStore) Edges() ([]Edge, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.file != nil {
+		if err := store.writer.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.Open(store.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadEdges(file)
+}
+
+func (store *myFileStore) EdgeNumber() uint64 {
+	return atomic.LoadUint64(&store.total)
+}
+
+func (store *myFileStore) Close() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.file == nil {
+		return nil
+	}
+	err := store.writer.Flush()
+	if closeErr := store.file.Close(); err == nil {
+		err = closeErr
+	}
+	store.file = nil
+	store.writer = nil
+	store.encoder = nil
+	return err
+}
+
+// ReadEdges 会从给定的读取器读出所有的边。
+// 读取器中的内容应为每行一条边的JSON。
+func ReadEdges(r io.Reader) ([]Edge, error) {
+	var edges []Edge
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var edge Edge
+		if err := json.Unmarshal([]byte(line), &edge); err != nil {
+			return edges, fmt.Errorf("couldn't decode edge at line %d: %s",
+				lineNumber, err)
+		}
+		edges = append(edges, edge)
+	}
+	return edges, scanner.Err()
+}
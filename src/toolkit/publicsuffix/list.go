@@ -0,0 +1,247 @@
+package publicsuffix
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"errs"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// embeddedList 代表内嵌的公共后缀列表的原始内容。
+// 可以用publicsuffix.org上的最新版本替换public_suffix_list.dat以更新内嵌的列表，
+// 也可以在运行时通过SetDefault函数替换默认的列表。
+//
+//go:embed public_suffix_list.dat
+var embeddedList string
+
+// ErrPublicSuffix 代表给定的域名本身就是公共后缀的错误。
+var ErrPublicSuffix = errors.New("the domain is a public suffix")
+
+// 规则的种类，可以组合。
+const (
+	// ruleNormal 代表普通规则，如"co.uk"。
+	ruleNormal uint8 = 1 << iota
+	// ruleWildcard 代表通配规则，如"*.ck"。
+	// 以去掉"*."后的部分为键存储。
+	ruleWildcard
+	// ruleException 代表例外规则，如"!www.ck"。
+	// 以去掉"!"后的部分为键存储。
+	ruleException
+)
+
+// rule 代表公共后缀规则。
+type rule struct {
+	kinds uint8
+	// icann 代表规则是否属于ICANN部分。否则属于私有部分。
+	icann bool
+}
+
+// List 代表公共后缀列表的接口类型。
+// 所有方法接受的域名都可以包含大写字母和国际化标签，
+// 而返回的域名都是小写的ASCII形式。
+type List interface {
+	// PublicSuffix 会返回给定域名的公共后缀，
+	// 以及与之匹配的规则是否属于ICANN部分。
+	// 若没有规则与之匹配，则按照隐含的"*"规则以顶级域名作为公共后缀。
+	PublicSuffix(domain string) (suffix string, icann bool, err error)
+	// EffectiveTLDPlusOne 会返回给定域名的可注册域名，
+	// 即公共后缀再加上一个标签。
+	EffectiveTLDPlusOne(domain string) (string, error)
+	// RuleNumber 会返回规则的数量。
+	RuleNumber() int
+}
+
+// myList 代表公共后缀列表的实现类型。
+type myList struct {
+	rules  map[string]*rule
+	number int
+}
+
+// Parse 会从给定的读取器中解析公共后缀列表。
+// 列表的格式参见https://publicsuffix.org/list/。
+// 列表中"===BEGIN PRIVATE DOMAINS==="之后的规则属于私有部分。
+func Parse(r io.Reader) (List, error) {
+	if r == nil {
+		return nil, errs.NewIllegalParameterError("nil reader")
+	}
+	list := &myList{rules: map[string]*rule{}}
+	icann := true
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "//") {
+			if strings.Contains(line, "===BEGIN ICANN DOMAINS===") {
+				icann = true
+			} else if strings.Contains(line, "===BEGIN PRIVATE DOMAINS===") {
+				icann = false
+			}
+			continue
+		}
+		// 规则在第一个空白字符处结束。
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+		if err := list.add(line, icann); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if list.number == 0 {
+		return nil, errors.New("no public suffix rule")
+	}
+	return list, nil
+}
+
+// add 用于添加一条规则。
+func (list *myList) add(line string, icann bool) error {
+	kind := ruleNormal
+	switch {
+	case strings.HasPrefix(line, "!"):
+		kind = ruleException
+		line = line[1:]
+	case strings.HasPrefix(line, "*."):
+		kind = ruleWildcard
+		line = line[2:]
+	}
+	key, err := ToASCII(line)
+	if err != nil {
+		return fmt.Errorf("illegal public suffix rule %q: %s", line, err)
+	}
+	if key == "" {
+		return fmt.Errorf("empty public suffix rule")
+	}
+	r := list.rules[key]
+	if r == nil {
+		r = &rule{icann: icann}
+		list.rules[key] = r
+	}
+	r.kinds |= kind
+	list.number++
+	return nil
+}
+
+func (list *myList) RuleNumber() int {
+	return list.number
+}
+
+func (list *myList) PublicSuffix(domain string) (suffix string, icann bool, err error) {
+	labels, err := splitDomain(domain)
+	if err != nil {
+		return "", false, err
+	}
+	index, icann := list.suffixIndex(labels)
+	return strings.Join(labels[index:], "."), icann, nil
+}
+
+func (list *myList) EffectiveTLDPlusOne(domain string) (string, error) {
+	labels, err := splitDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	index, _ := list.suffixIndex(labels)
+	if index == 0 {
+		return "", ErrPublicSuffix
+	}
+	return strings.Join(labels[index-1:], "."), nil
+}
+
+// suffixIndex 用于获取公共后缀在给定标签列表中的起始索引。
+// 例外规则优先于其他规则，其他规则中最长的优先。
+func (list *myList) suffixIndex(labels []string) (index int, icann bool) {
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+		r := list.rules[candidate]
+		if r == nil {
+			continue
+		}
+		if r.kinds&ruleException != 0 {
+			return i + 1, r.icann
+		}
+		if r.kinds&ruleWildcard != 0 && i > 0 {
+			return i - 1, r.icann
+		}
+		if r.kinds&ruleNormal != 0 {
+			return i, r.icann
+		}
+	}
+	// 隐含的"*"规则。
+	return len(labels) - 1, false
+}
+
+// splitDomain 用于把给定的域名转换为ASCII形式并拆分为标签。
+func splitDomain(domain string) ([]string, error) {
+	ascii, err := ToASCII(domain)
+	if err != nil {
+		return nil, err
+	}
+	if ascii == "" {
+		return nil, errs.NewIllegalParameterError("empty domain")
+	}
+	labels := strings.Split(ascii, ".")
+	for _, label := range labels {
+		if label == "" {
+			return nil, fmt.Errorf("empty label in domain %q", domain)
+		}
+	}
+	return labels, nil
+}
+
+var (
+	// defaultList 代表默认的公共后缀列表。
+	defaultList List
+	// defaultErr 代表解析内嵌列表时发生的错误。
+	defaultErr  error
+	defaultOnce sync.Once
+	defaultLock sync.RWMutex
+)
+
+// Default 会返回默认的公共后缀列表。
+// 在调用SetDefault之前，默认列表就是内嵌的列表。
+func Default() List {
+	defaultOnce.Do(func() {
+		list, err := Parse(strings.NewReader(embeddedList))
+		defaultLock.Lock()
+		if defaultList == nil {
+			defaultList, defaultErr = list, err
+		}
+		defaultLock.Unlock()
+	})
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+	if defaultList == nil {
+		// 内嵌的列表总是有效的，除非它被替换为了错误的内容。
+		panic(fmt.Sprintf("invalid embedded public suffix list: %s", defaultErr))
+	}
+	return defaultList
+}
+
+// SetDefault 用于替换默认的公共后缀列表。
+func SetDefault(list List) error {
+	if list == nil {
+		return errs.NewIllegalParameterError("nil public suffix list")
+	}
+	defaultOnce.Do(func() {})
+	defaultLock.Lock()
+	defaultList = list
+	defaultLock.Unlock()
+	return nil
+}
+
+// EffectiveTLDPlusOne 会使用默认的公共后缀列表返回给定域名的可注册域名。
+func EffectiveTLDPlusOne(domain string) (string, error) {
+	return Default().EffectiveTLDPlusOne(domain)
+}
+
+// PublicSuffix 会使用默认的公共后缀列表返回给定域名的公共后缀。
+func PublicSuffix(domain string) (suffix string, icann bool, err error) {
+	return Default().PublicSuffix(domain)
+}
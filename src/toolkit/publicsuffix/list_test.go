@@ -0,0 +1,112 @@
+package publicsuffix
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+	cases := map[string]string{
+		"Example.COM.": "example.com",
+		"公司.cn":        "xn--55qx5d.cn",
+		"bücher.de":    "xn--bcher-kva.de",
+		"münchen":      "xn--mnchen-3ya",
+	}
+	for domain, expected := range cases {
+		actual, err := ToASCII(domain)
+		if err != nil {
+			t.Fatalf("An error occurs when converting %q: %s", domain, err)
+		}
+		if actual != expected {
+			t.Fatalf("Inconsistent ASCII domain for %q: expected: %s, actual: %s",
+				domain, expected, actual)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse(nil); err == nil {
+		t.Fatal("No error when parsing a nil reader, but should not be the case!")
+	}
+	if _, err := Parse(strings.NewReader("// comment only\n")); err == nil {
+		t.Fatal("No error when parsing a list without rules, but should not be the case!")
+	}
+	content := "// ===BEGIN ICANN DOMAINS===\ncom\nuk\nco.uk\n*.ck\n!www.ck\n" +
+		"// ===END ICANN DOMAINS===\n// ===BEGIN PRIVATE DOMAINS===\n" +
+		"github.io\n// ===END PRIVATE DOMAINS===\n"
+	list, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("An error occurs when parsing the list: %s", err)
+	}
+	if list.RuleNumber() != 6 {
+		t.Fatalf("Inconsistent rule number: expected: %d, actual: %d",
+			6, list.RuleNumber())
+	}
+	suffix, icann, err := list.PublicSuffix("foo.github.io")
+	if err != nil || suffix != "github.io" || icann {
+		t.Fatalf("Inconsistent public suffix for %q: %s, %v, %v",
+			"foo.github.io", suffix, icann, err)
+	}
+	suffix, icann, err = list.PublicSuffix("foo.co.uk")
+	if err != nil || suffix != "co.uk" || !icann {
+		t.Fatalf("Inconsistent public suffix for %q: %s, %v, %v",
+			"foo.co.uk", suffix, icann, err)
+	}
+}
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	cases := map[string]string{
+		"www.example.com":         "example.com",
+		"example.com":             "example.com",
+		"a.b.foo.co.uk":           "foo.co.uk",
+		"x.github.io":             "x.github.io",
+		"a.x.github.io":           "x.github.io",
+		"a.blogspot.com":          "a.blogspot.com",
+		"go.dev":                  "go.dev",
+		"pkg.go.dev":              "go.dev",
+		"WWW.Example.COM.":        "example.com",
+		"b.c.ck":                  "b.c.ck",
+		"a.b.c.ck":                "b.c.ck",
+		"www.ck":                  "www.ck",
+		"a.www.ck":                "www.ck",
+		"a.city.kawasaki.jp":      "city.kawasaki.jp",
+		"a.b.c.kawasaki.jp":       "b.c.kawasaki.jp",
+		"www.食狮.公司.cn":            "xn--85x722f.xn--55qx5d.cn",
+		"www.xn--85x722f.公司.cn":   "xn--85x722f.xn--55qx5d.cn",
+		"foo.unknown-tld-example": "foo.unknown-tld-example",
+	}
+	for domain, expected := range cases {
+		actual, err := EffectiveTLDPlusOne(domain)
+		if err != nil {
+			t.Fatalf("An error occurs when getting eTLD+1 of %q: %s", domain, err)
+		}
+		if actual != expected {
+			t.Fatalf("Inconsistent eTLD+1 for %q: expected: %s, actual: %s",
+				domain, expected, actual)
+		}
+	}
+	for _, domain := range []string{"", "com", "co.uk", "github.io", "c.ck", "a..com"} {
+		if _, err := EffectiveTLDPlusOne(domain); err == nil {
+			t.Fatalf("No error when getting eTLD+1 of %q, but should not be the case!", domain)
+		}
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	if err := SetDefault(nil); err == nil {
+		t.Fatal("No error when setting a nil default list, but should not be the case!")
+	}
+	original := Default()
+	defer SetDefault(original)
+	list, err := Parse(strings.NewReader("com\nexample.com\n"))
+	if err != nil {
+		t.Fatalf("An error occurs when parsing the list: %s", err)
+	}
+	if err = SetDefault(list); err != nil {
+		t.Fatalf("An error occurs when setting the default list: %s", err)
+	}
+	actual, err := EffectiveTLDPlusOne("a.b.example.com")
+	if err != nil || actual != "b.example.com" {
+		t.Fatalf("Inconsistent eTLD+1 with the new default list: %s, %v", actual, err)
+	}
+}
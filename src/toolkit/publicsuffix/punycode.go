@@ -0,0 +1,139 @@
+package publicsuffix
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// punycode 编码所用的参数，参见RFC 3492。
+const (
+	punyBase        int32 = 36
+	punyTMin        int32 = 1
+	punyTMax        int32 = 26
+	punySkew        int32 = 38
+	punyDamp        int32 = 700
+	punyInitialBias int32 = 72
+	punyInitialN    int32 = 128
+	// acePrefix 代表国际化域名标签的ASCII兼容编码前缀。
+	acePrefix = "xn--"
+)
+
+// ToASCII 会把给定的域名转换为小写的ASCII形式。
+// 其中的非ASCII标签会被转换为以"xn--"开头的punycode编码。
+// 末尾的点会被去掉。
+func ToASCII(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if isASCII(domain) {
+		return domain, nil
+	}
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		encoded, err := encodePunycode(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = acePrefix + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// isASCII 用于判断给定的字符串是否只包含ASCII字符。
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// encodePunycode 用于对给定的标签进行punycode编码。
+func encodePunycode(label string) (string, error) {
+	if !utf8.ValidString(label) {
+		return "", fmt.Errorf("invalid UTF-8 label: %q", label)
+	}
+	runes := []rune(label)
+	output := make([]byte, 0, len(label)+8)
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			output = append(output, byte(r))
+		}
+	}
+	basicNumber := int32(len(output))
+	handled := basicNumber
+	if basicNumber > 0 {
+		output = append(output, '-')
+	}
+	n, delta, bias := punyInitialN, int32(0), punyInitialBias
+	for handled < int32(len(runes)) {
+		m := rune(0x7fffffff)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		if int64(delta)+int64(m-n)*int64(handled+1) > 0x7fffffff {
+			return "", fmt.Errorf("punycode overflow: %q", label)
+		}
+		delta += (m - n) * (handled + 1)
+		n = m
+		for _, r := range runes {
+			if r < n {
+				delta++
+				continue
+			}
+			if r > n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := k - bias
+				if t < punyTMin {
+					t = punyTMin
+				} else if t > punyTMax {
+					t = punyTMax
+				}
+				if q < t {
+					break
+				}
+				output = append(output, punyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			output = append(output, punyDigit(q))
+			bias = punyAdapt(delta, handled+1, handled == basicNumber)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(output), nil
+}
+
+// punyDigit 用于获取给定数值对应的punycode字符。
+func punyDigit(d int32) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// punyAdapt 用于调整punycode编码的偏差值。
+func punyAdapt(delta, numPoints int32, firstTime bool) int32 {
+	if firstTime {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := int32(0)
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}